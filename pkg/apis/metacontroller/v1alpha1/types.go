@@ -20,6 +20,7 @@ package v1alpha1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // CompositeController
@@ -46,6 +47,10 @@ func (cc *CompositeController) GetEndpointConfigs() []EndpointConfig {
 	return cc.Spec.EndpointConfigs
 }
 
+func (cc *CompositeController) GetRelatedResourceRules() []RelatedResourceRuleTemplate {
+	return cc.Spec.RelatedResources
+}
+
 type CompositeControllerSpec struct {
 	ParentResource CompositeControllerParentResourceRule  `json:"parentResource"`
 	ChildResources []CompositeControllerChildResourceRule `json:"childResources,omitempty"`
@@ -58,15 +63,121 @@ type CompositeControllerSpec struct {
 	// +optional
 	EndpointConfigs []EndpointConfig `json:"endpointConfigs,omitempty"`
 
+	// RelatedResources declares related objects without a customize hook.
+	// The rules are evaluated locally against each parent and combined with
+	// any rules returned by the customize hook.
+	// +optional
+	RelatedResources []RelatedResourceRuleTemplate `json:"relatedResources,omitempty"`
+
+	// ApplyStrategy selects how children are written, overriding the
+	// --apply-strategy flags for this controller.
+	// +optional
+	ApplyStrategy *ApplyStrategy `json:"applyStrategy,omitempty"`
+
+	// DryRun makes the controller compute everything as usual, but only log,
+	// record Events for and count the writes it would make, instead of
+	// persisting them. The --dry-run flag enables it for all controllers.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// LogLevel makes the loggers of the controller log at least at this
+	// level, like the --zap-log-level flag: one of debug, info, error and
+	// panic, or a verbosity greater than 0. A verbosity of 6 logs the
+	// requests and responses of hooks.
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// RedactionRules mask fields of objects in the payloads that the
+	// controller logs, records in the audit log and attaches to Events, like
+	// the requests and responses of hooks. The data and stringData of Secrets
+	// are always masked.
+	// +optional
+	RedactionRules []RedactionRule `json:"redactionRules,omitempty"`
+
 	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
 	GenerateSelector    *bool  `json:"generateSelector,omitempty"`
 }
 
+// +kubebuilder:validation:Enum={"DynamicApply","ServerSideApply"}
+type ApplyStrategyType string
+
+const (
+	ApplyStrategyDynamicApply    ApplyStrategyType = "DynamicApply"
+	ApplyStrategyServerSideApply ApplyStrategyType = "ServerSideApply"
+)
+
+// RedactionRule masks fields of objects in logged and recorded payloads.
+type RedactionRule struct {
+	// APIVersion selects the objects the rule applies to. All API versions
+	// match if it's empty.
+	// +optional
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Kind selects the objects the rule applies to. All kinds match if it's
+	// empty.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Paths are JSONPath templates like {.spec.password} or
+	// {.spec.env[?(@.name=="TOKEN")].value} that select the fields to mask.
+	Paths []string `json:"paths"`
+}
+
+// ApplyStrategy selects how children are written. Unset fields fall back to
+// the strategy of the controller, and then to the --apply-strategy flags.
+type ApplyStrategy struct {
+	// Type is either DynamicApply, a client-side 3-way merge, or
+	// ServerSideApply.
+	// +optional
+	Type ApplyStrategyType `json:"type,omitempty"`
+	// FieldManager is the field manager used by server-side apply.
+	// +optional
+	FieldManager string `json:"fieldManager,omitempty"`
+	// Force makes server-side apply take ownership of fields that are
+	// managed by someone else. Defaults to true.
+	// +optional
+	Force *bool `json:"force,omitempty"`
+	// MigrateFieldManagers are the client-side field managers, usually
+	// metacontroller, whose ownership of fields is moved to FieldManager when
+	// server-side apply first updates a child that was written by dynamic
+	// apply. This makes sure fields the hook stops returning are pruned.
+	// +optional
+	MigrateFieldManagers []string `json:"migrateFieldManagers,omitempty"`
+	// LastAppliedEncoding is how dynamic apply stores the last-applied
+	// configuration of children in their annotations. Gzip lifts the size
+	// limit of annotations for large children, and Auto only compresses the
+	// configuration if it is large.
+	// +optional
+	LastAppliedEncoding LastAppliedEncoding `json:"lastAppliedEncoding,omitempty"`
+}
+
+// +kubebuilder:validation:Enum={"JSON","Gzip","Auto"}
+type LastAppliedEncoding string
+
+const (
+	LastAppliedEncodingJSON LastAppliedEncoding = "JSON"
+	LastAppliedEncodingGzip LastAppliedEncoding = "Gzip"
+	LastAppliedEncodingAuto LastAppliedEncoding = "Auto"
+)
+
 type ResourceRule struct {
 	APIVersion string `json:"apiVersion"`
 	Resource   string `json:"resource"`
 }
 
+// CacheMode selects what the controller caches of the objects of a resource.
+// +kubebuilder:validation:Enum={"Full","Metadata","MetadataOnly"}
+type CacheMode string
+
+const (
+	// CacheModeFull caches full objects.
+	CacheModeFull CacheMode = "Full"
+	// CacheModeMetadata caches only the metadata of objects, and fetches the
+	// full objects that are sent to hooks from the API server.
+	CacheModeMetadata CacheMode = "Metadata"
+	// CacheModeMetadataOnly caches only the metadata of objects, and sends
+	// only the metadata to hooks.
+	CacheModeMetadataOnly CacheMode = "MetadataOnly"
+)
+
 type CompositeControllerParentResourceRule struct {
 	ResourceRule        `json:",inline"`
 	RevisionHistory     *CompositeControllerRevisionHistory `json:"revisionHistory,omitempty"`
@@ -76,6 +187,13 @@ type CompositeControllerParentResourceRule struct {
 
 type CompositeControllerRevisionHistory struct {
 	FieldPaths []string `json:"fieldPaths,omitempty"`
+	// RevisionHistoryLimit is the number of old ControllerRevisions, which no
+	// longer own any children, to retain for each parent. Defaults to 0, which
+	// means such revisions are deleted as soon as the rollout away from them
+	// completes.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
 }
 
 // +kubebuilder:validation:Enum={"OnDelete","Recreate","InPlace","RollingRecreate","RollingInPlace"}
@@ -89,18 +207,130 @@ const (
 	ChildUpdateRollingInPlace  ChildUpdateMethod = "RollingInPlace"
 )
 
+// +kubebuilder:validation:Enum={"Fail","Recreate"}
+type ImmutableFieldErrorPolicy string
+
+const (
+	// ImmutableFieldErrorFail keeps failing the sync, until the hook stops
+	// changing the field.
+	ImmutableFieldErrorFail ImmutableFieldErrorPolicy = "Fail"
+	// ImmutableFieldErrorRecreate deletes the child, so that it's recreated
+	// with the new value on the next sync.
+	ImmutableFieldErrorRecreate ImmutableFieldErrorPolicy = "Recreate"
+)
+
 type CompositeControllerChildResourceRule struct {
 	ResourceRule   `json:",inline"`
 	UpdateStrategy *CompositeControllerChildUpdateStrategy `json:"updateStrategy,omitempty"`
+	// ApplyStrategy overrides the apply strategy of the controller for
+	// children of this type.
+	// +optional
+	ApplyStrategy *ApplyStrategy `json:"applyStrategy,omitempty"`
+	// ManageStatus makes the controller also write the status returned by
+	// the sync hook for existing children of this type, if they have a
+	// status subresource. It requires the Full cache mode.
+	// +optional
+	ManageStatus bool `json:"manageStatus,omitempty"`
+	// CacheMode selects what the controller caches of children of this type.
+	// Defaults to Full.
+	// +optional
+	CacheMode CacheMode `json:"cacheMode,omitempty"`
 }
 
 type CompositeControllerChildUpdateStrategy struct {
 	Method       ChildUpdateMethod       `json:"method,omitempty"`
 	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+	// RollingUpdate controls how many children of this type are updated at
+	// once by the RollingRecreate and RollingInPlace methods. If unset, one
+	// child is updated at a time.
+	// +optional
+	RollingUpdate *ChildRollingUpdate `json:"rollingUpdate,omitempty"`
+	// ProgressDeadlineSeconds is the number of seconds a rolling update of
+	// children of this type may go without moving any child to the latest
+	// revision, before the rollout is considered to have failed.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// AutoRollback rolls the parent back to its previous revision once the
+	// progress deadline is exceeded.
+	// +optional
+	AutoRollback *bool `json:"autoRollback,omitempty"`
+	// OnImmutableFieldError selects what happens when an in-place update of a
+	// child fails because it changes an immutable field. Defaults to Fail.
+	// +optional
+	OnImmutableFieldError ImmutableFieldErrorPolicy `json:"onImmutableFieldError,omitempty"`
+}
+
+// ChildRollingUpdate holds the parameters of a rolling update. All values are
+// either an absolute number of children, or a percentage of the children of
+// this type desired by the latest parent revision.
+type ChildRollingUpdate struct {
+	// MaxUnavailable is the maximum number of updated children which may be
+	// failing their status checks, or still be in the process of being
+	// updated. Percentages are rounded down. Defaults to 1.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// MaxSurge is the number of children which may be in the process of being
+	// recreated on top of MaxUnavailable. Percentages are rounded up. Only
+	// supported by the RollingRecreate method. Defaults to 0.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// Partition is the number of children, in the order returned by the sync
+	// hook, which are kept at their current revision. Percentages are rounded
+	// down. Defaults to 0.
+	// +optional
+	Partition *intstr.IntOrString `json:"partition,omitempty"`
 }
 
 type ChildUpdateStatusChecks struct {
 	Conditions []StatusConditionCheck `json:"conditions,omitempty"`
+	// Fields are checks comparing fields of the child object, for resources
+	// which don't signal readiness through status conditions.
+	// +optional
+	Fields []StatusFieldCheck `json:"fields,omitempty"`
+	// Preset selects a built-in readiness check for a common Kubernetes kind.
+	// It is evaluated in addition to any conditions and fields.
+	// +optional
+	Preset ReadinessPreset `json:"preset,omitempty"`
+}
+
+// +kubebuilder:validation:Enum={"Deployment","StatefulSet","DaemonSet","Job","PersistentVolumeClaim"}
+type ReadinessPreset string
+
+const (
+	ReadinessPresetDeployment            ReadinessPreset = "Deployment"
+	ReadinessPresetStatefulSet           ReadinessPreset = "StatefulSet"
+	ReadinessPresetDaemonSet             ReadinessPreset = "DaemonSet"
+	ReadinessPresetJob                   ReadinessPreset = "Job"
+	ReadinessPresetPersistentVolumeClaim ReadinessPreset = "PersistentVolumeClaim"
+)
+
+// +kubebuilder:validation:Enum={"Equal","NotEqual","GreaterThan","GreaterThanOrEqual","LessThan","LessThanOrEqual","Exists","DoesNotExist"}
+type FieldCheckOperator string
+
+const (
+	FieldCheckEqual              FieldCheckOperator = "Equal"
+	FieldCheckNotEqual           FieldCheckOperator = "NotEqual"
+	FieldCheckGreaterThan        FieldCheckOperator = "GreaterThan"
+	FieldCheckGreaterThanOrEqual FieldCheckOperator = "GreaterThanOrEqual"
+	FieldCheckLessThan           FieldCheckOperator = "LessThan"
+	FieldCheckLessThanOrEqual    FieldCheckOperator = "LessThanOrEqual"
+	FieldCheckExists             FieldCheckOperator = "Exists"
+	FieldCheckDoesNotExist       FieldCheckOperator = "DoesNotExist"
+)
+
+// StatusFieldCheck compares a field of the child object, selected by a
+// JSONPath template like `{.status.readyReplicas}`, with either a literal
+// value or another field of the child object.
+type StatusFieldCheck struct {
+	Path     string             `json:"path"`
+	Operator FieldCheckOperator `json:"operator"`
+	// Value is the literal value to compare with.
+	// +optional
+	Value *string `json:"value,omitempty"`
+	// ValuePath is a JSONPath template selecting the field to compare with.
+	// +optional
+	ValuePath string `json:"valuePath,omitempty"`
 }
 
 type StatusConditionCheck struct {
@@ -390,6 +620,10 @@ func (dc *DecoratorController) GetEndpointConfigs() []EndpointConfig {
 	return dc.Spec.EndpointConfigs
 }
 
+func (dc *DecoratorController) GetRelatedResourceRules() []RelatedResourceRuleTemplate {
+	return dc.Spec.RelatedResources
+}
+
 type DecoratorControllerSpec struct {
 	Resources   []DecoratorControllerResourceRule   `json:"resources"`
 	Attachments []DecoratorControllerAttachmentRule `json:"attachments,omitempty"`
@@ -402,6 +636,37 @@ type DecoratorControllerSpec struct {
 	// +optional
 	EndpointConfigs []EndpointConfig `json:"endpointConfigs,omitempty"`
 
+	// RelatedResources declares related objects without a customize hook.
+	// The rules are evaluated locally against each target object and combined
+	// with any rules returned by the customize hook.
+	// +optional
+	RelatedResources []RelatedResourceRuleTemplate `json:"relatedResources,omitempty"`
+
+	// ApplyStrategy selects how attachments are written, overriding the
+	// --apply-strategy flags for this controller.
+	// +optional
+	ApplyStrategy *ApplyStrategy `json:"applyStrategy,omitempty"`
+
+	// DryRun makes the controller compute everything as usual, but only log,
+	// record Events for and count the writes it would make, instead of
+	// persisting them. The --dry-run flag enables it for all controllers.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// LogLevel makes the loggers of the controller log at least at this
+	// level, like the --zap-log-level flag: one of debug, info, error and
+	// panic, or a verbosity greater than 0. A verbosity of 6 logs the
+	// requests and responses of hooks.
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// RedactionRules mask fields of objects in the payloads that the
+	// controller logs, records in the audit log and attaches to Events, like
+	// the requests and responses of hooks. The data and stringData of Secrets
+	// are always masked.
+	// +optional
+	RedactionRules []RedactionRule `json:"redactionRules,omitempty"`
+
 	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
 }
 
@@ -420,10 +685,23 @@ type AnnotationSelector struct {
 type DecoratorControllerAttachmentRule struct {
 	ResourceRule   `json:",inline"`
 	UpdateStrategy *DecoratorControllerAttachmentUpdateStrategy `json:"updateStrategy,omitempty"`
+	// ApplyStrategy overrides the apply strategy of the controller for
+	// attachments of this type.
+	// +optional
+	ApplyStrategy *ApplyStrategy `json:"applyStrategy,omitempty"`
+	// CacheMode selects what the controller caches of attachments of this
+	// type. Defaults to Full.
+	// +optional
+	CacheMode CacheMode `json:"cacheMode,omitempty"`
 }
 
 type DecoratorControllerAttachmentUpdateStrategy struct {
 	Method ChildUpdateMethod `json:"method,omitempty"`
+	// OnImmutableFieldError selects what happens when an in-place update of
+	// an attachment fails because it changes an immutable field. Defaults to
+	// Fail.
+	// +optional
+	OnImmutableFieldError ImmutableFieldErrorPolicy `json:"onImmutableFieldError,omitempty"`
 }
 
 type DecoratorControllerHooks struct {
@@ -448,6 +726,36 @@ type RelatedResourceRule struct {
 	NamespaceSelector     *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 	Namespace             string                `json:"namespace,omitempty"`
 	Names                 []string              `json:"names"`
+	// CacheMode selects what the controller caches of related objects of
+	// this type. Defaults to Full.
+	CacheMode CacheMode `json:"cacheMode,omitempty"`
+}
+
+// RelatedResourceRuleTemplate is a RelatedResourceRule whose namespace, names
+// and label selector values may contain JSONPath templates (e.g.
+// "{.spec.secretName}"), which are evaluated against the parent object.
+type RelatedResourceRuleTemplate struct {
+	ResourceRule `json:",inline"`
+	// LabelSelector selects related objects by label. Values in matchLabels
+	// and matchExpressions may be JSONPath templates.
+	// +optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// NamespaceSelector selects the namespaces to look in by label.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// Namespace is the namespace to look in, or a JSONPath template producing it.
+	// Defaults to the namespace of a namespaced parent.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Names lists the related objects by name. Each entry may be a JSONPath
+	// template; templates producing several whitespace-separated values
+	// (e.g. "{.spec.secrets[*].name}") contribute one name per value.
+	// +optional
+	Names []string `json:"names,omitempty"`
+	// CacheMode selects what the controller caches of related objects of
+	// this type. Defaults to Full.
+	// +optional
+	CacheMode CacheMode `json:"cacheMode,omitempty"`
 }
 
 // CustomizableController is an interface representing Controller exposing customize hook
@@ -458,4 +766,7 @@ type CustomizableController interface {
 
 	// GetEndpointConfigs returns the webhook endpoint config entries defined on the controller.
 	GetEndpointConfigs() []EndpointConfig
+
+	// GetRelatedResourceRules returns the declarative related resource rules defined on the controller.
+	GetRelatedResourceRules() []RelatedResourceRuleTemplate
 }
@@ -0,0 +1,235 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit writes a JSON-lines log of the writes of controllers to
+// parents and children.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Record is a line of the audit log, about a write to an object.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Controller is the kind and name of the controller, like
+	// CompositeController/my-controller.
+	Controller string `json:"controller"`
+	// Parent is the parent the write was made for.
+	Parent *ObjectReference `json:"parent,omitempty"`
+	// Object is the object written, either a child or the parent itself.
+	Object ObjectReference `json:"object"`
+	// Operation is one of create, update, update_status and delete.
+	Operation string `json:"operation"`
+	// MergePatch is the JSON merge patch of the write. It's empty for
+	// deletes, and holds the whole object for creates.
+	MergePatch json.RawMessage `json:"mergePatch,omitempty"`
+	// HookResponseETag is the ETag of the last response of the sync or
+	// finalize hook in the sync that made the write, if there was one.
+	HookResponseETag string `json:"hookResponseETag,omitempty"`
+}
+
+// ObjectReference identifies an object in a Record.
+type ObjectReference struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid,omitempty"`
+}
+
+// Sink writes Records as JSON lines. A file sink is rotated once it grows
+// beyond its maximum size. A nil *Sink writes nothing.
+type Sink struct {
+	mutex sync.Mutex
+	out   io.Writer
+
+	// file is set for sinks that write to a file.
+	file       *os.File
+	path       string
+	size       int64
+	maxSize    int64
+	maxBackups int
+}
+
+// Open returns a sink that writes to the given destination: a path, or - for
+// stdout. It returns nil if the destination is empty. Files are rotated once
+// they grow beyond maxSize bytes, keeping maxBackups of the old files, named
+// like path.1, path.2 and so on; a maxSize of 0 disables rotation.
+func Open(destination string, maxSize int64, maxBackups int) (*Sink, error) {
+	switch destination {
+	case "":
+		return nil, nil
+	case "-":
+		return NewSink(os.Stdout), nil
+	}
+	s := &Sink{path: destination, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.openFile(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSink returns a sink that writes to out, without rotation.
+func NewSink(out io.Writer) *Sink {
+	return &Sink{out: out}
+}
+
+// Write writes a record as a line.
+func (s *Sink) Write(record Record) error {
+	if s == nil {
+		return nil
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("can't marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// A record is still written if the file can't be rotated, and the
+	// rotation is tried again with the next record.
+	var rotateErr error
+	switch {
+	case s.path != "" && s.file == nil:
+		// The file couldn't be reopened after the last rotation.
+		rotateErr = s.openFile()
+	case s.file != nil && s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize:
+		rotateErr = s.rotate()
+	}
+	if s.out == nil {
+		return rotateErr
+	}
+	n, err := s.out.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("can't write audit record: %w", err)
+	}
+	return rotateErr
+}
+
+// Close closes the file of the sink, if any.
+func (s *Sink) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+func (s *Sink) openFile() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) // #nosec G304 -- the path is set by a flag
+	if err != nil {
+		return fmt.Errorf("can't open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("can't open audit log: %w", err)
+	}
+	s.file, s.out, s.size = file, file, info.Size()
+	return nil
+}
+
+// rotate moves the current file to path.1, shifting the older backups and
+// dropping the oldest one, and starts a new file. Whether or not that
+// succeeds, the sink is left with a fresh handle of the file at path, or
+// without one if it can't be opened.
+func (s *Sink) rotate() error {
+	err := s.file.Close()
+	s.file, s.out = nil, nil
+	if err == nil {
+		err = s.shiftBackups()
+	}
+	if openErr := s.openFile(); openErr != nil {
+		return openErr
+	}
+	if err != nil {
+		return fmt.Errorf("can't rotate audit log: %w", err)
+	}
+	return nil
+}
+
+// shiftBackups moves the file at path to path.1 and each backup to the next
+// number, or removes the file if no backups are kept.
+func (s *Sink) shiftBackups() error {
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for i := s.maxBackups; i > 0; i-- {
+		from := s.path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", s.path, i-1)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+type syncKey struct{}
+
+// syncState holds what the hooks of a sync returned that goes into the
+// records of its writes.
+type syncState struct {
+	mutex            sync.Mutex
+	hookResponseETag string
+}
+
+// WithSync returns a context for a sync of a parent, in which hook calls can
+// set the ETag of their response.
+func WithSync(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syncKey{}, &syncState{})
+}
+
+// SetHookResponseETag sets the ETag of a hook response in the sync of ctx.
+func SetHookResponseETag(ctx context.Context, etag string) {
+	state, ok := ctx.Value(syncKey{}).(*syncState)
+	if !ok || etag == "" {
+		return
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.hookResponseETag = etag
+}
+
+// HookResponseETag returns the ETag of the last hook response in the sync of
+// ctx, if any.
+func HookResponseETag(ctx context.Context) string {
+	state, ok := ctx.Value(syncKey{}).(*syncState)
+	if !ok {
+		return ""
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.hookResponseETag
+}
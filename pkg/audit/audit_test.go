@@ -0,0 +1,140 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	record := Record{Controller: "CompositeController/test", Object: ObjectReference{Name: "x"}, Operation: "create"}
+	line, _ := json.Marshal(record)
+
+	// Each file holds two records.
+	sink, err := Open(path, int64(2*(len(line)+1)), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	for i := range 7 {
+		record.Object.Name = string(rune('a' + i))
+		if err := sink.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(records []Record) string {
+		var names []string
+		for _, record := range records {
+			names = append(names, record.Object.Name)
+		}
+		return strings.Join(names, "")
+	}
+	for file, want := range map[string]string{path: "g", path + ".1": "ef", path + ".2": "cd"} {
+		if got := names(readRecords(t, file)); got != want {
+			t.Errorf("%s holds %q, want %q", filepath.Base(file), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("the oldest backup wasn't dropped: %v", err)
+	}
+}
+
+func TestSinkRotation_failure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	record := Record{Controller: "CompositeController/test", Object: ObjectReference{Name: "a"}, Operation: "create"}
+	line, _ := json.Marshal(record)
+	// A non-empty directory in the way of the backup makes the rotation fail.
+	if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := Open(path, int64(len(line)+1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Write(record); err != nil {
+		t.Fatal(err)
+	}
+	record.Object.Name = "b"
+	if err := sink.Write(record); err == nil {
+		t.Error("Write() with a failed rotation = nil, want an error")
+	}
+
+	// The sink keeps writing to the file, and rotates it once it can.
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	record.Object.Name = "c"
+	if err := sink.Write(record); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(readRecords(t, path+".1")); got != 2 {
+		t.Errorf("backup holds %d records, want 2", got)
+	}
+	if got := readRecords(t, path); len(got) != 1 || got[0].Object.Name != "c" {
+		t.Errorf("audit log holds %v, want record c", got)
+	}
+}
+
+func TestOpen_disabled(t *testing.T) {
+	sink, err := Open("", 0, 0)
+	if err != nil || sink != nil {
+		t.Fatalf("Open() = %v, %v, want nil", sink, err)
+	}
+	if err := sink.Write(Record{}); err != nil {
+		t.Errorf("Write() on nil sink = %v, want nil", err)
+	}
+}
+
+func TestHookResponseETag(t *testing.T) {
+	SetHookResponseETag(context.Background(), "ignored")
+	if got := HookResponseETag(context.Background()); got != "" {
+		t.Errorf("outside of a sync: HookResponseETag() = %q, want none", got)
+	}
+
+	ctx := WithSync(context.Background())
+	SetHookResponseETag(ctx, `"a"`)
+	SetHookResponseETag(ctx, "")
+	if got := HookResponseETag(ctx); got != `"a"` {
+		t.Errorf("HookResponseETag() = %q, want %q", got, `"a"`)
+	}
+}
@@ -57,3 +57,9 @@ func (c *Cache[K, V]) Set(key K, val V) {
 func (c *Cache[K, V]) SetNoExpiration(key K, val V) {
 	c.cache.SetWithExpire(key, val, zcache.NoExpiration)
 }
+
+// Len returns the number of items in the cache, including expired items that
+// weren't cleaned up yet.
+func (c *Cache[K, V]) Len() int {
+	return c.cache.ItemCount()
+}
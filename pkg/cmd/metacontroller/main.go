@@ -17,10 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"metacontroller/pkg/audit"
 	"metacontroller/pkg/logging"
 	"metacontroller/pkg/profile"
+	"metacontroller/pkg/tracing"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,8 +47,10 @@ import (
 )
 
 var (
-	discoveryInterval          = flag.Duration("discovery-interval", 30*time.Second, "How often to refresh discovery cache to pick up newly-installed resources")
+	discoveryInterval          = flag.Duration("discovery-interval", 10*time.Minute, "How often to fully refresh discovery cache, in addition to the refreshes triggered by CRD and APIService changes")
 	informerRelist             = flag.Duration("cache-flush-interval", 30*time.Minute, "How often to flush local caches and relist objects from the API server")
+	cacheStripAnnotations      = flag.String("cache-strip-annotations", "", "Comma-separated annotations to drop from cached objects, in addition to metadata.managedFields")
+	cacheStripFields           = flag.String("cache-strip-fields", "", "Comma-separated, dot-separated paths of fields to drop from cached objects, e.g. status.conditions")
 	metricsAddr                = flag.String("metrics-address", ":9999", "The address to bind metrics endpoint - /metrics")
 	clientGoQPS                = flag.Float64("client-go-qps", 5, "Number of queries per second client-go is allowed to make (default 5)")
 	clientGoBurst              = flag.Int("client-go-burst", 10, "Allowed burst queries for client-go (default 10)")
@@ -56,13 +63,31 @@ var (
 	leaderElectionNamespace    = flag.String("leader-election-namespace", "", "Determines the namespace in which the leader election resource will be created")
 	leaderElectionID           = flag.String("leader-election-id", "metacontroller", "Determines the name of the resource that leader election will use for holding the leader lock")
 	healthProbeBindAddress     = flag.String("health-probe-bind-address", ":8081", "The address the health probes endpoint binds to")
+	stuckQueueTimeout          = flag.Duration("stuck-queue-timeout", 10*time.Minute, "How long the workqueue of a controller may make no progress while items are queued before the liveness probe fails, 0 to disable")
 	targetLabelSelector        = flag.String("target-label-selector", "", "Label selector used to restrict an instance of metacontroller to manage specific Composite and Decorator controllers")
 	applyStrategy              = flag.String("apply-strategy", "dynamic-apply", "Strategy to use for applying changes to objects")
 	ssaFieldManager            = flag.String("apply-strategy-ssa-field-manager", "metacontroller", "FieldManager to use for server-side apply")
+	ssaMigrateFieldManagers    = flag.String("apply-strategy-ssa-migrate-field-managers", "metacontroller", "Comma-separated client-side field managers whose fields are moved to the server-side apply FieldManager when a child is first server-side applied")
+	lastAppliedEncoding        = flag.String("apply-strategy-last-applied-encoding", "json", "How dynamic apply stores the last-applied configuration of children: json, gzip or auto, which only compresses large configurations")
+	tracingEndpoint            = flag.String("tracing-endpoint", "", "The host:port of the OTLP gRPC collector to export traces of parent syncs to, empty to disable tracing")
+	tracingInsecure            = flag.Bool("tracing-insecure", false, "Connect to the OTLP collector without TLS")
+	tracingSampleRatio         = flag.Float64("tracing-sample-ratio", 1, "The fraction of parent syncs to trace, between 0 and 1")
+	auditLog                   = flag.String("audit-log", "", "The file to write a JSON-lines record of every write to parents and children to, - for stdout, empty to disable the audit log")
+	auditLogMaxSize            = flag.Int64("audit-log-max-size", 100, "The size in megabytes at which the audit log file is rotated, 0 to disable rotation")
+	auditLogMaxBackups         = flag.Int("audit-log-max-backups", 5, "The number of rotated audit log files to keep")
+	dryRun                     = flag.Bool("dry-run", false, "Compute everything as usual, but only log, record Events for and count the writes to objects instead of persisting them")
 	version                    = "No version provided"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -71,6 +96,8 @@ func main() {
 	logging.Logger.Info("Configuration information",
 		"discovery-interval", *discoveryInterval,
 		"cache-flush-interval", *informerRelist,
+		"cache-strip-annotations", *cacheStripAnnotations,
+		"cache-strip-fields", *cacheStripFields,
 		"metrics-address", *metricsAddr,
 		"client-go-qps", *clientGoQPS,
 		"client-go-burst", *clientGoBurst,
@@ -83,13 +110,39 @@ func main() {
 		"leader-election-namespace", *leaderElectionNamespace,
 		"leader-election-id", *leaderElectionID,
 		"health-probe-bind-address", *healthProbeBindAddress,
+		"stuck-queue-timeout", *stuckQueueTimeout,
 		"target-label-selector", *targetLabelSelector,
+		"tracing-endpoint", *tracingEndpoint,
+		"tracing-insecure", *tracingInsecure,
+		"tracing-sample-ratio", *tracingSampleRatio,
+		"audit-log", *auditLog,
+		"audit-log-max-size", *auditLogMaxSize,
+		"audit-log-max-backups", *auditLogMaxBackups,
+		"dry-run", *dryRun,
 		"version", version)
 
 	stopCtx := signals.SetupSignalHandler()
 
 	pprofStopChan := profile.EnablePprof(stopCtx, *pprofAddr)
 
+	shutdownTracing, err := tracing.Setup(stopCtx, tracing.Options{
+		Endpoint:    *tracingEndpoint,
+		Insecure:    *tracingInsecure,
+		SampleRatio: *tracingSampleRatio,
+		Version:     version,
+	})
+	if err != nil {
+		logging.Logger.Error(err, "Terminating")
+		os.Exit(1)
+	}
+
+	auditSink, err := audit.Open(*auditLog, *auditLogMaxSize*1024*1024, *auditLogMaxBackups)
+	if err != nil {
+		logging.Logger.Error(err, "Terminating")
+		os.Exit(1)
+	}
+	defer auditSink.Close()
+
 	config, err := controllerruntime.GetConfig()
 	if err != nil {
 		logging.Logger.Error(err, "Terminating")
@@ -99,18 +152,23 @@ func main() {
 	config.Burst = *clientGoBurst
 
 	configuration := options.Configuration{
-		RestConfig:        config,
-		DiscoveryInterval: *discoveryInterval,
-		InformerRelist:    *informerRelist,
-		Workers:           *workers,
-		ApplyStrategy:     *applyStrategy,
-		SsaFieldManager:   *ssaFieldManager,
+		RestConfig:              config,
+		DiscoveryInterval:       *discoveryInterval,
+		InformerRelist:          *informerRelist,
+		CacheStripAnnotations:   splitList(*cacheStripAnnotations),
+		CacheStripFields:        splitList(*cacheStripFields),
+		Workers:                 *workers,
+		ApplyStrategy:           *applyStrategy,
+		SsaFieldManager:         *ssaFieldManager,
+		SsaMigrateFieldManagers: splitList(*ssaMigrateFieldManagers),
+		LastAppliedEncoding:     *lastAppliedEncoding,
 		CorrelatorOptions: record.CorrelatorOptions{
 			BurstSize: *eventsBurst,
 			QPS:       float32(*eventsQPS),
 		},
 		MetricsEndpoint:        *metricsAddr,
 		HealthProbeBindAddress: *healthProbeBindAddress,
+		StuckQueueTimeout:      *stuckQueueTimeout,
 		LeaderElectionOptions: leaderelection.Options{
 			LeaderElection:             *leaderElection,
 			LeaderElectionResourceLock: *leaderElectionResourceLock,
@@ -118,6 +176,8 @@ func main() {
 			LeaderElectionID:           *leaderElectionID,
 		},
 		TargetLabelSelector: *targetLabelSelector,
+		DryRun:              *dryRun,
+		AuditSink:           auditSink,
 	}
 
 	// Create a new manager with a stop function
@@ -132,10 +192,6 @@ func main() {
 		logging.Logger.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err = mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		logging.Logger.Error(err, "unable to set up ready check")
-		os.Exit(1)
-	}
 
 	// Use a WaitGroup to make sure the metrics server
 	// and controller manager stop gracefully
@@ -160,4 +216,23 @@ func main() {
 	logging.Logger.Info("Stopped controller manager")
 
 	wg.Wait()
+
+	// stopCtx is already Done here, so flushing the pending spans must not
+	// inherit its cancellation.
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(stopCtx), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logging.Logger.Error(err, "Cannot flush traces")
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
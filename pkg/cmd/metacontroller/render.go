@@ -0,0 +1,149 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/render"
+)
+
+// fileList is a flag that may be given several times.
+type fileList []string
+
+func (f *fileList) String() string {
+	return fmt.Sprint(*f)
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runRender runs the render subcommand, which syncs a parent against local
+// objects, without an API server, and prints the outcome as YAML.
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	controller := flags.String("controller", "", "The file of the CompositeController or DecoratorController to render")
+	parent := flags.String("parent", "", "The file of the parent to sync")
+	var children, related, crds fileList
+	flags.Var(&children, "children", "A file of observed children or attachments of the parent, may be repeated")
+	flags.Var(&related, "related", "A file of related objects, as the customize hook would select them, may be repeated")
+	flags.Var(&crds, "crds", "A file of CustomResourceDefinitions of the resources of the controller, may be repeated")
+	hookURL := flags.String("hook-url", "", "The URL to call the hooks at instead of their webhook URL")
+	hookResponse := flags.String("hook-response", "", "A file of the response to return from the hooks instead of calling them")
+	showRequest := flags.Bool("show-request", false, "Print the request sent to the hook too")
+	opts := zap.Options{}
+	opts.BindFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s render --controller FILE --parent FILE [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	logging.InitLogging(&opts)
+
+	if *controller == "" || *parent == "" {
+		flags.Usage()
+		return fmt.Errorf("--controller and --parent are required")
+	}
+	in := render.Input{HookURL: *hookURL}
+	var err error
+	if in.Controller, err = readObject(*controller); err != nil {
+		return err
+	}
+	if in.Parent, err = readObject(*parent); err != nil {
+		return err
+	}
+	if in.Children, err = readObjects(children...); err != nil {
+		return err
+	}
+	if in.Related, err = readObjects(related...); err != nil {
+		return err
+	}
+	if in.CRDs, err = readObjects(crds...); err != nil {
+		return err
+	}
+	if *hookResponse != "" {
+		data, err := os.ReadFile(*hookResponse)
+		if err != nil {
+			return err
+		}
+		response, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return fmt.Errorf("can't read hook response %v: %w", *hookResponse, err)
+		}
+		in.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(response)
+		})
+	}
+
+	out, err := render.Render(context.Background(), in)
+	if err != nil {
+		return err
+	}
+	if !*showRequest {
+		out.Request = nil
+	}
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// readObject reads the only object of a file.
+func readObject(file string) (*unstructured.Unstructured, error) {
+	objects, err := readObjects(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) != 1 {
+		return nil, fmt.Errorf("want one object in %v, got %d", file, len(objects))
+	}
+	return objects[0], nil
+}
+
+// readObjects reads the objects of the given files.
+func readObjects(files ...string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		fileObjects, err := render.ReadObjects(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("can't read %v: %w", file, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
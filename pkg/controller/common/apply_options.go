@@ -0,0 +1,216 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/redact"
+)
+
+// ChildApplyOptions returns the apply options for children of a given kind.
+type ChildApplyOptions interface {
+	GetApplyOptions(apiGroup, kind string) *ApplyOptions
+}
+
+// GetApplyOptions returns the options themselves, since they apply to
+// children of all kinds.
+func (o *ApplyOptions) GetApplyOptions(apiGroup, kind string) *ApplyOptions {
+	return o
+}
+
+// IsForced reports whether server-side apply should take ownership of
+// conflicting fields.
+func (o *ApplyOptions) IsForced() bool {
+	return o.Force == nil || *o.Force
+}
+
+// WithStrategy returns a copy of the options, with the fields that are set in
+// the given apply strategy overridden.
+func (o *ApplyOptions) WithStrategy(strategy *v1alpha1.ApplyStrategy) (*ApplyOptions, error) {
+	result := &ApplyOptions{}
+	if o != nil {
+		*result = *o
+	}
+	if strategy == nil {
+		return result, nil
+	}
+	switch strategy.Type {
+	case "":
+	case v1alpha1.ApplyStrategyDynamicApply:
+		result.Strategy = ApplyStrategyDynamicApply
+	case v1alpha1.ApplyStrategyServerSideApply:
+		result.Strategy = ApplyStrategyServerSideApply
+	default:
+		return nil, fmt.Errorf("invalid apply strategy: unknown type %q", strategy.Type)
+	}
+	if strategy.FieldManager != "" {
+		result.FieldManager = strategy.FieldManager
+	}
+	if strategy.Force != nil {
+		result.Force = strategy.Force
+	}
+	if strategy.MigrateFieldManagers != nil {
+		result.MigrateFieldManagers = strategy.MigrateFieldManagers
+	}
+	if strategy.LastAppliedEncoding != "" {
+		encoding := dynamicapply.LastAppliedEncoding(strategy.LastAppliedEncoding)
+		if err := dynamicapply.ValidateLastAppliedEncoding(encoding); err != nil {
+			return nil, fmt.Errorf("invalid apply strategy: %w", err)
+		}
+		result.LastAppliedEncoding = encoding
+	}
+	return result, nil
+}
+
+// ApplyOptionsMap holds the apply options of a controller, with overrides
+// for some child kinds.
+type ApplyOptionsMap struct {
+	defaults *ApplyOptions
+	kinds    map[schema.GroupKind]*ApplyOptions
+}
+
+// NewApplyOptionsMap returns the apply options of a controller with the given
+// apply strategy, falling back to the global options.
+func NewApplyOptionsMap(global *ApplyOptions, strategy *v1alpha1.ApplyStrategy) (*ApplyOptionsMap, error) {
+	defaults, err := global.WithStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	return &ApplyOptionsMap{
+		defaults: defaults,
+		kinds:    make(map[schema.GroupKind]*ApplyOptions),
+	}, nil
+}
+
+// Set overrides the apply options of the controller for children of the
+// given kind.
+func (m *ApplyOptionsMap) Set(apiGroup, kind string, strategy *v1alpha1.ApplyStrategy) error {
+	if strategy == nil {
+		return nil
+	}
+	options, err := m.defaults.WithStrategy(strategy)
+	if err != nil {
+		return err
+	}
+	m.kinds[schema.GroupKind{Group: apiGroup, Kind: kind}] = options
+	return nil
+}
+
+// SetManageStatus makes the controller write the desired status of children
+// of the given kind.
+func (m *ApplyOptionsMap) SetManageStatus(apiGroup, kind string) {
+	key := schema.GroupKind{Group: apiGroup, Kind: kind}
+	options, ok := m.kinds[key]
+	if !ok {
+		options, _ = m.defaults.WithStrategy(nil)
+		m.kinds[key] = options
+	}
+	options.ManageStatus = true
+}
+
+// SetDryRun sets the dry-run reporter of the controller, for children of all
+// kinds. A nil reporter makes the controller persist its writes.
+func (m *ApplyOptionsMap) SetDryRun(dryRun *DryRun) {
+	m.defaults.DryRun = dryRun
+	for _, options := range m.kinds {
+		options.DryRun = dryRun
+	}
+}
+
+// SetEventRecorder sets the recorder of Events about children of the
+// controller, for children of all kinds.
+func (m *ApplyOptionsMap) SetEventRecorder(eventRecorder record.EventRecorder) {
+	m.defaults.EventRecorder = eventRecorder
+	for _, options := range m.kinds {
+		options.EventRecorder = eventRecorder
+	}
+}
+
+// SetMetrics sets the metrics of the controller, which count the writes to
+// children of all kinds.
+func (m *ApplyOptionsMap) SetMetrics(metrics *ControllerMetrics) {
+	m.defaults.Metrics = metrics
+	for _, options := range m.kinds {
+		options.Metrics = metrics
+	}
+}
+
+// SetAudit sets the audit recorder of the controller, for children of all
+// kinds.
+func (m *ApplyOptionsMap) SetAudit(audit *Audit) {
+	m.defaults.Audit = audit
+	for _, options := range m.kinds {
+		options.Audit = audit
+	}
+}
+
+// SetRedactor sets the redactor of the controller, for children of all kinds.
+func (m *ApplyOptionsMap) SetRedactor(redactor *redact.Redactor) {
+	m.defaults.Redactor = redactor
+	for _, options := range m.kinds {
+		options.Redactor = redactor
+	}
+}
+
+// DryRun returns the dry-run reporter of the controller, or nil if the
+// controller persists its writes.
+func (m *ApplyOptionsMap) DryRun() *DryRun {
+	if m == nil {
+		return nil
+	}
+	return m.defaults.DryRun
+}
+
+// Audit returns the audit recorder of the controller, or nil if its writes
+// aren't recorded.
+func (m *ApplyOptionsMap) Audit() *Audit {
+	if m == nil {
+		return nil
+	}
+	return m.defaults.Audit
+}
+
+// Redactor returns the redactor of the controller.
+func (m *ApplyOptionsMap) Redactor() *redact.Redactor {
+	if m == nil {
+		return nil
+	}
+	return m.defaults.Redactor
+}
+
+// Stripped returns what the informer caches of the controller drop from
+// objects.
+func (m *ApplyOptionsMap) Stripped() dynamicinformer.StripOptions {
+	if m == nil {
+		return dynamicinformer.StripOptions{}
+	}
+	return m.defaults.Stripped
+}
+
+func (m *ApplyOptionsMap) GetApplyOptions(apiGroup, kind string) *ApplyOptions {
+	if options, ok := m.kinds[schema.GroupKind{Group: apiGroup, Kind: kind}]; ok {
+		return options
+	}
+	return m.defaults
+}
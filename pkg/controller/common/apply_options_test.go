@@ -0,0 +1,75 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/ptr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+func TestApplyOptionsMap(t *testing.T) {
+	global := &ApplyOptions{FieldManager: "metacontroller", Strategy: ApplyStrategyDynamicApply}
+
+	m, err := NewApplyOptionsMap(global, &v1alpha1.ApplyStrategy{
+		Type:         v1alpha1.ApplyStrategyServerSideApply,
+		FieldManager: "my-controller",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, m.Set("apps", "Deployment", &v1alpha1.ApplyStrategy{Force: ptr.To(false)}))
+	assert.NoError(t, m.Set("", "ConfigMap", &v1alpha1.ApplyStrategy{Type: v1alpha1.ApplyStrategyDynamicApply}))
+	assert.NoError(t, m.Set("", "Secret", nil))
+
+	pod := m.GetApplyOptions("", "Pod")
+	assert.Equal(t, ApplyStrategyServerSideApply, pod.Strategy)
+	assert.Equal(t, "my-controller", pod.FieldManager)
+	assert.True(t, pod.IsForced())
+
+	deployment := m.GetApplyOptions("apps", "Deployment")
+	assert.Equal(t, ApplyStrategyServerSideApply, deployment.Strategy)
+	assert.Equal(t, "my-controller", deployment.FieldManager)
+	assert.False(t, deployment.IsForced())
+
+	assert.Equal(t, ApplyStrategyDynamicApply, m.GetApplyOptions("", "ConfigMap").Strategy)
+	assert.Same(t, pod, m.GetApplyOptions("", "Secret"))
+
+	// The global options are left alone.
+	assert.Equal(t, &ApplyOptions{FieldManager: "metacontroller", Strategy: ApplyStrategyDynamicApply}, global)
+}
+
+func TestApplyOptionsMap_invalidType(t *testing.T) {
+	_, err := NewApplyOptionsMap(&ApplyOptions{}, &v1alpha1.ApplyStrategy{Type: "Replace"})
+	assert.Error(t, err)
+
+	m, err := NewApplyOptionsMap(&ApplyOptions{}, nil)
+	assert.NoError(t, err)
+	assert.Error(t, m.Set("", "Pod", &v1alpha1.ApplyStrategy{Type: "Replace"}))
+}
+
+func TestApplyOptionsMap_lastAppliedEncoding(t *testing.T) {
+	m, err := NewApplyOptionsMap(&ApplyOptions{LastAppliedEncoding: dynamicapply.LastAppliedEncodingAuto}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Set("", "ConfigMap", &v1alpha1.ApplyStrategy{LastAppliedEncoding: v1alpha1.LastAppliedEncodingGzip}))
+	assert.Error(t, m.Set("", "Secret", &v1alpha1.ApplyStrategy{LastAppliedEncoding: "Zstd"}))
+
+	assert.Equal(t, dynamicapply.LastAppliedEncodingGzip, m.GetApplyOptions("", "ConfigMap").LastAppliedEncoding)
+	assert.Equal(t, dynamicapply.LastAppliedEncodingAuto, m.GetApplyOptions("", "Pod").LastAppliedEncoding)
+}
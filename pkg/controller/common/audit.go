@@ -0,0 +1,151 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/audit"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
+)
+
+// Operations on parents and children, as recorded in the audit log.
+const (
+	AuditCreate       = childCreate
+	AuditUpdate       = childUpdate
+	AuditUpdateStatus = childUpdateStatus
+	AuditDelete       = childDelete
+)
+
+// Audit records the writes of a controller to the audit log. A nil *Audit
+// records nothing.
+type Audit struct {
+	sink       *audit.Sink
+	controller string
+	redactor   *redact.Redactor
+	now        func() time.Time
+}
+
+// NewAudit returns an audit recorder that writes to the given sink, or nil if
+// the sink is nil.
+func NewAudit(sink *audit.Sink) *Audit {
+	if sink == nil {
+		return nil
+	}
+	return &Audit{sink: sink, now: time.Now}
+}
+
+// ForController returns a recorder of the writes of the given controller,
+// whose redactor masks sensitive fields in the merge patches.
+func (a *Audit) ForController(controllerKind, controllerName string, redactor *redact.Redactor) *Audit {
+	if a == nil {
+		return nil
+	}
+	return &Audit{sink: a.sink, controller: controllerKind + "/" + controllerName, redactor: redactor, now: a.now}
+}
+
+// Record records a write made for the parent. The original object is nil for
+// creates, and updated is nil for deletes. Updates that only change the
+// system fields of the metadata, like the resourceVersion, aren't recorded.
+func (a *Audit) Record(ctx context.Context, parent *unstructured.Unstructured, operation string, original, updated *unstructured.Unstructured) {
+	if a == nil {
+		return
+	}
+	obj := updated
+	if obj == nil {
+		obj = original
+	}
+
+	var patch []byte
+	if updated != nil {
+		from := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		if original != nil {
+			from = withoutSystemFields(original)
+		}
+		var err error
+		if patch, err = JsonMergePatch(from, withoutSystemFields(updated)); err != nil {
+			logging.Logger.Error(err, "Cannot create merge patch for audit log", "controller", a.controller, "object", obj)
+		} else if original != nil && string(patch) == "{}" {
+			return
+		}
+	}
+
+	a.write(ctx, parent, obj, operation, patch)
+}
+
+// RecordFinalizers records an update of the finalizers of the parent, which
+// resulted in the updated parent.
+func (a *Audit) RecordFinalizers(ctx context.Context, parent, updated *unstructured.Unstructured) {
+	if a == nil {
+		return
+	}
+	// Lists are replaced as a whole by merge patches.
+	var finalizers interface{}
+	if len(updated.GetFinalizers()) > 0 {
+		finalizers = updated.GetFinalizers()
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"finalizers": finalizers},
+	})
+	if err != nil {
+		logging.Logger.Error(err, "Cannot create merge patch for audit log", "controller", a.controller, "object", parent)
+	}
+	a.write(ctx, parent, parent, AuditUpdate, patch)
+}
+
+func (a *Audit) write(ctx context.Context, parent, obj *unstructured.Unstructured, operation string, patch []byte) {
+	record := audit.Record{
+		Time:             a.now(),
+		Controller:       a.controller,
+		Object:           objectReference(obj),
+		Operation:        operation,
+		MergePatch:       json.RawMessage(a.redactor.Patch(patch, obj)),
+		HookResponseETag: audit.HookResponseETag(ctx),
+	}
+	if parent != nil {
+		ref := objectReference(parent)
+		record.Parent = &ref
+	}
+	if err := a.sink.Write(record); err != nil {
+		logging.Logger.Error(err, "Cannot write audit record", "controller", a.controller, "object", obj)
+	}
+}
+
+// withoutSystemFields returns a copy of the object without the read-only
+// system fields of its metadata.
+func withoutSystemFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	c := obj.DeepCopy()
+	for _, fieldName := range objectMetaSystemFields {
+		unstructured.RemoveNestedField(c.Object, "metadata", fieldName)
+	}
+	return c
+}
+
+func objectReference(obj *unstructured.Unstructured) audit.ObjectReference {
+	return audit.ObjectReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+	}
+}
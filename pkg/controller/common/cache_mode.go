@@ -0,0 +1,176 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cespare/xxhash/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+// NewResourceInformer returns a shared informer for the given resource, which
+// caches full objects or only their metadata, as the cache mode says.
+func NewResourceInformer(ctx context.Context, dynInformers *dynamicinformer.SharedInformerFactory, apiVersion, resource string, cacheMode v1alpha1.CacheMode) (*dynamicinformer.ResourceInformer, error) {
+	switch cacheMode {
+	case v1alpha1.CacheModeFull, "":
+		return dynInformers.Resource(ctx, apiVersion, resource)
+	case v1alpha1.CacheModeMetadata, v1alpha1.CacheModeMetadataOnly:
+		return dynInformers.MetadataResource(ctx, apiVersion, resource)
+	default:
+		return nil, fmt.Errorf("unknown cache mode %q", cacheMode)
+	}
+}
+
+// ValidateChildCacheMode checks that children are cached in full, if they
+// may be updated. Updates are computed from the observed children, so they
+// need the full objects.
+func ValidateChildCacheMode(cacheMode v1alpha1.CacheMode, method v1alpha1.ChildUpdateMethod) error {
+	if cacheMode == v1alpha1.CacheModeMetadataOnly && method != "" && method != v1alpha1.ChildUpdateOnDelete {
+		return fmt.Errorf("cache mode %q requires the %q update method, got %q", cacheMode, v1alpha1.ChildUpdateOnDelete, method)
+	}
+	return nil
+}
+
+// FetchObjects returns the full objects for objects that the informer caches
+// only the metadata of, if the cache mode says so. It gets each object from
+// the API server, leaving out the objects that are gone.
+func FetchObjects(ctx context.Context, client *dynamicclientset.ResourceClient, cacheMode v1alpha1.CacheMode, objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	if cacheMode != v1alpha1.CacheModeMetadata {
+		return objects, nil
+	}
+	return GetLiveObjects(ctx, client, objects)
+}
+
+// GetLiveObjects gets the current state of cached objects from the API
+// server, leaving out the objects that are gone.
+func GetLiveObjects(ctx context.Context, client *dynamicclientset.ResourceClient, objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		full, err := GetLive(ctx, client, obj)
+		if err != nil {
+			return nil, err
+		}
+		if full != nil {
+			result = append(result, full)
+		}
+	}
+	return result, nil
+}
+
+// GetLive gets the current state of a cached object from the API server. It
+// returns nil if the object is gone or was replaced, in which case the
+// informer will tell us about it.
+func GetLive(ctx context.Context, client *dynamicclientset.ResourceClient, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	live, err := client.Namespace(obj.GetNamespace()).Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("can't get %v: %w", describeObject(obj), err)
+	}
+	if live.GetUID() != obj.GetUID() {
+		return nil, nil
+	}
+	return live, nil
+}
+
+// lastAppliedRecords remembers, by its hash, the configuration that dynamic
+// apply last applied to each child, so that children don't have to be read
+// to learn it when the caches drop the last-applied annotation.
+var lastAppliedRecords = NewLastUpdateCache()
+
+func lastAppliedKey(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	return fmt.Sprintf("%s/%s/%s/%s", gvk.Group, gvk.Kind, obj.GetNamespace(), obj.GetName())
+}
+
+// lastAppliedOf returns the configuration that dynamic apply stores in the
+// last-applied annotation when it applies the desired object.
+func lastAppliedOf(desired *unstructured.Unstructured) map[string]interface{} {
+	lastApplied := desired.DeepCopy()
+	nullifyLastAppliedAnnotation(lastApplied)
+	return lastApplied.UnstructuredContent()
+}
+
+func hashLastApplied(lastApplied map[string]interface{}) (uint64, bool) {
+	data, err := json.Marshal(lastApplied)
+	if err != nil {
+		return 0, false
+	}
+	return xxhash.Sum64(data), true
+}
+
+// recordLastApplied remembers that the child has the last-applied
+// configuration of the desired object.
+func recordLastApplied(child, desired *unstructured.Unstructured) {
+	if hash, ok := hashLastApplied(lastAppliedOf(desired)); ok {
+		lastAppliedRecords.Set(lastAppliedKey(child), hash, 0, "", child.GetUID())
+	}
+}
+
+// restoreLastApplied returns a copy of a cached child with the last-applied
+// configuration of the desired object, if that's what dynamic apply last
+// applied to the child. It returns nil if the configuration isn't known.
+func restoreLastApplied(cached, desired *unstructured.Unstructured, encoding dynamicapply.LastAppliedEncoding) *unstructured.Unstructured {
+	record, ok := lastAppliedRecords.Load(lastAppliedKey(cached))
+	if !ok || record.uid != cached.GetUID() {
+		return nil
+	}
+	lastApplied := lastAppliedOf(desired)
+	if hash, ok := hashLastApplied(lastApplied); !ok || hash != record.hash {
+		return nil
+	}
+	restored := cached.DeepCopy()
+	if err := dynamicapply.SetLastAppliedWithEncoding(restored, lastApplied, encoding); err != nil {
+		return nil
+	}
+	return restored
+}
+
+// ObservedForApply returns the child to compute the dynamic apply of the
+// desired object from, and whether it was read from the API server. That's the
+// cached child, unless the comparison depends on data that the caches drop:
+// the desired object sets a stripped field, or the last-applied annotation is
+// stripped and, as far as this process knows, the configuration last applied
+// to the child isn't the desired one. Then it's the live child, or nil if the
+// child is gone or was replaced.
+func ObservedForApply(ctx context.Context, client *dynamicclientset.ResourceClient, stripped dynamicinformer.StripOptions, cached, desired *unstructured.Unstructured, encoding dynamicapply.LastAppliedEncoding) (*unstructured.Unstructured, bool, error) {
+	if !stripped.Sets(desired.UnstructuredContent()) {
+		if !stripped.StripsAnnotation(dynamicapply.LastAppliedAnnotation) {
+			return cached, false, nil
+		}
+		if _, ok := cached.GetAnnotations()[dynamicapply.LastAppliedAnnotation]; ok {
+			// The child was read from the API server already.
+			return cached, true, nil
+		}
+		if restored := restoreLastApplied(cached, desired, encoding); restored != nil {
+			return restored, false, nil
+		}
+	}
+	live, err := GetLive(ctx, client, cached)
+	return live, live != nil, err
+}
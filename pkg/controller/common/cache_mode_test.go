@@ -0,0 +1,96 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	. "metacontroller/pkg/internal/testutils/common"
+	. "metacontroller/pkg/internal/testutils/dynamic/clientset"
+	. "metacontroller/pkg/internal/testutils/dynamic/discovery"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestFetchObjects(t *testing.T) {
+	full := NewDefaultUnstructured()
+	full.SetName("full")
+	full.SetUID("full-uid")
+	full.Object["spec"] = map[string]interface{}{"key": "value"}
+	replaced := NewDefaultUnstructured()
+	replaced.SetName("replaced")
+	replaced.SetUID("new-uid")
+
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+	dynClient := NewClientset(NewDefaultRestConfig(), testResourceMap, fake.NewSimpleDynamicClient(scheme, full.DeepCopy(), replaced.DeepCopy()))
+	client, err := dynClient.Kind(TestAPIVersion, TestKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadataOf := func(name, uid string) *unstructured.Unstructured {
+		obj := NewDefaultUnstructured()
+		obj.SetName(name)
+		obj.SetUID(types.UID(uid))
+		return obj
+	}
+	cached := []*unstructured.Unstructured{
+		metadataOf("full", "full-uid"),
+		metadataOf("replaced", "old-uid"),
+		metadataOf("gone", "gone-uid"),
+	}
+
+	objects, err := FetchObjects(context.TODO(), client, v1alpha1.CacheModeFull, cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != len(cached) {
+		t.Errorf("FetchObjects() with cache mode Full returned %d objects, want the %d cached ones", len(objects), len(cached))
+	}
+
+	objects, err = FetchObjects(context.TODO(), client, v1alpha1.CacheModeMetadata, cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || !DeepEqual(objects[0].UnstructuredContent(), full.UnstructuredContent()) {
+		t.Errorf("FetchObjects() with cache mode Metadata = %v, want only %v", objects, full)
+	}
+}
+
+func TestValidateChildCacheMode(t *testing.T) {
+	tests := []struct {
+		cacheMode v1alpha1.CacheMode
+		method    v1alpha1.ChildUpdateMethod
+		wantErr   bool
+	}{
+		{cacheMode: "", method: v1alpha1.ChildUpdateInPlace},
+		{cacheMode: v1alpha1.CacheModeMetadata, method: v1alpha1.ChildUpdateRollingInPlace},
+		{cacheMode: v1alpha1.CacheModeMetadataOnly, method: ""},
+		{cacheMode: v1alpha1.CacheModeMetadataOnly, method: v1alpha1.ChildUpdateOnDelete},
+		{cacheMode: v1alpha1.CacheModeMetadataOnly, method: v1alpha1.ChildUpdateRecreate, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.cacheMode)+"/"+string(tt.method), func(t *testing.T) {
+			if err := ValidateChildCacheMode(tt.cacheMode, tt.method); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateChildCacheMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
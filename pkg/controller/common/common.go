@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"metacontroller/pkg/logging"
 	"metacontroller/pkg/options"
+	"metacontroller/pkg/redact"
 	"strings"
 	"sync"
 
@@ -29,10 +30,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
 
 	"metacontroller/pkg/events"
 
@@ -93,13 +96,20 @@ type ControllerContext struct {
 	EventRecorder     record.EventRecorder
 	Broadcaster       record.EventBroadcaster
 	configuration     options.Configuration
+	// metadataClient watches CRDs and APIServices to refresh discovery.
+	metadataClient metadata.Interface
 }
 
 // NewControllerContext creates a new ControllerContext using given Configuration and metacontroller client
 func NewControllerContext(configuration options.Configuration, mcClient *mcclientset.Clientset) (*ControllerContext, error) {
-	// Periodically refresh discovery to pick up newly-installed resources.
+	// Refresh discovery to pick up newly-installed resources, both
+	// periodically and whenever CRDs or APIServices change.
 	dc := discovery.NewDiscoveryClientForConfigOrDie(configuration.RestConfig)
 	resources := dynamicdiscovery.NewResourceMap(dc)
+	metadataClient, err := metadata.NewForConfig(configuration.RestConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	mcInformerFactory := mcinformers.NewSharedInformerFactory(mcClient, configuration.InformerRelist)
 
@@ -110,6 +120,10 @@ func NewControllerContext(configuration options.Configuration, mcClient *mcclien
 	}
 	// Create dynamic informer factory (for sharing dynamic informers).
 	dynInformers := dynamicinformer.NewSharedInformerFactory(dynClient, configuration.InformerRelist)
+	dynInformers.SetStripOptions(dynamicinformer.StripOptions{
+		Annotations: configuration.CacheStripAnnotations,
+		Fields:      configuration.CacheStripFields,
+	})
 
 	// Start metacontrollers (controllers that spawn controllers).
 	// Each one requests the informers it needs from the factory.
@@ -127,6 +141,7 @@ func NewControllerContext(configuration options.Configuration, mcClient *mcclien
 		EventRecorder:     recorder,
 		Broadcaster:       broadcaster,
 		configuration:     configuration,
+		metadataClient:    metadataClient,
 	}, nil
 }
 
@@ -134,6 +149,7 @@ func NewControllerContext(configuration options.Configuration, mcClient *mcclien
 // Informers created after Start is called will not be automatically started
 func (controllerContext ControllerContext) Start(ctx context.Context) {
 	controllerContext.Resources.Start(ctx, controllerContext.configuration.DiscoveryInterval)
+	controllerContext.Resources.Watch(ctx, controllerContext.metadataClient)
 	// Start all requested informers.
 	controllerContext.McInformerFactory.Start(ctx.Done())
 }
@@ -280,6 +296,37 @@ func HasStatusSubresource(crd *v1.CustomResourceDefinition, version string) bool
 type ApplyOptions struct {
 	FieldManager string
 	Strategy     ApplyStrategy
+	// Force makes server-side apply take ownership of conflicting fields.
+	// Defaults to true.
+	Force *bool
+	// MigrateFieldManagers are the client-side field managers whose fields
+	// are moved to FieldManager when server-side apply first updates a child
+	// that was written by dynamic apply.
+	MigrateFieldManagers []string
+	// LastAppliedEncoding is how dynamic apply stores the last-applied
+	// configuration of children.
+	LastAppliedEncoding dynamicapply.LastAppliedEncoding
+	// ManageStatus makes the controller write the desired status of children
+	// through their status subresource.
+	ManageStatus bool
+	// EventRecorder records Events about children on their parent.
+	EventRecorder record.EventRecorder
+	// DryRun reports the writes that aren't persisted because the controller
+	// is in dry-run mode. It is nil if writes are persisted.
+	DryRun *DryRun
+	// Stripped is what the informer caches drop from objects, which the
+	// controller reads from the API server when an update depends on it.
+	Stripped dynamicinformer.StripOptions
+	// Metrics counts the writes to children. It is nil if they aren't
+	// counted.
+	Metrics *ControllerMetrics
+	// Audit records the writes to parents and children. It is nil if they
+	// aren't recorded.
+	Audit *Audit
+	// Redactor masks sensitive fields of children in the payloads that are
+	// logged, recorded and attached to Events. A nil Redactor only masks the
+	// data of Secrets.
+	Redactor *redact.Redactor
 }
 
 type ApplyStrategy string
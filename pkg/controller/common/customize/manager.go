@@ -18,7 +18,6 @@ package customize
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
@@ -28,6 +27,7 @@ import (
 	v1 "metacontroller/pkg/controller/common/customize/api/v1"
 	v2 "metacontroller/pkg/controller/common/customize/api/v2"
 	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
 
 	"k8s.io/apimachinery/pkg/types"
 	clientgo_cache "k8s.io/client-go/tools/cache"
@@ -72,7 +72,11 @@ type Manager struct {
 
 	nsInformer       *dynamicinformer.ResourceInformer
 	relatedInformers *common.InformerMap
-	customizeCache   *cache.Cache[customizeKey, *v1.CustomizeHookResponse]
+	// relatedMetadataInformers cache only the metadata of related objects,
+	// for rules that ask for it.
+	relatedMetadataInformers *common.InformerMap
+	customizeCache           *cache.Cache[customizeKey, *v1.CustomizeHookResponse]
+	relatedIndex             *relatedParentIndex
 
 	ctx context.Context
 
@@ -88,6 +92,10 @@ type Manager struct {
 
 	customizeHook hooks.Hook
 
+	// static holds the related objects of all parents instead of the ones
+	// the rules select, if it's set.
+	static api.ObjectMap
+
 	logger logr.Logger
 }
 
@@ -133,6 +141,9 @@ func NewCustomizeManager(
 	} else {
 		hook = nil
 	}
+	if err := validateRelatedRuleTemplates(controller.GetRelatedResourceRules()); err != nil {
+		return nil, err
+	}
 	if parentInformers == nil {
 		parentInformers = common.NewInformerMap()
 	}
@@ -148,41 +159,83 @@ func NewCustomizeManager(
 	}
 
 	return &Manager{
-		name:             name,
-		controller:       controller,
-		parentKinds:      parentKinds,
-		customizeCache:   newResponseCache(),
-		dynClient:        dynClient,
-		dynInformers:     dynInformers,
-		parentInformers:  parentInformers,
-		relatedInformers: common.NewInformerMap(),
-		nsInformer:       nsInformer,
-		enqueueParent:    enqueueParent,
-		customizeHook:    hook,
-		logger:           logger,
-		ctx:              ctx,
-		informerCtx:      ctx,
+		name:                     name,
+		controller:               controller,
+		parentKinds:              parentKinds,
+		customizeCache:           newResponseCache(),
+		relatedIndex:             newRelatedParentIndex(),
+		dynClient:                dynClient,
+		dynInformers:             dynInformers,
+		parentInformers:          parentInformers,
+		relatedInformers:         common.NewInformerMap(),
+		relatedMetadataInformers: common.NewInformerMap(),
+		nsInformer:               nsInformer,
+		enqueueParent:            enqueueParent,
+		customizeHook:            hook,
+		logger:                   logger,
+		ctx:                      ctx,
+		informerCtx:              ctx,
 	}, nil
 }
 
+// NewStaticManager returns a manager that returns the given related objects
+// for all parents, without calling the customize hook or watching anything.
+func NewStaticManager(related api.ObjectMap) *Manager {
+	return &Manager{static: related}
+}
+
+// IsEnabled returns true if related objects are configured, either by
+// a customize hook or by declarative related resource rules.
 func (rm *Manager) IsEnabled() bool {
+	return rm.isHookEnabled() || len(rm.controller.GetRelatedResourceRules()) != 0
+}
+
+func (rm *Manager) isHookEnabled() bool {
 	return rm.customizeHook != nil && rm.customizeHook.IsEnabled()
 }
 
 func (rm *Manager) Start(ctx context.Context) {
 	rm.ctx = ctx
+
+	// Drop the related rules of deleted parents from the reverse index.
+	// The handlers are removed together with the controller's own handlers
+	// when the controller stops.
+	rm.parentInformers.ForEach(func(_ schema.GroupVersionResource, informer *dynamicinformer.ResourceInformer) {
+		_, err := informer.Informer().AddEventHandler(clientgo_cache.ResourceEventHandlerFuncs{
+			DeleteFunc: rm.onParentDelete,
+		})
+		if err != nil {
+			rm.logger.Error(err, "Unable to AddEventHandler to Parent Informer")
+		}
+	})
 }
 
 func (rm *Manager) Stop() {
-	rm.relatedInformers.ForEach(func(_ schema.GroupVersionResource, informer *dynamicinformer.ResourceInformer) {
-		informer.Informer().RemoveEventHandlers()
-		informer.Close()
-	})
+	for _, informers := range []*common.InformerMap{rm.relatedInformers, rm.relatedMetadataInformers} {
+		informers.ForEach(func(_ schema.GroupVersionResource, informer *dynamicinformer.ResourceInformer) {
+			informer.Informer().RemoveEventHandlers()
+			informer.Close()
+		})
+	}
 	if rm.nsInformer != nil {
 		rm.nsInformer.Close()
 	}
 }
 
+// CacheSize returns the number of cached customize hook responses.
+func (rm *Manager) CacheSize() int {
+	return rm.customizeCache.Len()
+}
+
+// ETagCacheSize returns the number of responses that the customize hook
+// caches for ETags.
+func (rm *Manager) ETagCacheSize() int {
+	if rm.customizeHook == nil {
+		return 0
+	}
+	return hooks.CacheSize(rm.customizeHook)
+}
+
 func (rm *Manager) getCachedCustomizeHookResponse(parent *unstructured.Unstructured) (*v1.CustomizeHookResponse, bool) {
 	return rm.customizeCache.Get(customizeKey{parent.GetUID(), parent.GetGeneration()})
 }
@@ -196,6 +249,30 @@ func (rm *Manager) getCustomizeHookResponse(ctx context.Context, parent *unstruc
 		return cached, nil
 	}
 
+	// Declarative rules follow the v2 semantics unless a customize hook
+	// determines the version.
+	response := &v1.CustomizeHookResponse{Version: v1alpha1.HookVersionV2}
+	if rm.isHookEnabled() {
+		var err error
+		response, err = rm.callCustomizeHook(ctx, parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	declaredRules, err := evaluateRelatedRuleTemplates(rm.controller.GetRelatedResourceRules(), parent)
+	if err != nil {
+		return nil, err
+	}
+	response.RelatedResourceRules = append(response.RelatedResourceRules, declaredRules...)
+
+	rm.customizeCache.Set(customizeKey{parent.GetUID(), parent.GetGeneration()}, response)
+	rm.relatedIndex.update(parent, response)
+	return response, nil
+}
+
+func (rm *Manager) callCustomizeHook(ctx context.Context, parent *unstructured.Unstructured) (*v1.CustomizeHookResponse, error) {
+	ctx = logging.NewContext(ctx, rm.logger)
 	hookVersion := rm.customizeHook.GetVersion()
 
 	var requestBuilder customizecommon.WebhookRequestBuilder
@@ -224,8 +301,6 @@ func (rm *Manager) getCustomizeHookResponse(ctx context.Context, parent *unstruc
 		}
 	}
 	v1Response.Version = hookVersion
-
-	rm.customizeCache.Set(customizeKey{parent.GetUID(), parent.GetGeneration()}, v1Response)
 	return v1Response, nil
 }
 
@@ -235,9 +310,20 @@ func (rm *Manager) convertV2ToV1Response(v2Response v2.CustomizeHookResponse) *v
 	}
 }
 
-var ErrRelatedInformerNotSynced = errors.New("related informer not synced yet")
+var ErrRelatedInformerNotSynced error = relatedInformerNotSyncedError{}
+
+type relatedInformerNotSyncedError struct{}
+
+func (relatedInformerNotSyncedError) Error() string {
+	return "related informer not synced yet"
+}
 
-func (rm *Manager) getRelatedClient(apiVersion, resource string) (*dynamicclientset.ResourceClient, *dynamicinformer.ResourceInformer, error) {
+// SyncErrorReason implements common.SyncErrorReasoner.
+func (relatedInformerNotSyncedError) SyncErrorReason() string {
+	return "RelatedInformerNotSynced"
+}
+
+func (rm *Manager) getRelatedClient(apiVersion, resource string, cacheMode v1alpha1.CacheMode) (*dynamicclientset.ResourceClient, *dynamicinformer.ResourceInformer, error) {
 	if rm.ctx == nil {
 		return nil, nil, fmt.Errorf("customize Manager not started")
 	}
@@ -249,7 +335,7 @@ func (rm *Manager) getRelatedClient(apiVersion, resource string) (*dynamicclient
 	groupVersion, _ := schema.ParseGroupVersion(apiVersion)
 	gvr := groupVersion.WithResource(resource)
 
-	informer, err := rm.getOrCreateRelatedInformer(apiVersion, resource, gvr)
+	informer, err := rm.getOrCreateRelatedInformer(apiVersion, resource, gvr, cacheMode)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -261,18 +347,22 @@ func (rm *Manager) getRelatedClient(apiVersion, resource string) (*dynamicclient
 	return client, informer, nil
 }
 
-func (rm *Manager) getOrCreateRelatedInformer(apiVersion, resource string, gvr schema.GroupVersionResource) (*dynamicinformer.ResourceInformer, error) {
-	informer := rm.relatedInformers.Get(gvr)
+func (rm *Manager) getOrCreateRelatedInformer(apiVersion, resource string, gvr schema.GroupVersionResource, cacheMode v1alpha1.CacheMode) (*dynamicinformer.ResourceInformer, error) {
+	informers := rm.relatedInformers
+	if cacheMode == v1alpha1.CacheModeMetadata || cacheMode == v1alpha1.CacheModeMetadataOnly {
+		informers = rm.relatedMetadataInformers
+	}
+	informer := informers.Get(gvr)
 	if informer != nil {
 		return informer, nil
 	}
 
-	informer, err := rm.dynInformers.Resource(rm.informerCtx, apiVersion, resource)
+	informer, err := common.NewResourceInformer(rm.informerCtx, rm.dynInformers, apiVersion, resource, cacheMode)
 	if err != nil {
 		return nil, fmt.Errorf("can't create informer for related resource: %w", err)
 	}
 
-	actual, loaded := rm.relatedInformers.GetOrCreate(gvr, informer)
+	actual, loaded := informers.GetOrCreate(gvr, informer)
 	if loaded {
 		// If we lost the race, clean up the informer we just created.
 		informer.Close()
@@ -288,7 +378,7 @@ func (rm *Manager) getOrCreateRelatedInformer(apiVersion, resource string, gvr s
 	if err != nil {
 		// If we fail to add event handlers, we should probably remove the informer from the map
 		// and close it so we don't leave a "broken" informer.
-		rm.relatedInformers.Delete(gvr)
+		informers.Delete(gvr)
 		actual.Close()
 		return nil, fmt.Errorf("can't add event handlers for related resource: %w", err)
 	}
@@ -350,52 +440,60 @@ func (rm *Manager) notifyRelatedParents(related ...*unstructured.Unstructured) {
 	}
 }
 
+// findRelatedParents returns the parents with a related resource rule matching
+// any of the given related objects. Candidates are looked up in the reverse
+// index, so the cost scales with the number of matching parents rather than
+// with the number of all parents.
 func (rm *Manager) findRelatedParents(relatedSlice ...*unstructured.Unstructured) []*unstructured.Unstructured {
 	var matchingParents []*unstructured.Unstructured
+	matched := make(map[types.UID]struct{})
 
-	rm.parentInformers.ForEach(func(_ schema.GroupVersionResource, parentInformer *dynamicinformer.ResourceInformer) {
-		parents, err := parentInformer.Lister().List(labels.Everything())
+	for _, related := range relatedSlice {
+		relatedClient, err := rm.dynClient.Kind(related.GetAPIVersion(), related.GetKind())
 		if err != nil {
-			return
+			utilruntime.HandleError(fmt.Errorf("unknown related object kind %v/%v: %w", related.GetAPIVersion(), related.GetKind(), err))
+			continue
 		}
-
-	MATCHPARENTS:
-		for _, parent := range parents {
-			customizeHookResponse, err := rm.getCustomizeHookResponse(rm.ctx, parent)
-			if err != nil || customizeHookResponse == nil {
-				// skip for now, the informer relist interval will try again later.
+		for _, entry := range rm.relatedIndex.lookup(relatedClient.Name, related) {
+			parent := entry.parent
+			if _, ok := matched[parent.GetUID()]; ok {
 				continue
 			}
-
-			for _, relatedRule := range customizeHookResponse.RelatedResourceRules {
-				for _, related := range relatedSlice {
-					parentGroup, _ := schema.ParseGroupVersion(parent.GetAPIVersion())
-					parentResource := rm.parentKinds.Get(schema.GroupKind{Group: parentGroup.Group, Kind: parent.GetKind()})
-					if parentResource == nil {
-						utilruntime.HandleError(fmt.Errorf("unknown parent %v/%v", parentGroup, parent.GetKind()))
-						continue
-					}
-					relatedRuleClient, _ := rm.dynClient.Resource(relatedRule.APIVersion, relatedRule.Resource)
-					if relatedRuleClient == nil {
-						utilruntime.HandleError(fmt.Errorf("unknown related rule %v/%v", relatedRule.APIVersion, relatedRule.Resource))
-						continue
-					}
-					matches, err := rm.matchesRelatedRule(customizeHookResponse.Version, parentResource.Namespaced, parent, related, relatedRule, relatedRuleClient.Kind, relatedRuleClient.Namespaced)
-					if err != nil {
-						utilruntime.HandleError(err)
-						continue
-					}
-					if matches {
-						matchingParents = append(matchingParents, parent)
-						continue MATCHPARENTS
-					}
-				}
+			parentGroup, _ := schema.ParseGroupVersion(parent.GetAPIVersion())
+			parentResource := rm.parentKinds.Get(schema.GroupKind{Group: parentGroup.Group, Kind: parent.GetKind()})
+			if parentResource == nil {
+				utilruntime.HandleError(fmt.Errorf("unknown parent %v/%v", parentGroup, parent.GetKind()))
+				continue
+			}
+			matches, err := rm.matchesRelatedRule(entry.version, parentResource.Namespaced, parent, related, entry.rule, relatedClient.Kind, relatedClient.Namespaced)
+			if err != nil {
+				utilruntime.HandleError(err)
+				continue
+			}
+			if matches {
+				matched[parent.GetUID()] = struct{}{}
+				matchingParents = append(matchingParents, parent)
 			}
 		}
-	})
+	}
 	return matchingParents
 }
 
+func (rm *Manager) onParentDelete(obj interface{}) {
+	parent, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		tombstone, ok := obj.(clientgo_cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		parent, ok = tombstone.Obj.(*unstructured.Unstructured)
+		if !ok {
+			return
+		}
+	}
+	rm.relatedIndex.remove(parent.GetUID())
+}
+
 func determineSelectionType(relatedRule *v1alpha1.RelatedResourceRule) (relatedObjectsSelectionType, error) {
 	hasLabelSelector := relatedRule.LabelSelector != nil
 	hasNamespaceSelector := relatedRule.NamespaceSelector != nil
@@ -560,6 +658,9 @@ func listObjects(selector labels.Selector, namespace string, informer *dynamicin
 }
 
 func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.Unstructured) (api.ObjectMap, error) {
+	if rm.static != nil {
+		return rm.static, nil
+	}
 	childMap := make(commonv2.UniformObjectMap)
 	if !rm.IsEnabled() {
 		return childMap, nil
@@ -578,7 +679,7 @@ func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.U
 	}
 
 	for _, relatedRule := range customizeHookResponse.RelatedResourceRules {
-		relatedClient, informer, err := rm.getRelatedClient(relatedRule.APIVersion, relatedRule.Resource)
+		relatedClient, informer, err := rm.getRelatedClient(relatedRule.APIVersion, relatedRule.Resource, relatedRule.CacheMode)
 		if err != nil {
 			return nil, err
 		}
@@ -601,7 +702,9 @@ func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.U
 				return nil, fmt.Errorf("can't list %v related objects: %w", relatedClient.Kind, err)
 			}
 			childMap.InitGroup(relatedClient.GroupVersionKind())
-			childMap.InsertAll(parent, all)
+			if err := insertRelatedObjects(ctx, childMap, parent, relatedClient, relatedRule.CacheMode, all); err != nil {
+				return nil, err
+			}
 
 		case selectByNamespaceAndLabels:
 			if customizeHookResponse.Version == v1alpha1.HookVersionV1 && parentResource.Namespaced && relatedClient.Namespaced && len(relatedRule.Namespace) != 0 && parentNamespace != relatedRule.Namespace {
@@ -616,7 +719,9 @@ func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.U
 				return nil, fmt.Errorf("can't list %v related objects: %w", relatedClient.Kind, err)
 			}
 			childMap.InitGroup(relatedClient.GroupVersionKind())
-			childMap.InsertAll(parent, all)
+			if err := insertRelatedObjects(ctx, childMap, parent, relatedClient, relatedRule.CacheMode, all); err != nil {
+				return nil, err
+			}
 
 		case selectByNamespaceSelector:
 			if customizeHookResponse.Version == v1alpha1.HookVersionV1 && parentResource.Namespaced && relatedClient.Namespaced {
@@ -659,7 +764,9 @@ func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.U
 				if err != nil {
 					return nil, fmt.Errorf("can't list %v related objects in namespace %s: %w", relatedClient.Kind, ns.GetName(), err)
 				}
-				childMap.InsertAll(parent, all)
+				if err := insertRelatedObjects(ctx, childMap, parent, relatedClient, relatedRule.CacheMode, all); err != nil {
+					return nil, err
+				}
 			}
 
 		case selectByNamespaceAndNames:
@@ -671,14 +778,17 @@ func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.U
 				return nil, fmt.Errorf("can't list %v related objects: %w", relatedClient.Kind, err)
 			}
 			childMap.InitGroup(relatedClient.GroupVersionKind())
-			if len(relatedRule.Names) == 0 {
-				childMap.InsertAll(parent, all)
-			} else {
+			if len(relatedRule.Names) != 0 {
+				var named []*unstructured.Unstructured
 				for _, obj := range all {
 					if stringInArray(obj.GetName(), relatedRule.Names) {
-						childMap.Insert(parent, obj)
+						named = append(named, obj)
 					}
 				}
+				all = named
+			}
+			if err := insertRelatedObjects(ctx, childMap, parent, relatedClient, relatedRule.CacheMode, all); err != nil {
+				return nil, err
 			}
 		case invalid:
 			return nil, err
@@ -686,3 +796,14 @@ func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.U
 	}
 	return childMap, err
 }
+
+// insertRelatedObjects adds related objects to the map, fetching the full
+// objects if the rule caches only their metadata.
+func insertRelatedObjects(ctx context.Context, relatedMap commonv2.UniformObjectMap, parent *unstructured.Unstructured, client *dynamicclientset.ResourceClient, cacheMode v1alpha1.CacheMode, objects []*unstructured.Unstructured) error {
+	objects, err := common.FetchObjects(ctx, client, cacheMode, objects)
+	if err != nil {
+		return err
+	}
+	relatedMap.InsertAll(parent, objects)
+	return nil
+}
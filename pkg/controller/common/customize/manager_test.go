@@ -30,6 +30,7 @@ import (
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
+	commonv2 "metacontroller/pkg/controller/common/api/v2"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicinformer "metacontroller/pkg/dynamic/informer"
@@ -123,6 +124,24 @@ func TestGetRelatedObjects_whenHookDisabled_returnEmptyMap(t *testing.T) {
 	}
 }
 
+func TestGetRelatedObjects_static(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetName("test")
+	related := &unstructured.Unstructured{}
+	related.SetAPIVersion("v1")
+	related.SetKind("Secret")
+	related.SetName("related")
+
+	relatedObjects, err := NewStaticManager(commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{related})).GetRelatedObjects(context.TODO(), parent)
+	if err != nil {
+		t.Errorf("Incorrect invocation, err should be nil, got: %v", err)
+	}
+
+	if list := relatedObjects.List(); len(list) != 1 || list[0].GetName() != "related" {
+		t.Errorf("Expected the static related object, got %v", relatedObjects)
+	}
+}
+
 func TestGetRelatedObject_requestResponse(t *testing.T) {
 	expectedResponse := &v1.CustomizeHookResponse{
 		Version: v1alpha1.HookVersionV1,
@@ -803,15 +822,17 @@ func TestGetRelatedObjects_ErrorWhenNamespaceSelectorForClusterScopedResource(t
 	parentKinds.Set(parentGK, parentResource)
 
 	rm := &Manager{
-		controller:       &v1alpha1.CompositeController{},
-		parentKinds:      parentKinds,
-		dynClient:        dynClient,
-		dynInformers:     dynInformers,
-		nsInformer:       nsInformer,
-		parentInformers:  common.NewInformerMap(),
-		relatedInformers: common.NewInformerMap(),
-		logger:           fakeLogger,
-		customizeCache:   newResponseCache(),
+		controller:               &v1alpha1.CompositeController{},
+		parentKinds:              parentKinds,
+		dynClient:                dynClient,
+		dynInformers:             dynInformers,
+		nsInformer:               nsInformer,
+		parentInformers:          common.NewInformerMap(),
+		relatedInformers:         common.NewInformerMap(),
+		relatedMetadataInformers: common.NewInformerMap(),
+		logger:                   fakeLogger,
+		customizeCache:           newResponseCache(),
+		relatedIndex:             newRelatedParentIndex(),
 	}
 
 	// Setup customize hook response
@@ -832,7 +853,7 @@ func TestGetRelatedObjects_ErrorWhenNamespaceSelectorForClusterScopedResource(t
 
 	// Trigger creation and wait for sync of the related informer
 	for {
-		_, informer, err := rm.getRelatedClient("rbac.authorization.k8s.io/v1", resourceClusterRoles)
+		_, informer, err := rm.getRelatedClient("rbac.authorization.k8s.io/v1", resourceClusterRoles, v1alpha1.CacheModeFull)
 		if err == nil {
 			if informer.Informer().HasSynced() {
 				break
@@ -920,14 +941,16 @@ func TestGetRelatedObjects_IgnoreNamespaceForClusterScopedResource(t *testing.T)
 	parentKinds.Set(parentGK, parentResource)
 
 	rm := &Manager{
-		controller:       &v1alpha1.CompositeController{},
-		parentKinds:      parentKinds,
-		dynClient:        dynClient,
-		dynInformers:     dynInformers,
-		parentInformers:  common.NewInformerMap(),
-		relatedInformers: common.NewInformerMap(),
-		logger:           fakeLogger,
-		customizeCache:   newResponseCache(),
+		controller:               &v1alpha1.CompositeController{},
+		parentKinds:              parentKinds,
+		dynClient:                dynClient,
+		dynInformers:             dynInformers,
+		parentInformers:          common.NewInformerMap(),
+		relatedInformers:         common.NewInformerMap(),
+		relatedMetadataInformers: common.NewInformerMap(),
+		logger:                   fakeLogger,
+		customizeCache:           newResponseCache(),
+		relatedIndex:             newRelatedParentIndex(),
 	}
 
 	// Setup customize hook response with explicit namespace for cluster-scoped resource
@@ -951,7 +974,7 @@ func TestGetRelatedObjects_IgnoreNamespaceForClusterScopedResource(t *testing.T)
 	defer cancel()
 	rm.Start(ctx)
 	for {
-		_, informer, err := rm.getRelatedClient("rbac.authorization.k8s.io/v1", resourceClusterRoles)
+		_, informer, err := rm.getRelatedClient("rbac.authorization.k8s.io/v1", resourceClusterRoles, v1alpha1.CacheModeFull)
 		if err == nil {
 			if informer.Informer().HasSynced() {
 				break
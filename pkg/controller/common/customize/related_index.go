@@ -0,0 +1,235 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customize
+
+import (
+	"sort"
+	"sync"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	v1 "metacontroller/pkg/controller/common/customize/api/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// relatedIndexEntry is a single related resource rule of a single parent.
+type relatedIndexEntry struct {
+	parent  *unstructured.Unstructured
+	version v1alpha1.HookVersion
+	rule    *v1alpha1.RelatedResourceRule
+}
+
+type relatedResourceKey struct {
+	apiVersion string
+	resource   string
+}
+
+type relatedNameKey struct {
+	relatedResourceKey
+	namespace string
+	name      string
+}
+
+type relatedLabelKey struct {
+	relatedResourceKey
+	key   string
+	value string
+}
+
+type entrySet map[*relatedIndexEntry]struct{}
+
+// relatedParentIndex is a reverse index from related objects to the related
+// resource rules (and therefore parents) which may select them.
+//
+// Rules selecting objects by name are indexed by namespace and name, rules
+// selecting objects by label are bucketed by one label they require. Rules
+// which can't be narrowed down either way are kept per resource. A lookup
+// therefore only returns candidate entries; callers must still check each
+// candidate against the related object with matchesRelatedRule.
+//
+// The index is updated whenever a new customize response is computed for a
+// parent, and entries are dropped when the parent is deleted.
+type relatedParentIndex struct {
+	mutex sync.RWMutex
+
+	byParent   map[types.UID][]*relatedIndexEntry
+	byName     map[relatedNameKey]entrySet
+	byLabel    map[relatedLabelKey]entrySet
+	unbucketed map[relatedResourceKey]entrySet
+}
+
+func newRelatedParentIndex() *relatedParentIndex {
+	return &relatedParentIndex{
+		byParent:   make(map[types.UID][]*relatedIndexEntry),
+		byName:     make(map[relatedNameKey]entrySet),
+		byLabel:    make(map[relatedLabelKey]entrySet),
+		unbucketed: make(map[relatedResourceKey]entrySet),
+	}
+}
+
+// update replaces all entries of the given parent with the given rules.
+func (idx *relatedParentIndex) update(parent *unstructured.Unstructured, response *v1.CustomizeHookResponse) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.removeLocked(parent.GetUID())
+	if response == nil || len(response.RelatedResourceRules) == 0 {
+		return
+	}
+	entries := make([]*relatedIndexEntry, 0, len(response.RelatedResourceRules))
+	for _, rule := range response.RelatedResourceRules {
+		entry := &relatedIndexEntry{parent: parent, version: response.Version, rule: rule}
+		entries = append(entries, entry)
+		idx.addLocked(entry)
+	}
+	idx.byParent[parent.GetUID()] = entries
+}
+
+// remove drops all entries of the parent with the given UID.
+func (idx *relatedParentIndex) remove(uid types.UID) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	idx.removeLocked(uid)
+}
+
+// lookup returns the entries whose rules may select the given related object,
+// which is an object of the given resource.
+func (idx *relatedParentIndex) lookup(resource string, related *unstructured.Unstructured) []*relatedIndexEntry {
+	idx.mutex.RLock()
+	defer idx.mutex.RUnlock()
+
+	resourceKey := relatedResourceKey{apiVersion: related.GetAPIVersion(), resource: resource}
+	seen := make(entrySet)
+	var result []*relatedIndexEntry
+	collect := func(entries entrySet) {
+		for entry := range entries {
+			if _, ok := seen[entry]; ok {
+				continue
+			}
+			seen[entry] = struct{}{}
+			result = append(result, entry)
+		}
+	}
+
+	// Rules without an explicit namespace are indexed under the empty namespace.
+	collect(idx.byName[relatedNameKey{relatedResourceKey: resourceKey, namespace: related.GetNamespace(), name: related.GetName()}])
+	if related.GetNamespace() != "" {
+		collect(idx.byName[relatedNameKey{relatedResourceKey: resourceKey, name: related.GetName()}])
+	}
+	for key, value := range related.GetLabels() {
+		collect(idx.byLabel[relatedLabelKey{relatedResourceKey: resourceKey, key: key, value: value}])
+	}
+	collect(idx.unbucketed[resourceKey])
+	return result
+}
+
+func (idx *relatedParentIndex) addLocked(entry *relatedIndexEntry) {
+	resourceKey := relatedResourceKey{apiVersion: entry.rule.APIVersion, resource: entry.rule.Resource}
+	if nameKeys := nameKeysFor(resourceKey, entry.rule); len(nameKeys) != 0 {
+		for _, key := range nameKeys {
+			insert(idx.byName, key, entry)
+		}
+		return
+	}
+	if labelKeys := labelKeysFor(resourceKey, entry.rule.LabelSelector); len(labelKeys) != 0 {
+		for _, key := range labelKeys {
+			insert(idx.byLabel, key, entry)
+		}
+		return
+	}
+	insert(idx.unbucketed, resourceKey, entry)
+}
+
+func (idx *relatedParentIndex) removeLocked(uid types.UID) {
+	for _, entry := range idx.byParent[uid] {
+		resourceKey := relatedResourceKey{apiVersion: entry.rule.APIVersion, resource: entry.rule.Resource}
+		for _, key := range nameKeysFor(resourceKey, entry.rule) {
+			remove(idx.byName, key, entry)
+		}
+		for _, key := range labelKeysFor(resourceKey, entry.rule.LabelSelector) {
+			remove(idx.byLabel, key, entry)
+		}
+		remove(idx.unbucketed, resourceKey, entry)
+	}
+	delete(idx.byParent, uid)
+}
+
+// nameKeysFor returns the name index keys of a rule selecting objects by name,
+// or nil if the rule does not select objects by name.
+func nameKeysFor(resourceKey relatedResourceKey, rule *v1alpha1.RelatedResourceRule) []relatedNameKey {
+	selectionType, err := determineSelectionType(rule)
+	if err != nil || selectionType != selectByNamespaceAndNames || len(rule.Names) == 0 {
+		return nil
+	}
+	keys := make([]relatedNameKey, 0, len(rule.Names))
+	for _, name := range rule.Names {
+		keys = append(keys, relatedNameKey{relatedResourceKey: resourceKey, namespace: rule.Namespace, name: name})
+	}
+	return keys
+}
+
+// labelKeysFor returns the label index keys of a label selector. An object can
+// only match the selector if it has one of the returned labels. It returns nil
+// if the selector doesn't require any specific label value.
+func labelKeysFor(resourceKey relatedResourceKey, selector *metav1.LabelSelector) []relatedLabelKey {
+	if selector == nil {
+		return nil
+	}
+	if len(selector.MatchLabels) != 0 {
+		// Any required label will do, pick the first one to be deterministic.
+		keys := make([]string, 0, len(selector.MatchLabels))
+		for key := range selector.MatchLabels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return []relatedLabelKey{{relatedResourceKey: resourceKey, key: keys[0], value: selector.MatchLabels[keys[0]]}}
+	}
+	for _, requirement := range selector.MatchExpressions {
+		if requirement.Operator != metav1.LabelSelectorOpIn || len(requirement.Values) == 0 {
+			continue
+		}
+		keys := make([]relatedLabelKey, 0, len(requirement.Values))
+		for _, value := range requirement.Values {
+			keys = append(keys, relatedLabelKey{relatedResourceKey: resourceKey, key: requirement.Key, value: value})
+		}
+		return keys
+	}
+	return nil
+}
+
+func insert[K comparable](index map[K]entrySet, key K, entry *relatedIndexEntry) {
+	entries, ok := index[key]
+	if !ok {
+		entries = make(entrySet)
+		index[key] = entries
+	}
+	entries[entry] = struct{}{}
+}
+
+func remove[K comparable](index map[K]entrySet, key K, entry *relatedIndexEntry) {
+	entries, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(entries, entry)
+	if len(entries) == 0 {
+		delete(index, key)
+	}
+}
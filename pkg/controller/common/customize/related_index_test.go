@@ -0,0 +1,220 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customize
+
+import (
+	"sort"
+	"testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	v1 "metacontroller/pkg/controller/common/customize/api/v1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	"metacontroller/pkg/internal/testutils/dynamic/discovery"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func newIndexParent(name string) *unstructured.Unstructured {
+	parent := &unstructured.Unstructured{}
+	parent.SetAPIVersion("test/v1")
+	parent.SetKind(kindParent)
+	parent.SetNamespace(someNS)
+	parent.SetName(name)
+	parent.SetUID(types.UID(name + "-uid"))
+	return parent
+}
+
+func newSecret(namespace, name string, secretLabels map[string]string) *unstructured.Unstructured {
+	secret := &unstructured.Unstructured{}
+	secret.SetAPIVersion("v1")
+	secret.SetKind(kindSecret)
+	secret.SetNamespace(namespace)
+	secret.SetName(name)
+	secret.SetLabels(secretLabels)
+	return secret
+}
+
+func lookupParentNames(idx *relatedParentIndex, related *unstructured.Unstructured) []string {
+	var names []string
+	for _, entry := range idx.lookup(resourceSecrets, related) {
+		names = append(names, entry.parent.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRelatedParentIndex_lookup(t *testing.T) {
+	idx := newRelatedParentIndex()
+	idx.update(newIndexParent("by-name"), &v1.CustomizeHookResponse{
+		Version: v1alpha1.HookVersionV2,
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), Namespace: someNS, Names: []string{"a", "b"}},
+		},
+	})
+	idx.update(newIndexParent("by-label"), &v1.CustomizeHookResponse{
+		Version: v1alpha1.HookVersionV2,
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelKeyEnv: labelValueProd, labelKeyAAA: labelValueBBB}}},
+		},
+	})
+	idx.update(newIndexParent("by-expression"), &v1.CustomizeHookResponse{
+		Version: v1alpha1.HookVersionV2,
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), LabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: labelKeyEnv, Operator: metav1.LabelSelectorOpIn, Values: []string{"dev", labelValueProd}},
+			}}},
+		},
+	})
+	idx.update(newIndexParent("everything"), &v1.CustomizeHookResponse{
+		Version: v1alpha1.HookVersionV2,
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), LabelSelector: &metav1.LabelSelector{}},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		related *unstructured.Unstructured
+		want    []string
+	}{
+		{
+			name:    "name match",
+			related: newSecret(someNS, "a", nil),
+			want:    []string{"by-name", "everything"},
+		},
+		{
+			name:    "name in other namespace is not a candidate",
+			related: newSecret("other", "a", nil),
+			want:    []string{"everything"},
+		},
+		{
+			name:    "label match",
+			related: newSecret(someNS, "c", map[string]string{labelKeyAAA: labelValueBBB, labelKeyEnv: labelValueProd}),
+			want:    []string{"by-expression", "by-label", "everything"},
+		},
+		{
+			name:    "expression match only",
+			related: newSecret(someNS, "c", map[string]string{labelKeyEnv: "dev"}),
+			want:    []string{"by-expression", "everything"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lookupParentNames(idx, tt.related)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRelatedParentIndex_updateAndRemove(t *testing.T) {
+	idx := newRelatedParentIndex()
+	parent := newIndexParent("parent")
+	idx.update(parent, &v1.CustomizeHookResponse{
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), Namespace: someNS, Names: []string{"old"}},
+		},
+	})
+	idx.update(parent, &v1.CustomizeHookResponse{
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), Namespace: someNS, Names: []string{"new"}},
+		},
+	})
+
+	if got := lookupParentNames(idx, newSecret(someNS, "old", nil)); len(got) != 0 {
+		t.Errorf("expected no candidates for replaced rule, got %v", got)
+	}
+	if got := lookupParentNames(idx, newSecret(someNS, "new", nil)); len(got) != 1 {
+		t.Errorf("expected one candidate for current rule, got %v", got)
+	}
+
+	idx.remove(parent.GetUID())
+	if got := lookupParentNames(idx, newSecret(someNS, "new", nil)); len(got) != 0 {
+		t.Errorf("expected no candidates after removal, got %v", got)
+	}
+	if len(idx.byParent) != 0 || len(idx.byName) != 0 || len(idx.byLabel) != 0 || len(idx.unbucketed) != 0 {
+		t.Errorf("expected empty index after removal, got %#v", idx)
+	}
+}
+
+func TestFindRelatedParents_usesIndex(t *testing.T) {
+	simple := fakeclientset.NewClientset()
+	fakeDiscovery := simple.Discovery().(*fakediscovery.FakeDiscovery)
+	fakeDiscovery.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: resourceSecrets, Kind: kindSecret, Namespaced: true},
+			},
+		},
+	}
+	resourceMap := discovery.NewFakeResourceMap(simple)
+
+	parentKinds := common.NewGroupKindMap()
+	parentKinds.Set(schema.GroupKind{Group: groupTest, Kind: kindParent}, &dynamicdiscovery.APIResource{
+		APIResource: metav1.APIResource{Name: "parents", Namespaced: true, Kind: kindParent, Group: groupTest, Version: "v1"},
+	})
+	rm := &Manager{
+		parentKinds:  parentKinds,
+		dynClient:    dynamicclientset.NewClientset(&rest.Config{}, resourceMap, fake.NewSimpleDynamicClient(runtime.NewScheme())),
+		relatedIndex: newRelatedParentIndex(),
+	}
+
+	matching := newIndexParent("matching")
+	rm.relatedIndex.update(matching, &v1.CustomizeHookResponse{
+		Version: v1alpha1.HookVersionV1,
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), Names: []string{"secret"}},
+		},
+	})
+	// A v1 rule without namespace from a parent in another namespace is a
+	// candidate in the index, but must not match.
+	otherNamespace := newIndexParent("other-namespace")
+	otherNamespace.SetNamespace("other")
+	rm.relatedIndex.update(otherNamespace, &v1.CustomizeHookResponse{
+		Version: v1alpha1.HookVersionV1,
+		RelatedResourceRules: []*v1alpha1.RelatedResourceRule{
+			{ResourceRule: secretRule(), Names: []string{"secret"}},
+		},
+	})
+
+	parents := rm.findRelatedParents(newSecret(someNS, "secret", nil), newSecret(someNS, "secret", nil))
+	if len(parents) != 1 || parents[0].GetName() != "matching" {
+		t.Errorf("expected only parent 'matching', got %v", parents)
+	}
+
+	rm.onParentDelete(matching)
+	if parents := rm.findRelatedParents(newSecret(someNS, "secret", nil)); len(parents) != 0 {
+		t.Errorf("expected no parents after deletion, got %v", parents)
+	}
+}
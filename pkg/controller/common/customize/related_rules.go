@@ -0,0 +1,168 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customize
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// isTemplate returns true if the given value contains a JSONPath expression.
+func isTemplate(value string) bool {
+	return strings.Contains(value, "{")
+}
+
+// validateRelatedRuleTemplates checks that all JSONPath templates in the given
+// rules can be parsed, so that mistakes surface when the controller is created
+// rather than on every sync.
+func validateRelatedRuleTemplates(templates []v1alpha1.RelatedResourceRuleTemplate) error {
+	for i := range templates {
+		values := append([]string{templates[i].Namespace}, templates[i].Names...)
+		values = append(values, selectorValues(templates[i].LabelSelector)...)
+		for _, value := range values {
+			if !isTemplate(value) {
+				continue
+			}
+			if err := jsonpath.New("related").Parse(value); err != nil {
+				return fmt.Errorf("invalid template %q in related resource rule for %s/%s: %w", value, templates[i].APIVersion, templates[i].Resource, err)
+			}
+		}
+	}
+	return nil
+}
+
+func selectorValues(selector *metav1.LabelSelector) []string {
+	if selector == nil {
+		return nil
+	}
+	var values []string
+	for _, value := range selector.MatchLabels {
+		values = append(values, value)
+	}
+	for _, requirement := range selector.MatchExpressions {
+		values = append(values, requirement.Values...)
+	}
+	return values
+}
+
+// evaluateTemplate renders a single JSONPath template against the parent.
+// Values without a JSONPath expression are returned unchanged, missing fields
+// render as an empty string.
+func evaluateTemplate(value string, parent *unstructured.Unstructured) (string, error) {
+	if !isTemplate(value) {
+		return value, nil
+	}
+	jp := jsonpath.New("related").AllowMissingKeys(true)
+	if err := jp.Parse(value); err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := jp.Execute(buf, parent.UnstructuredContent()); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// evaluateRelatedRuleTemplates renders the declarative related resource rules
+// of a controller for the given parent.
+func evaluateRelatedRuleTemplates(templates []v1alpha1.RelatedResourceRuleTemplate, parent *unstructured.Unstructured) ([]*v1alpha1.RelatedResourceRule, error) {
+	rules := make([]*v1alpha1.RelatedResourceRule, 0, len(templates))
+	for i := range templates {
+		rule, err := evaluateRelatedRuleTemplate(&templates[i], parent)
+		if err != nil {
+			return nil, fmt.Errorf("can't evaluate related resource rule for %s/%s: %w", templates[i].APIVersion, templates[i].Resource, err)
+		}
+		if rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// evaluateRelatedRuleTemplate returns nil if the rule refers to objects by name,
+// but none of the names could be resolved from the parent. Dropping the rule
+// prevents it from degrading into a rule that selects every object.
+func evaluateRelatedRuleTemplate(template *v1alpha1.RelatedResourceRuleTemplate, parent *unstructured.Unstructured) (*v1alpha1.RelatedResourceRule, error) {
+	rule := &v1alpha1.RelatedResourceRule{
+		ResourceRule:      template.ResourceRule,
+		NamespaceSelector: template.NamespaceSelector,
+		CacheMode:         template.CacheMode,
+	}
+
+	namespace, err := evaluateTemplate(template.Namespace, parent)
+	if err != nil {
+		return nil, err
+	}
+	if len(namespace) == 0 && template.NamespaceSelector == nil {
+		namespace = parent.GetNamespace()
+	}
+	rule.Namespace = namespace
+
+	for _, name := range template.Names {
+		evaluated, err := evaluateTemplate(name, parent)
+		if err != nil {
+			return nil, err
+		}
+		rule.Names = append(rule.Names, strings.Fields(evaluated)...)
+	}
+	if len(template.Names) != 0 && len(rule.Names) == 0 {
+		return nil, nil
+	}
+
+	if template.LabelSelector != nil {
+		selector, err := evaluateLabelSelector(template.LabelSelector, parent)
+		if err != nil {
+			return nil, err
+		}
+		rule.LabelSelector = selector
+	}
+	return rule, nil
+}
+
+func evaluateLabelSelector(template *metav1.LabelSelector, parent *unstructured.Unstructured) (*metav1.LabelSelector, error) {
+	selector := template.DeepCopy()
+	for key, value := range selector.MatchLabels {
+		evaluated, err := evaluateTemplate(value, parent)
+		if err != nil {
+			return nil, err
+		}
+		selector.MatchLabels[key] = evaluated
+	}
+	for i := range selector.MatchExpressions {
+		var values []string
+		for _, value := range selector.MatchExpressions[i].Values {
+			evaluated, err := evaluateTemplate(value, parent)
+			if err != nil {
+				return nil, err
+			}
+			if isTemplate(value) {
+				values = append(values, strings.Fields(evaluated)...)
+			} else {
+				values = append(values, evaluated)
+			}
+		}
+		selector.MatchExpressions[i].Values = values
+	}
+	return selector, nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customize
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	. "metacontroller/pkg/internal/testutils/hooks"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTemplateParent() *unstructured.Unstructured {
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "test/v1",
+		"kind":       kindParent,
+		"metadata": map[string]interface{}{
+			"name":       "parent",
+			"namespace":  someNS,
+			"uid":        "parent-uid",
+			"generation": int64(1),
+		},
+		"spec": map[string]interface{}{
+			"secretName": "my-secret",
+			"secrets": []interface{}{
+				map[string]interface{}{"name": "first"},
+				map[string]interface{}{"name": "second"},
+			},
+			"sourceNamespace": "global",
+			"env":             labelValueProd,
+		},
+	}}
+	return parent
+}
+
+func secretRule() v1alpha1.ResourceRule {
+	return v1alpha1.ResourceRule{APIVersion: "v1", Resource: resourceSecrets}
+}
+
+func TestEvaluateRelatedRuleTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []v1alpha1.RelatedResourceRuleTemplate
+		want      []*v1alpha1.RelatedResourceRule
+	}{
+		{
+			name: "name from parent field defaults to parent namespace",
+			templates: []v1alpha1.RelatedResourceRuleTemplate{
+				{ResourceRule: secretRule(), Names: []string{"{.spec.secretName}"}},
+			},
+			want: []*v1alpha1.RelatedResourceRule{
+				{ResourceRule: secretRule(), Namespace: someNS, Names: []string{"my-secret"}},
+			},
+		},
+		{
+			name: "list of names and namespace from parent fields",
+			templates: []v1alpha1.RelatedResourceRuleTemplate{
+				{ResourceRule: secretRule(), Namespace: "{.spec.sourceNamespace}", Names: []string{"{.spec.secrets[*].name}", "literal"}},
+			},
+			want: []*v1alpha1.RelatedResourceRule{
+				{ResourceRule: secretRule(), Namespace: "global", Names: []string{"first", "second", "literal"}},
+			},
+		},
+		{
+			name: "rule is dropped when no names can be resolved",
+			templates: []v1alpha1.RelatedResourceRuleTemplate{
+				{ResourceRule: secretRule(), Names: []string{"{.spec.missing}"}},
+			},
+			want: []*v1alpha1.RelatedResourceRule{},
+		},
+		{
+			name: "label selector values from parent fields",
+			templates: []v1alpha1.RelatedResourceRuleTemplate{
+				{
+					ResourceRule: secretRule(),
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{labelKeyEnv: "{.spec.env}"},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: nameValue, Operator: metav1.LabelSelectorOpIn, Values: []string{"{.spec.secrets[*].name}"}},
+						},
+					},
+				},
+			},
+			want: []*v1alpha1.RelatedResourceRule{
+				{
+					ResourceRule: secretRule(),
+					Namespace:    someNS,
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{labelKeyEnv: labelValueProd},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: nameValue, Operator: metav1.LabelSelectorOpIn, Values: []string{"first", "second"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "namespace selector is kept and namespace is not defaulted",
+			templates: []v1alpha1.RelatedResourceRuleTemplate{
+				{ResourceRule: secretRule(), NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelKeyEnv: labelValueProd}}},
+			},
+			want: []*v1alpha1.RelatedResourceRule{
+				{ResourceRule: secretRule(), NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelKeyEnv: labelValueProd}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateRelatedRuleTemplates(tt.templates, newTemplateParent())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRelatedRuleTemplates_invalidTemplate(t *testing.T) {
+	templates := []v1alpha1.RelatedResourceRuleTemplate{
+		{ResourceRule: secretRule(), Names: []string{"{.spec.secretName"}},
+	}
+	if err := validateRelatedRuleTemplates(templates); err == nil {
+		t.Error("expected error for unterminated template, got nil")
+	}
+}
+
+func TestGetCustomizeHookResponse_declarativeRulesWithoutHook(t *testing.T) {
+	controller := &v1alpha1.CompositeController{
+		Spec: v1alpha1.CompositeControllerSpec{
+			RelatedResources: []v1alpha1.RelatedResourceRuleTemplate{
+				{ResourceRule: secretRule(), Names: []string{"{.spec.secretName}"}},
+			},
+		},
+	}
+	rm := &Manager{
+		controller:     controller,
+		customizeCache: newResponseCache(),
+		relatedIndex:   newRelatedParentIndex(),
+	}
+
+	response, err := rm.getCustomizeHookResponse(context.TODO(), newTemplateParent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Version != v1alpha1.HookVersionV2 {
+		t.Errorf("expected version %s, got %s", v1alpha1.HookVersionV2, response.Version)
+	}
+	want := []*v1alpha1.RelatedResourceRule{
+		{ResourceRule: secretRule(), Namespace: someNS, Names: []string{"my-secret"}},
+	}
+	if !reflect.DeepEqual(response.RelatedResourceRules, want) {
+		t.Errorf("got %#v, want %#v", response.RelatedResourceRules, want)
+	}
+}
+
+func TestGetCustomizeHookResponse_declarativeRulesAppendedToHookRules(t *testing.T) {
+	controller := &v1alpha1.CompositeController{
+		Spec: v1alpha1.CompositeControllerSpec{
+			RelatedResources: []v1alpha1.RelatedResourceRuleTemplate{
+				{ResourceRule: secretRule(), Names: []string{"{.spec.secretName}"}},
+			},
+		},
+	}
+	hookRule := &v1alpha1.RelatedResourceRule{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"}}
+	rm := &Manager{
+		controller:     controller,
+		customizeCache: newResponseCache(),
+		relatedIndex:   newRelatedParentIndex(),
+		customizeHook:  NewSerializingExecutorStub(`{"relatedResources":[{"apiVersion":"v1","resource":"configmaps"}]}`),
+	}
+
+	response, err := rm.getCustomizeHookResponse(context.TODO(), newTemplateParent())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Version != v1alpha1.HookVersionV1 {
+		t.Errorf("expected version %s, got %s", v1alpha1.HookVersionV1, response.Version)
+	}
+	want := []*v1alpha1.RelatedResourceRule{
+		hookRule,
+		{ResourceRule: secretRule(), Namespace: someNS, Names: []string{"my-secret"}},
+	}
+	if !reflect.DeepEqual(response.RelatedResourceRules, want) {
+		t.Errorf("got %#v, want %#v", response.RelatedResourceRules, want)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+// DiscoveryRetrier remembers the CompositeControllers or DecoratorControllers
+// that failed to sync, and enqueues them again as soon as API discovery
+// changes, instead of waiting for their backoff to expire, since they may be
+// waiting for a resource to be installed.
+type DiscoveryRetrier struct {
+	ctx       context.Context
+	newObject func(name string) client.Object
+	events    chan event.GenericEvent
+
+	mutex  sync.Mutex
+	failed map[string]bool
+}
+
+// NewDiscoveryRetrier returns a retrier of the controllers that newObject
+// returns a stub of, given their name.
+func NewDiscoveryRetrier(ctx context.Context, resources *dynamicdiscovery.ResourceMap, newObject func(name string) client.Object) *DiscoveryRetrier {
+	r := &DiscoveryRetrier{
+		ctx:       ctx,
+		newObject: newObject,
+		events:    make(chan event.GenericEvent),
+		failed:    make(map[string]bool),
+	}
+	resources.AddChangeHandler(r.retry)
+	return r
+}
+
+// Observe remembers whether the controller with the given name failed to
+// sync.
+func (r *DiscoveryRetrier) Observe(name string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err != nil {
+		r.failed[name] = true
+	} else {
+		delete(r.failed, name)
+	}
+}
+
+// Failed returns the names of the controllers that failed to sync, sorted.
+func (r *DiscoveryRetrier) Failed() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names := make([]string, 0, len(r.failed))
+	for name := range r.failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the source of the events that enqueue failed controllers.
+func (r *DiscoveryRetrier) Source() source.Source {
+	return source.Channel(r.events, &handler.TypedEnqueueRequestForObject[client.Object]{})
+}
+
+func (r *DiscoveryRetrier) retry() {
+	r.mutex.Lock()
+	names := make([]string, 0, len(r.failed))
+	for name := range r.failed {
+		names = append(names, name)
+	}
+	r.mutex.Unlock()
+	if len(names) == 0 {
+		return
+	}
+
+	// Don't block discovery until the controller reads the events.
+	go func() {
+		for _, name := range names {
+			select {
+			case r.events <- event.GenericEvent{Object: r.newObject(name)}:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
+)
+
+// DryRunAction is the kind of write a controller in dry-run mode didn't make.
+type DryRunAction string
+
+const (
+	DryRunCreate       DryRunAction = "Create"
+	DryRunUpdate       DryRunAction = "Update"
+	DryRunUpdateStatus DryRunAction = "UpdateStatus"
+	DryRunDelete       DryRunAction = "Delete"
+)
+
+// maxDryRunEventPatchLength limits the size of the merge patch included in
+// Events, which the API server truncates anyway.
+const maxDryRunEventPatchLength = 512
+
+var dryRunOperations = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "metacontroller",
+		Subsystem: "dry_run",
+		Name:      "operations_total",
+		Help:      "A counter for writes that controllers in dry-run mode computed but didn't persist.",
+	},
+	[]string{"controller", "group", "kind", "action"},
+)
+
+func init() {
+	controllerruntimemetrics.Registry.MustRegister(dryRunOperations)
+}
+
+// DryRunWrite is a write that a controller in dry-run mode didn't make.
+type DryRunWrite struct {
+	Action DryRunAction
+	// Original is nil for creates.
+	Original *unstructured.Unstructured
+	// Updated is nil for deletes.
+	Updated *unstructured.Unstructured
+}
+
+// DryRun reports the writes of a controller in dry-run mode, which computes
+// everything as usual but never persists changes. A nil *DryRun means the
+// controller isn't in dry-run mode.
+type DryRun struct {
+	controller string
+	recorder   record.EventRecorder
+	redactor   *redact.Redactor
+	// collect receives the writes instead of the logs, metrics and Events,
+	// if it's set.
+	collect func(write DryRunWrite)
+}
+
+// NewDryRun returns the dry-run reporter of the given controller, which
+// records Events with the given recorder. The redactor masks sensitive fields
+// in the logs and Events.
+func NewDryRun(controllerKind, controllerName string, recorder record.EventRecorder, redactor *redact.Redactor) *DryRun {
+	return &DryRun{
+		controller: controllerKind + "/" + controllerName,
+		recorder:   recorder,
+		redactor:   redactor,
+	}
+}
+
+// NewCollectingDryRun returns a dry-run reporter that passes the writes of the
+// given controller to collect, in order, instead of logging, counting and
+// recording them.
+func NewCollectingDryRun(controllerKind, controllerName string, collect func(write DryRunWrite)) *DryRun {
+	return &DryRun{
+		controller: controllerKind + "/" + controllerName,
+		collect:    collect,
+	}
+}
+
+// Enabled reports whether writes should be skipped or sent as dry runs.
+func (d *DryRun) Enabled() bool {
+	return d != nil
+}
+
+// Options returns the value of the DryRun field of create, update, patch and
+// delete options.
+func (d *DryRun) Options() []string {
+	if !d.Enabled() {
+		return nil
+	}
+	return []string{metav1.DryRunAll}
+}
+
+// Report logs, counts and records an Event on the parent for a write that
+// wasn't persisted. The original object is nil for creates, and updated is
+// nil for deletes.
+func (d *DryRun) Report(parent *unstructured.Unstructured, action DryRunAction, original, updated *unstructured.Unstructured) {
+	if !d.Enabled() {
+		return
+	}
+	if d.collect != nil {
+		d.collect(DryRunWrite{Action: action, Original: original.DeepCopy(), Updated: updated.DeepCopy()})
+		return
+	}
+	obj := updated
+	if obj == nil {
+		obj = original
+	}
+
+	var patch []byte
+	if updated != nil {
+		if original == nil {
+			original = &unstructured.Unstructured{Object: map[string]interface{}{}}
+		}
+		var err error
+		if patch, err = JsonMergePatch(original, updated); err != nil {
+			logging.Logger.Error(err, "Cannot create merge patch to visualize dry run", "controller", d.controller, "object", obj)
+		}
+		patch = d.redactor.Patch(patch, obj)
+	}
+
+	logging.Logger.Info("Dry run, not persisting change", "controller", d.controller, "action", action, "parent", d.redactor.Object(parent), "object", d.redactor.Object(obj), "mergePatch", json.RawMessage(patch))
+	gvk := obj.GroupVersionKind()
+	dryRunOperations.WithLabelValues(d.controller, gvk.Group, gvk.Kind, string(action)).Inc()
+	if d.recorder != nil && parent != nil {
+		d.recorder.Event(parent, corev1.EventTypeNormal, events.ReasonDryRun, dryRunMessage(action, obj, patch))
+	}
+}
+
+func dryRunMessage(action DryRunAction, obj *unstructured.Unstructured, patch []byte) string {
+	message := "Dry run, not persisted: " + string(action) + " " + describeObject(obj)
+	if len(patch) == 0 {
+		return message
+	}
+	if len(patch) > maxDryRunEventPatchLength {
+		return message + ": " + string(patch[:maxDryRunEventPatchLength]) + "..."
+	}
+	return message + ": " + string(patch)
+}
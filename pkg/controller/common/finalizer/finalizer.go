@@ -41,24 +41,49 @@ func NewManager(name string, enabled bool) *Manager {
 	}
 }
 
-// SyncObject adds or removes the finalizer on the given object as necessary.
-func (m *Manager) SyncObject(ctx context.Context, client *dynamicclientset.ResourceClient, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+// SyncObject adds or removes the finalizer on the given object as necessary,
+// and returns the object and whether it was updated.
+func (m *Manager) SyncObject(ctx context.Context, client *dynamicclientset.ResourceClient, obj *unstructured.Unstructured) (*unstructured.Unstructured, bool, error) {
 	// If the cached object passed in is already in the right state,
 	// we'll assume we don't need to check the live object.
 	if controllerutil.ContainsFinalizer(obj, m.Name) == m.Enabled {
-		return obj, nil
+		return obj, false, nil
 	}
 	// Otherwise, we may need to update the object.
-	if m.Enabled {
-		// If the object is already pending deletion, we don't add the finalizer.
-		// We might have already removed it.
-		if obj.GetDeletionTimestamp() != nil {
-			return obj, nil
+	// If the object is already pending deletion, we don't add the finalizer.
+	// We might have already removed it.
+	if m.Enabled && obj.GetDeletionTimestamp() != nil {
+		return obj, false, nil
+	}
+	changed := false
+	updated, err := client.Namespace(obj.GetNamespace()).AtomicUpdate(ctx, obj, func(current *unstructured.Unstructured) bool {
+		if m.Enabled {
+			changed = controllerutil.AddFinalizer(current, m.Name)
+		} else {
+			changed = controllerutil.RemoveFinalizer(current, m.Name)
 		}
-		return client.Namespace(obj.GetNamespace()).AddFinalizer(ctx, obj, m.Name)
+		return changed
+	})
+	return updated, changed && err == nil, err
+}
+
+// DryRunSyncObject returns a copy of the given object with the finalizer
+// added or removed as SyncObject would, without writing it, and whether the
+// object changed.
+func (m *Manager) DryRunSyncObject(obj *unstructured.Unstructured) (*unstructured.Unstructured, bool) {
+	if controllerutil.ContainsFinalizer(obj, m.Name) == m.Enabled {
+		return obj, false
+	}
+	if m.Enabled && obj.GetDeletionTimestamp() != nil {
+		return obj, false
+	}
+	updated := obj.DeepCopy()
+	if m.Enabled {
+		controllerutil.AddFinalizer(updated, m.Name)
 	} else {
-		return client.Namespace(obj.GetNamespace()).RemoveFinalizer(ctx, obj, m.Name)
+		controllerutil.RemoveFinalizer(updated, m.Name)
 	}
+	return updated, true
 }
 
 // ShouldFinalize returns true if the controller should take action to manage
@@ -0,0 +1,120 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// trackedQueues are the workqueues of the running controllers.
+var trackedQueues = struct {
+	sync.Mutex
+	queues map[*QueueProgress]bool
+}{queues: make(map[*QueueProgress]bool)}
+
+// QueueProgress tracks how long items have been waiting in the workqueue of a
+// controller without any being processed, so that stuck controllers can be
+// detected.
+type QueueProgress struct {
+	name  string
+	queue interface{ Len() int }
+	// last is when the last item was processed, in Unix nanoseconds.
+	last atomic.Int64
+	// waiting is when items were first seen waiting after the queue was
+	// empty, in Unix nanoseconds, or 0 if the queue was last seen empty.
+	waiting atomic.Int64
+	now     func() time.Time
+}
+
+// TrackQueue starts tracking the progress of the workqueue of a controller.
+// Call Untrack when the controller stops.
+func TrackQueue(name string, queue interface{ Len() int }) *QueueProgress {
+	p := &QueueProgress{name: name, queue: queue, now: time.Now}
+	p.Progress()
+
+	trackedQueues.Lock()
+	defer trackedQueues.Unlock()
+	trackedQueues.queues[p] = true
+	return p
+}
+
+// Progress records that an item was processed. It does nothing if the queue
+// isn't tracked.
+func (p *QueueProgress) Progress() {
+	if p == nil {
+		return
+	}
+	p.last.Store(p.now().UnixNano())
+	if p.queue.Len() == 0 {
+		p.waiting.Store(0)
+	}
+}
+
+// Untrack stops tracking the progress of the workqueue.
+func (p *QueueProgress) Untrack() {
+	trackedQueues.Lock()
+	defer trackedQueues.Unlock()
+	delete(trackedQueues.queues, p)
+}
+
+// stuck returns true if items have been waiting in the queue for longer than
+// the timeout, and none was processed meanwhile. A queue that was idle isn't
+// stuck just because items arrive: they count as waiting from the time the
+// check first sees them.
+func (p *QueueProgress) stuck(timeout time.Duration) bool {
+	if p.queue.Len() == 0 {
+		p.waiting.Store(0)
+		return false
+	}
+	now := p.now().UnixNano()
+	p.waiting.CompareAndSwap(0, now)
+	since := max(p.last.Load(), p.waiting.Load())
+	return time.Duration(now-since) > timeout
+}
+
+// StuckQueuesCheck returns a health check that fails if the workqueue of any
+// running controller made no progress for longer than the timeout, while
+// items were waiting in it. A zero timeout disables the check.
+func StuckQueuesCheck(timeout time.Duration) healthz.Checker {
+	return func(_ *http.Request) error {
+		if timeout <= 0 {
+			return nil
+		}
+		trackedQueues.Lock()
+		var stuck []string
+		for p := range trackedQueues.queues {
+			if p.stuck(timeout) {
+				stuck = append(stuck, p.name)
+			}
+		}
+		trackedQueues.Unlock()
+
+		if len(stuck) > 0 {
+			sort.Strings(stuck)
+			return fmt.Errorf("no progress for %v in workqueues: %s", timeout, strings.Join(stuck, ", "))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeQueue int
+
+func (q *fakeQueue) Len() int {
+	return int(*q)
+}
+
+func TestStuckQueuesCheck(t *testing.T) {
+	now := time.Now()
+	var queue fakeQueue
+	p := TrackQueue("composite-test", &queue)
+	defer p.Untrack()
+	p.now = func() time.Time { return now }
+	p.Progress()
+	check := StuckQueuesCheck(time.Minute)
+
+	now = now.Add(2 * time.Minute)
+	if err := check(nil); err != nil {
+		t.Errorf("empty queue: check() = %v, want nil", err)
+	}
+
+	// Items arriving in a queue that was idle for longer than the timeout
+	// have only just started waiting.
+	queue = 3
+	if err := check(nil); err != nil {
+		t.Errorf("idle queue with new items: check() = %v, want nil", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := check(nil); err == nil {
+		t.Error("stuck queue: check() = nil, want an error")
+	}
+	if err := StuckQueuesCheck(0)(nil); err != nil {
+		t.Errorf("disabled check: check() = %v, want nil", err)
+	}
+
+	p.Progress()
+	if err := check(nil); err != nil {
+		t.Errorf("queue that made progress: check() = %v, want nil", err)
+	}
+
+	// Draining the queue makes it idle again.
+	queue = 0
+	p.Progress()
+	now = now.Add(2 * time.Minute)
+	queue = 1
+	if err := check(nil); err != nil {
+		t.Errorf("drained queue with new items: check() = %v, want nil", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	p.Untrack()
+	if err := check(nil); err != nil {
+		t.Errorf("untracked queue: check() = %v, want nil", err)
+	}
+}
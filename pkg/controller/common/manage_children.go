@@ -19,26 +19,42 @@ package common
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"metacontroller/pkg/controller/common/api"
+	"metacontroller/pkg/events"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
+	"metacontroller/pkg/tracing"
 	"strings"
 
 	"github.com/cespare/xxhash/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/client-go/util/csaupgrade"
 	"k8s.io/utils/ptr"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	dynamicapply "metacontroller/pkg/dynamic/apply"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 )
 
-func ApplyUpdate(orig, update *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+// ApplyUpdate computes the full updated object in the style of "kubectl apply".
+// Lists are merged as described by the given schema of the object, if any,
+// and the last-applied configuration is stored with the given encoding.
+func ApplyUpdate(orig, update *unstructured.Unstructured, schema *dynamicapply.Schema, encoding dynamicapply.LastAppliedEncoding) (*unstructured.Unstructured, error) {
 	// The controller only returns a partial object.
 	// We compute the full updated object in the style of "kubectl apply".
 	lastApplied, err := dynamicapply.GetLastApplied(orig)
@@ -50,7 +66,7 @@ func ApplyUpdate(orig, update *unstructured.Unstructured) (*unstructured.Unstruc
 	nullifyLastAppliedAnnotation(update)
 
 	newObj := &unstructured.Unstructured{}
-	newObj.Object, err = dynamicapply.Merge(orig.UnstructuredContent(), lastApplied, update.UnstructuredContent())
+	newObj.Object, err = dynamicapply.MergeWithSchema(orig.UnstructuredContent(), lastApplied, update.UnstructuredContent(), schema)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +83,12 @@ func ApplyUpdate(orig, update *unstructured.Unstructured) (*unstructured.Unstruc
 		return nil, fmt.Errorf("failed to revert .status: %w", err)
 	}
 
-	if err = dynamicapply.SetLastApplied(newObj, update.UnstructuredContent()); err != nil {
+	if lastApplied != nil && DeepEqual(lastApplied, update.UnstructuredContent()) {
+		// Keep the stored annotation, so that changing the encoding alone
+		// doesn't cause updates, or even recreates, of all children.
+		return newObj, nil
+	}
+	if err = dynamicapply.SetLastAppliedWithEncoding(newObj, update.UnstructuredContent(), encoding); err != nil {
 		logging.Logger.Error(err, "failed to set lastApplied")
 	}
 	return newObj, nil
@@ -135,6 +156,7 @@ func MakeControllerRef(parent *unstructured.Unstructured) *metav1.OwnerReference
 
 type ChildUpdateStrategy interface {
 	GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod
+	GetOnImmutableFieldError(apiGroup, kind string) v1alpha1.ImmutableFieldErrorPolicy
 }
 
 func ManageChildren(
@@ -142,7 +164,7 @@ func ManageChildren(
 	dynClient *dynamicclientset.Clientset,
 	updateStrategy ChildUpdateStrategy,
 	parent *unstructured.Unstructured,
-	observedChildren, desiredChildren api.ObjectMap, ssaOptions *ApplyOptions) error {
+	observedChildren, desiredChildren api.ObjectMap, applyOptions ChildApplyOptions) error {
 	// If some operations fail, keep trying others so, for example,
 	// we don't block recovery (create new Pod) on a failed delete.
 	var errs []error
@@ -166,7 +188,8 @@ func ManageChildren(
 			if desiredChildren != nil {
 				desiredObjects = desiredChildren.GetObjectsByGVK(gvk)
 			}
-			if err := deleteChildren(ctx, client, parent, objects, desiredObjects); err != nil {
+			options := applyOptions.GetApplyOptions(client.Group, client.Kind)
+			if err := deleteChildren(ctx, client, parent, objects, desiredObjects, options); err != nil {
 				errs = append(errs, err)
 				continue
 			}
@@ -192,7 +215,7 @@ func ManageChildren(
 			if observedChildren != nil {
 				observedObjects = observedChildren.GetObjectsByGVK(gvk)
 			}
-			if err := updateChildren(ctx, client, updateStrategy, parent, observedObjects, objects, ssaOptions); err != nil {
+			if err := updateChildren(ctx, client, updateStrategy, parent, observedObjects, objects, applyOptions.GetApplyOptions(client.Group, client.Kind)); err != nil {
 				errs = append(errs, err)
 				continue
 			}
@@ -202,7 +225,8 @@ func ManageChildren(
 	return utilerrors.NewAggregate(errs)
 }
 
-func deleteChildren(ctx context.Context, client *dynamicclientset.ResourceClient, parent *unstructured.Unstructured, observed, desired map[string]*unstructured.Unstructured) error {
+func deleteChildren(ctx context.Context, client *dynamicclientset.ResourceClient, parent *unstructured.Unstructured, observed, desired map[string]*unstructured.Unstructured, options *ApplyOptions) error {
+	dryRun := options.DryRun
 	var errs []error
 	for name, obj := range observed {
 		if obj.GetDeletionTimestamp() != nil {
@@ -217,14 +241,17 @@ func deleteChildren(ctx context.Context, client *dynamicclientset.ResourceClient
 			// Explicitly request deletion propagation, which is what users expect,
 			// since some objects default to orphaning for backwards compatibility.
 			propagation := metav1.DeletePropagationBackground
+			spanCtx, span := tracing.Start(ctx, "delete child", childAttributes(parent, obj)...)
 			err := client.Namespace(obj.GetNamespace()).Delete(
-				ctx,
+				spanCtx,
 				obj.GetName(),
 				metav1.DeleteOptions{
 					Preconditions:     &metav1.Preconditions{UID: &uid},
 					PropagationPolicy: &propagation,
+					DryRun:            dryRun.Options(),
 				},
 			)
+			tracing.End(span, err)
 			if err != nil {
 				if apierrors.IsNotFound(err) {
 					// Swallow the error since there's no point retrying if the child is gone.
@@ -234,6 +261,12 @@ func deleteChildren(ctx context.Context, client *dynamicclientset.ResourceClient
 				}
 				continue
 			}
+			if dryRun.Enabled() {
+				dryRun.Report(parent, DryRunDelete, obj, nil)
+				continue
+			}
+			options.Metrics.childOperation(client, childDelete)
+			options.Audit.Record(ctx, parent, childDelete, obj, nil)
 
 			lastUpdateName := lastUpdateCacheKey(client, obj)
 			lastUpdatedCache.Delete(lastUpdateName)
@@ -242,6 +275,12 @@ func deleteChildren(ctx context.Context, client *dynamicclientset.ResourceClient
 	return utilerrors.NewAggregate(errs)
 }
 
+// childAttributes returns the trace attributes of an operation on a child of
+// the parent.
+func childAttributes(parent, child *unstructured.Unstructured) []attribute.KeyValue {
+	return append(tracing.Object("parent", parent), tracing.Object("child", child)...)
+}
+
 type lastUpdate struct {
 	hash               uint64
 	resourcegeneration int64
@@ -282,10 +321,22 @@ type ApplyOperation struct {
 	parent         *unstructured.Unstructured
 	observed       *unstructured.Unstructured
 	desired        *unstructured.Unstructured
+	// live is true if observed was read from the API server, so that it has
+	// the data that the informer caches drop.
+	live bool
+	// deleted is true if the apply deleted the child, to recreate it on the
+	// next sync.
+	deleted bool
 }
 
 type baseApply struct {
-	client *dynamicclientset.ResourceClient
+	client        *dynamicclientset.ResourceClient
+	dryRun        *DryRun
+	eventRecorder record.EventRecorder
+	metrics       *ControllerMetrics
+	audit         *Audit
+	redactor      *redact.Redactor
+	stripped      dynamicinformer.StripOptions
 }
 
 type Applier interface {
@@ -299,17 +350,19 @@ type ServerSideApply struct {
 
 type DynamicApply struct {
 	*baseApply
+	lastAppliedEncoding dynamicapply.LastAppliedEncoding
 }
 
 func NewApplier(client *dynamicclientset.ResourceClient, ssaOptions *ApplyOptions) (Applier, error) {
 	switch ssaOptions.Strategy {
 	case ApplyStrategyDynamicApply, "":
 		return &DynamicApply{
-			baseApply: &baseApply{client: client},
+			baseApply:           &baseApply{client: client, dryRun: ssaOptions.DryRun, eventRecorder: ssaOptions.EventRecorder, metrics: ssaOptions.Metrics, audit: ssaOptions.Audit, redactor: ssaOptions.Redactor, stripped: ssaOptions.Stripped},
+			lastAppliedEncoding: ssaOptions.LastAppliedEncoding,
 		}, nil
 	case ApplyStrategyServerSideApply:
 		return &ServerSideApply{
-			baseApply:  &baseApply{client: client},
+			baseApply:  &baseApply{client: client, dryRun: ssaOptions.DryRun, eventRecorder: ssaOptions.EventRecorder, metrics: ssaOptions.Metrics, audit: ssaOptions.Audit, redactor: ssaOptions.Redactor, stripped: ssaOptions.Stripped},
 			ssaOptions: ssaOptions,
 		}, nil
 	default:
@@ -343,21 +396,85 @@ func updateChildren(ctx context.Context, client *dynamicclientset.ResourceClient
 		return err
 	}
 
+	manageStatus := ssaOptions.ManageStatus && client.HasSubresource("status")
 	for name, obj := range desired {
+		// The applier strips the status, so take it first.
+		status, hasStatus := obj.Object["status"]
+		observedChild := observed[name]
 		operation := &ApplyOperation{
 			updateStrategy: updateStrategy,
 			parent:         parent,
-			observed:       observed[name],
+			observed:       observedChild,
 			desired:        obj,
 		}
 
-		if err := applier.Apply(ctx, operation); err != nil {
+		spanCtx, span := tracing.Start(ctx, "apply child", childAttributes(parent, obj)...)
+		err := applier.Apply(spanCtx, operation)
+		tracing.End(span, err)
+		if err != nil {
 			errs = append(errs, err)
+			continue
+		}
+		// The status of a new or recreated child is written on the next sync,
+		// once the child is observed.
+		if manageStatus && hasStatus && operation.observed != nil && !operation.deleted {
+			if err := updateChildStatus(ctx, client, parent, operation.observed, status, ssaOptions); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
 	return utilerrors.NewAggregate(errs)
 }
 
+// updateChildStatus writes the status returned by the hook for an existing
+// child through its status subresource.
+func updateChildStatus(ctx context.Context, client *dynamicclientset.ResourceClient, parent, observed *unstructured.Unstructured, status interface{}, options *ApplyOptions) error {
+	if observed.GetDeletionTimestamp() != nil || equality.Semantic.DeepEqual(observed.Object["status"], status) {
+		return nil
+	}
+	if dryRun := options.DryRun; dryRun.Enabled() {
+		updated := observed.DeepCopy()
+		updated.Object["status"] = runtime.DeepCopyJSONValue(status)
+		dryRun.Report(parent, DryRunUpdateStatus, observed, updated)
+		return nil
+	}
+
+	logging.Logger.Info("Updating status", "parent", parent, "child", observed)
+	spanCtx, span := tracing.Start(ctx, "update child status", childAttributes(parent, observed)...)
+	// The status the update replaced, for the audit log.
+	var oldStatus interface{}
+	changed := false
+	_, err := client.Namespace(observed.GetNamespace()).AtomicStatusUpdate(spanCtx, observed, func(obj *unstructured.Unstructured) bool {
+		if equality.Semantic.DeepEqual(obj.Object["status"], status) {
+			changed = false
+			return false
+		}
+		oldStatus, changed = obj.Object["status"], true
+		obj.Object["status"] = runtime.DeepCopyJSONValue(status)
+		return true
+	})
+	tracing.End(span, err)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Swallow the error since there's no point retrying if the child is gone.
+			logging.Logger.Info("Failed to update status, child object has been deleted", "parent", parent, "child", observed)
+			return nil
+		}
+		return fmt.Errorf("can't update status of %v: %w", describeObject(observed), err)
+	}
+	if !changed {
+		return nil
+	}
+	options.Metrics.childOperation(client, childUpdateStatus)
+	if options.Audit != nil {
+		original, updated := observed.DeepCopy(), observed.DeepCopy()
+		original.Object["status"] = oldStatus
+		updated.Object["status"] = runtime.DeepCopyJSONValue(status)
+		options.Audit.Record(ctx, parent, childUpdateStatus, original, updated)
+	}
+	return nil
+}
+
 func isCacheInvalid(cache *LastUpdateCache, cacheKeyName string, observed *unstructured.Unstructured, desiredHash uint64) bool {
 	lastUpdated, ok := cache.Load(cacheKeyName)
 	switch {
@@ -398,6 +515,10 @@ func (h *ServerSideApply) Apply(ctx context.Context, op *ApplyOperation) error {
 	cacheKeyName := lastUpdateCacheKey(h.client, op.desired)
 
 	storeState := func(generation int64, resourceVersion string, uid types.UID) {
+		if h.dryRun.Enabled() {
+			// Nothing was written, so report the change again on the next sync.
+			return
+		}
 		lastUpdatedCache.Set(cacheKeyName, desiredHash, generation, resourceVersion, uid)
 		logging.Logger.Info("Cache updated", "name", cacheKeyName)
 	}
@@ -419,11 +540,24 @@ func (h *ServerSideApply) Apply(ctx context.Context, op *ApplyOperation) error {
 			defer storeState(op.observed.GetGeneration(), op.observed.GetResourceVersion(), op.observed.GetUID())
 			return h.childUpdateOnDelete(ctx, op)
 		case v1alpha1.ChildUpdateRecreate, v1alpha1.ChildUpdateRollingRecreate:
+			if ok, err := h.observeStripped(ctx, op); !ok {
+				return err
+			}
+			// migrate first, so that the dry run below also prunes fields owned by dynamic apply
+			if err := h.migrateFromDynamicApply(ctx, op); err != nil {
+				if apierrors.IsNotFound(err) {
+					// Swallow the error since there's no point retrying if the child is gone.
+					logging.Logger.Info("Failed to migrate child from dynamic apply, child object has been deleted", "parent", op.parent, "child", op.desired)
+					return nil
+				}
+				logging.Logger.Error(err, "Failed to migrate child from dynamic apply", "parent", op.parent, "child", op.desired)
+				return err
+			}
 			// run a dry run with server-side apply to check if the update would cause any changes. If it doesn't cause any changes, we can skip the delete and recreate process
 			// which can be disruptive for some resources like Jobs and Pods. If it does cause changes, we will proceed with the delete and recreate process as before.
 			dryRunPatched, err := h.client.Namespace(op.desired.GetNamespace()).Patch(ctx, op.desired.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
 				FieldManager: h.ssaOptions.FieldManager,
-				Force:        ptr.To(true),
+				Force:        ptr.To(h.ssaOptions.IsForced()),
 				DryRun:       []string{metav1.DryRunAll},
 			})
 			if err != nil {
@@ -443,7 +577,7 @@ func (h *ServerSideApply) Apply(ctx context.Context, op *ApplyOperation) error {
 				return fmt.Errorf("generation or UID changed during dry run for server-side apply, expected generation %d and UID %s but got generation %d and UID %s, this likely means the object was updated after we read it, retrying on next reconciliation loop to avoid unnecessary delete and recreate", op.observed.GetGeneration(), op.observed.GetUID(), dryRunPatched.GetGeneration(), dryRunPatched.GetUID())
 			}
 
-			if DeepEqual(sanitizeForSSACompare(dryRunPatched).UnstructuredContent(), sanitizeForSSACompare(op.observed).UnstructuredContent()) {
+			if DeepEqual(h.asObserved(op, dryRunPatched).UnstructuredContent(), sanitizeForSSACompare(op.observed).UnstructuredContent()) {
 				logging.Logger.Info("Skipping delete and recreate, no changes detected in dry run", "parent", op.parent, "child", op.desired)
 				storeState(op.observed.GetGeneration(), op.observed.GetResourceVersion(), op.observed.GetUID())
 				return nil
@@ -457,24 +591,19 @@ func (h *ServerSideApply) Apply(ctx context.Context, op *ApplyOperation) error {
 			return nil
 
 		case v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRollingInPlace:
-			// check if observed object hast last applied annotation
-			_, hasLastApplied := op.observed.GetAnnotations()[dynamicapply.LastAppliedAnnotation]
-			if hasLastApplied {
-				// if observed object has has last applied annotation we need to remove it
-				// from the remote object to avoid conflicts
-				annotationNameForJsonPatch := strings.ReplaceAll(strings.ReplaceAll(dynamicapply.LastAppliedAnnotation, "~", "~0"), "/", "~1")
-				patch := fmt.Sprintf(`[{"op": "remove", "path": "/metadata/annotations/%s"}]`, annotationNameForJsonPatch)
-				_, err := h.client.Namespace(op.desired.GetNamespace()).Patch(ctx, op.desired.GetName(), types.JSONPatchType, []byte(patch), metav1.PatchOptions{})
-				if err != nil {
-					if apierrors.IsNotFound(err) {
-						// Swallow the error since there's no point retrying if the child is gone.
-						logging.Logger.Info("Failed to remove last applied annotation, child object has been deleted", "parent", op.parent, "child", op.desired)
-					} else {
-						logging.Logger.Error(err, "Failed to remove last applied annotation from observed object", "parent", op.parent, "child", op.desired)
-						return err
-					}
+			if ok, err := h.observeStripped(ctx, op); !ok {
+				return err
+			}
+			// if observed object was last written by dynamic apply, we need to migrate it
+			// to avoid conflicts with the fields owned by dynamic apply
+			if err := h.migrateFromDynamicApply(ctx, op); err != nil {
+				if apierrors.IsNotFound(err) {
+					// Swallow the error since there's no point retrying if the child is gone.
+					logging.Logger.Info("Failed to migrate child from dynamic apply, child object has been deleted", "parent", op.parent, "child", op.desired)
 					return nil
 				}
+				logging.Logger.Error(err, "Failed to migrate child from dynamic apply", "parent", op.parent, "child", op.desired)
+				return err
 			}
 			// we intentionally fall through to the server-side apply below, which will update or create the object as needed
 		default:
@@ -485,14 +614,21 @@ func (h *ServerSideApply) Apply(ctx context.Context, op *ApplyOperation) error {
 	// create or update the object using server-side apply
 	patched, err := h.client.Namespace(op.desired.GetNamespace()).Patch(ctx, op.desired.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
 		FieldManager: h.ssaOptions.FieldManager,
-		Force:        ptr.To(true),
+		Force:        ptr.To(h.ssaOptions.IsForced()),
+		DryRun:       h.dryRun.Options(),
 	})
 
 	if err != nil {
 		switch {
+		case apierrors.IsConflict(err) && !h.ssaOptions.IsForced():
+			// Without force, a conflict means some fields are owned by another field manager.
+			logging.Logger.Error(err, "Failed to apply server-side apply due to field manager conflict", "parent", op.parent, "child", op.desired)
+			return fmt.Errorf("can't apply %v: %w", describeObject(op.desired), err)
 		case apierrors.IsConflict(err):
 			// it is possible that the object was modified after this sync was started, ignore conflict since we will reconcile again
 			logging.Logger.Info("Failed to apply server-side apply due to outdated resourceVersion", "parent", op.parent, "child", op.desired)
+		case op.observed != nil && h.recreateOnImmutableFieldError(op, err):
+			return h.childUpdateRecreate(ctx, op)
 		default:
 			logging.Logger.Error(err, "Failed to apply server-side apply", "parent", op.parent, "child", op.desired)
 			return err
@@ -500,10 +636,124 @@ func (h *ServerSideApply) Apply(ctx context.Context, op *ApplyOperation) error {
 		return nil
 	}
 
+	if h.dryRun.Enabled() {
+		h.reportApplied(op, patched)
+		return nil
+	}
+	// Server-side apply leaves the status alone, so it's no part of the write.
+	if op.observed == nil {
+		h.recordWrite(ctx, op, childCreate, nil, withoutStatus(patched))
+	} else {
+		h.recordWrite(ctx, op, childUpdate, withoutStatus(op.observed), withoutStatus(patched))
+	}
 	storeState(patched.GetGeneration(), patched.GetResourceVersion(), patched.GetUID())
 	return nil
 }
 
+func withoutStatus(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	c := obj.DeepCopy()
+	unstructured.RemoveNestedField(c.Object, "status")
+	return c
+}
+
+// reportApplied reports the result of a dry run of server-side apply, if it
+// would have created or changed the child.
+func (h *ServerSideApply) reportApplied(op *ApplyOperation, patched *unstructured.Unstructured) {
+	if op.observed == nil {
+		h.dryRun.Report(op.parent, DryRunCreate, nil, sanitizeForSSACompare(patched))
+		return
+	}
+	observed, updated := sanitizeForSSACompare(op.observed), h.asObserved(op, patched)
+	if !DeepEqual(observed.UnstructuredContent(), updated.UnstructuredContent()) {
+		h.dryRun.Report(op.parent, DryRunUpdate, observed, updated)
+	}
+}
+
+// observeStripped reads the live child, if the apply depends on data that the
+// caches drop: the last-applied annotation, which tells whether the child has
+// to be migrated, or fields that the desired object sets. It returns false if
+// the child is gone or was replaced.
+func (h *ServerSideApply) observeStripped(ctx context.Context, op *ApplyOperation) (bool, error) {
+	if op.live || (!h.stripped.StripsAnnotation(dynamicapply.LastAppliedAnnotation) && !h.stripped.Sets(op.desired.UnstructuredContent())) {
+		return true, nil
+	}
+	return h.readLive(ctx, op)
+}
+
+// asObserved returns the object as it would be observed, to compare it with
+// the observed child: without system fields and status, and without the data
+// that the caches drop if the child is a cached one.
+func (h *ServerSideApply) asObserved(op *ApplyOperation, obj *unstructured.Unstructured) *unstructured.Unstructured {
+	c := sanitizeForSSACompare(obj)
+	if !op.live {
+		h.stripped.Strip(c.UnstructuredContent())
+	}
+	return c
+}
+
+// migrateFromDynamicApply prepares a child that was last written by dynamic
+// apply for server-side apply, by removing its last-applied annotation. If
+// migration field managers are configured, it also moves the ownership of
+// their fields to the server-side apply field manager, like
+// `kubectl apply --server-side` does after client-side apply, so that fields
+// the hook stops returning are pruned.
+func (h *ServerSideApply) migrateFromDynamicApply(ctx context.Context, op *ApplyOperation) error {
+	if _, ok := op.observed.GetAnnotations()[dynamicapply.LastAppliedAnnotation]; !ok {
+		return nil
+	}
+
+	var patch []map[string]interface{}
+	if len(h.ssaOptions.MigrateFieldManagers) > 0 {
+		current := op.observed
+		if len(current.GetManagedFields()) == 0 {
+			// Informers don't cache managed fields, so read them.
+			live, err := GetLive(ctx, h.client, op.observed)
+			if err != nil {
+				return err
+			}
+			if live == nil {
+				return apierrors.NewNotFound(h.client.GroupResource(), op.observed.GetName())
+			}
+			current = live
+		}
+		upgraded := current.DeepCopy()
+		err := csaupgrade.UpgradeManagedFields(upgraded, sets.New(h.ssaOptions.MigrateFieldManagers...), h.ssaOptions.FieldManager)
+		if err != nil {
+			recordSSAMigration(h.client, ssaMigrationFailed)
+			return fmt.Errorf("can't upgrade managed fields of %v: %w", describeObject(op.observed), err)
+		}
+		if !equality.Semantic.DeepEqual(upgraded.GetManagedFields(), current.GetManagedFields()) {
+			patch = append(patch,
+				map[string]interface{}{"op": "replace", "path": "/metadata/managedFields", "value": upgraded.GetManagedFields()},
+				// Make sure nobody else changed the managed fields since we read them.
+				map[string]interface{}{"op": "replace", "path": "/metadata/resourceVersion", "value": current.GetResourceVersion()},
+			)
+		}
+	}
+	annotationNameForJsonPatch := strings.ReplaceAll(strings.ReplaceAll(dynamicapply.LastAppliedAnnotation, "~", "~0"), "/", "~1")
+	patch = append(patch, map[string]interface{}{"op": "remove", "path": "/metadata/annotations/" + annotationNameForJsonPatch})
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info("Migrating child from dynamic apply to server-side apply", "parent", op.parent, "child", op.desired, "fieldManagers", h.ssaOptions.MigrateFieldManagers)
+	migrated, err := h.client.Namespace(op.desired.GetNamespace()).Patch(ctx, op.desired.GetName(), types.JSONPatchType, data, metav1.PatchOptions{DryRun: h.dryRun.Options()})
+	if err != nil {
+		if !apierrors.IsNotFound(err) && !h.dryRun.Enabled() {
+			recordSSAMigration(h.client, ssaMigrationFailed)
+		}
+		return err
+	}
+	if h.dryRun.Enabled() {
+		h.dryRun.Report(op.parent, DryRunUpdate, op.observed, migrated)
+		return nil
+	}
+	recordSSAMigration(h.client, ssaMigrationMigrated)
+	h.audit.Record(ctx, op.parent, childUpdate, withoutStatus(op.observed), withoutStatus(migrated))
+	return nil
+}
+
 func (h *baseApply) claimOwnership(op *ApplyOperation) {
 	// We always claim everything we create or update.
 	controllerRef := MakeControllerRef(op.parent)
@@ -530,21 +780,98 @@ func (h *baseApply) childUpdateRecreate(ctx context.Context, op *ApplyOperation)
 		metav1.DeleteOptions{
 			Preconditions:     &metav1.Preconditions{UID: &uid},
 			PropagationPolicy: &propagation,
+			DryRun:            h.dryRun.Options(),
 		},
 	)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// Swallow the error since there's no point retrying if the child is gone.
 			logging.Logger.Info("Failed to delete child, child object has been deleted", "parent", op.parent, "child", op.desired)
+			op.deleted = true
 			return nil
 		} else {
 			return err
 		}
 	}
 
+	op.deleted = true
+	h.recordWrite(ctx, op, childDelete, op.observed, nil)
+	h.dryRun.Report(op.parent, DryRunDelete, op.observed, nil)
 	return nil
 }
 
+// recordWrite counts and audits a write to a child, unless it was a dry run.
+// The original object is nil for creates, and updated is nil for deletes.
+func (h *baseApply) recordWrite(ctx context.Context, op *ApplyOperation, operation string, original, updated *unstructured.Unstructured) {
+	if !h.dryRun.Enabled() {
+		h.metrics.childOperation(h.client, operation)
+		h.audit.Record(ctx, op.parent, operation, original, updated)
+	}
+}
+
+// recreateOnImmutableFieldError reports whether the in-place update of a
+// child failed because it changes an immutable field, and the update strategy
+// says to recreate the child in that case. It records an Event on the parent
+// naming the field.
+func (h *baseApply) recreateOnImmutableFieldError(op *ApplyOperation, err error) bool {
+	if op.updateStrategy.GetOnImmutableFieldError(h.client.Group, h.client.Kind) != v1alpha1.ImmutableFieldErrorRecreate {
+		return false
+	}
+	field, ok := immutableField(err)
+	if !ok {
+		return false
+	}
+	logging.Logger.Info("Recreating child", "parent", op.parent, "child", op.desired, "reason", "Immutable field changed", "field", field)
+	if h.eventRecorder != nil {
+		h.eventRecorder.Eventf(op.parent, corev1.EventTypeWarning, events.ReasonRecreatingChild,
+			"Recreating %v: can't update immutable field %s", describeObject(op.observed), field)
+	}
+	return true
+}
+
+// immutableField returns the field named by an Invalid error from the API
+// server that says the field can't be changed.
+func immutableField(err error) (string, bool) {
+	if !apierrors.IsInvalid(err) {
+		return "", false
+	}
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Details == nil {
+		return "", false
+	}
+	for _, cause := range status.Status().Details.Causes {
+		message := strings.ToLower(cause.Message)
+		// Built-in types report "field is immutable", or for some types,
+		// like StatefulSets, "updates to ... are forbidden". Validation
+		// rules of CRDs conventionally say the value is immutable.
+		if strings.Contains(message, "immutable") ||
+			(cause.Type == metav1.CauseType(field.ErrorTypeForbidden) && strings.Contains(message, "updates to")) {
+			return cause.Field, true
+		}
+	}
+	return "", false
+}
+
+// readLive replaces the cached child of the operation with the live one. It
+// returns false if the child is gone or was replaced, in which case the
+// informer will tell us about it.
+func (h *baseApply) readLive(ctx context.Context, op *ApplyOperation) (bool, error) {
+	live, err := GetLive(ctx, h.client, op.observed)
+	if err != nil || live == nil {
+		return false, err
+	}
+	op.observed, op.live = live, true
+	return true, nil
+}
+
+// recordLastApplied remembers that the child has the last-applied
+// configuration of the desired object, if the caches drop it.
+func (h *DynamicApply) recordLastApplied(op *ApplyOperation) {
+	if h.stripped.StripsAnnotation(dynamicapply.LastAppliedAnnotation) {
+		recordLastApplied(op.observed, op.desired)
+	}
+}
+
 func (h *baseApply) childUpdateOnDelete(ctx context.Context, op *ApplyOperation) error {
 	// This means we don't try to update anything unless it gets deleted
 	// by someone else (we won't delete it ourselves).
@@ -554,8 +881,21 @@ func (h *baseApply) childUpdateOnDelete(ctx context.Context, op *ApplyOperation)
 
 func (h *DynamicApply) Apply(ctx context.Context, op *ApplyOperation) error {
 	if op.observed != nil {
+		// Children that are left alone needn't be read.
+		if method := op.updateStrategy.GetMethod(h.client.Group, h.client.Kind); !op.live && method != v1alpha1.ChildUpdateOnDelete && method != "" {
+			observed, live, err := ObservedForApply(ctx, h.client, h.stripped, op.observed, op.desired, h.lastAppliedEncoding)
+			if err != nil {
+				return err
+			}
+			if observed == nil {
+				// The child is gone or was replaced; wait for the informer.
+				return nil
+			}
+			op.observed, op.live = observed, live
+		}
+
 		// Update
-		newObj, err := ApplyUpdate(op.observed, op.desired)
+		newObj, err := ApplyUpdate(op.observed, op.desired, h.client.Schema(), h.lastAppliedEncoding)
 		if err != nil {
 			return err
 		}
@@ -563,6 +903,7 @@ func (h *DynamicApply) Apply(ctx context.Context, op *ApplyOperation) error {
 		// Attempt an update, if the 3-way merge resulted in any changes.
 		if DeepEqual(newObj.UnstructuredContent(), op.observed.UnstructuredContent()) {
 			// Nothing changed.
+			h.recordLastApplied(op)
 			return nil
 		}
 
@@ -571,7 +912,7 @@ func (h *DynamicApply) Apply(ctx context.Context, op *ApplyOperation) error {
 			if err != nil {
 				logging.Logger.V(5).Error(err, "Cannot create merge patch to visualize diff")
 			} else {
-				rawMergePatch := json.RawMessage(mergePatch)
+				rawMergePatch := json.RawMessage(h.redactor.Patch(mergePatch, newObj))
 				logging.Logger.V(5).Info("Diff between observed and desired", "mergePatch", rawMergePatch)
 			}
 		}
@@ -589,9 +930,17 @@ func (h *DynamicApply) Apply(ctx context.Context, op *ApplyOperation) error {
 		case v1alpha1.ChildUpdateRecreate, v1alpha1.ChildUpdateRollingRecreate:
 			return h.childUpdateRecreate(ctx, op)
 		case v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRollingInPlace:
+			if h.stripped.Lossy() && !op.live {
+				// The update replaces the whole object, so compute it from the
+				// live child, which has the data that the caches drop.
+				if ok, err := h.readLive(ctx, op); !ok {
+					return err
+				}
+				return h.Apply(ctx, op)
+			}
 			// Update the object in-place.
 			logging.Logger.Info("Updating", "parent", op.parent, "child", op.desired, "reason", "InPlace update strategy selected")
-			if _, err := h.client.Namespace(op.desired.GetNamespace()).Update(ctx, newObj, metav1.UpdateOptions{}); err != nil {
+			if _, err := h.client.Namespace(op.desired.GetNamespace()).Update(ctx, newObj, metav1.UpdateOptions{DryRun: h.dryRun.Options()}); err != nil {
 				switch {
 				case apierrors.IsNotFound(err):
 					// Swallow the error since there's no point retrying if the child is gone.
@@ -599,9 +948,17 @@ func (h *DynamicApply) Apply(ctx context.Context, op *ApplyOperation) error {
 				case apierrors.IsConflict(err):
 					// it is possible that the object was modified after this sync was started, ignore conflict since we will reconcile again
 					logging.Logger.Info("Failed to update child due to outdated resourceVersion", "parent", op.parent, "child", op.desired)
+				case h.recreateOnImmutableFieldError(op, err):
+					return h.childUpdateRecreate(ctx, op)
 				default:
 					return err
 				}
+				return nil
+			}
+			h.recordWrite(ctx, op, childUpdate, op.observed, newObj)
+			h.dryRun.Report(op.parent, DryRunUpdate, op.observed, newObj)
+			if !h.dryRun.Enabled() {
+				h.recordLastApplied(op)
 			}
 			return nil // end of InPlace update case
 		default:
@@ -617,20 +974,23 @@ func (h *DynamicApply) Apply(ctx context.Context, op *ApplyOperation) error {
 	// a 3-way merge upon update, in the style of "kubectl apply".
 	//
 	// Make sure this happens before we add anything else to the object.
-	if err := dynamicapply.SetLastApplied(op.desired, op.desired.UnstructuredContent()); err != nil {
+	if err := dynamicapply.SetLastAppliedWithEncoding(op.desired, op.desired.UnstructuredContent(), h.lastAppliedEncoding); err != nil {
 		return err
 	}
 
 	h.claimOwnership(op)
 
-	if _, err := h.client.Namespace(op.desired.GetNamespace()).Create(ctx, op.desired, metav1.CreateOptions{}); err != nil {
+	if _, err := h.client.Namespace(op.desired.GetNamespace()).Create(ctx, op.desired, metav1.CreateOptions{DryRun: h.dryRun.Options()}); err != nil {
 		if apierrors.IsAlreadyExists(err) {
 			// Swallow the error since there's no point retrying if the child already exists
 			logging.Logger.Info("Failed to create child, child object already exists", "parent", op.parent, "child", op.desired)
 		} else {
 			return err
 		}
+		return nil
 	}
 
+	h.recordWrite(ctx, op, childCreate, nil, op.desired)
+	h.dryRun.Report(op.parent, DryRunCreate, nil, op.desired)
 	return nil
 }
@@ -17,26 +17,36 @@ limitations under the License.
 package common
 
 import (
+	"bytes"
 	"context"
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
 	commonv2 "metacontroller/pkg/controller/common/api/v2"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
 	. "metacontroller/pkg/internal/testutils/common"
 	. "metacontroller/pkg/internal/testutils/dynamic/clientset"
 	. "metacontroller/pkg/internal/testutils/dynamic/discovery"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/client-go/dynamic/fake"
 	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -46,18 +56,40 @@ func (m childUpdateOnDeleteStrategy) GetMethod(string, string) v1alpha1.ChildUpd
 	return v1alpha1.ChildUpdateOnDelete
 }
 
+func (m childUpdateOnDeleteStrategy) GetOnImmutableFieldError(string, string) v1alpha1.ImmutableFieldErrorPolicy {
+	return v1alpha1.ImmutableFieldErrorFail
+}
+
 type childUpdateInPlaceStrategy struct{}
 
 func (m childUpdateInPlaceStrategy) GetMethod(string, string) v1alpha1.ChildUpdateMethod {
 	return v1alpha1.ChildUpdateInPlace
 }
 
+func (m childUpdateInPlaceStrategy) GetOnImmutableFieldError(string, string) v1alpha1.ImmutableFieldErrorPolicy {
+	return v1alpha1.ImmutableFieldErrorFail
+}
+
 type childUpdateRecreateStrategy struct{}
 
 func (m childUpdateRecreateStrategy) GetMethod(string, string) v1alpha1.ChildUpdateMethod {
 	return v1alpha1.ChildUpdateRecreate
 }
 
+func (m childUpdateRecreateStrategy) GetOnImmutableFieldError(string, string) v1alpha1.ImmutableFieldErrorPolicy {
+	return v1alpha1.ImmutableFieldErrorFail
+}
+
+type childUpdateInPlaceOrRecreateStrategy struct{}
+
+func (m childUpdateInPlaceOrRecreateStrategy) GetMethod(string, string) v1alpha1.ChildUpdateMethod {
+	return v1alpha1.ChildUpdateInPlace
+}
+
+func (m childUpdateInPlaceOrRecreateStrategy) GetOnImmutableFieldError(string, string) v1alpha1.ImmutableFieldErrorPolicy {
+	return v1alpha1.ImmutableFieldErrorRecreate
+}
+
 func TestRevertObjectMetaSystemFields(t *testing.T) {
 	origJSON := `{
 		"metadata": {
@@ -517,3 +549,623 @@ func TestManageChildren(t *testing.T) {
 		})
 	}
 }
+
+func TestServerSideApply_migrateFromDynamicApply(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	observed := NewDefaultUnstructured()
+	observed.SetResourceVersion("42")
+	observed.SetAnnotations(map[string]string{dynamicapply.LastAppliedAnnotation: `{"spec":{"replicas":1}}`})
+	observed.SetManagedFields([]metav1.ManagedFieldsEntry{{
+		Manager:    "metacontroller",
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: TestAPIVersion,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+	}})
+
+	tests := []struct {
+		name          string
+		fieldManagers []string
+		wantOps       []string
+		wantManager   string
+	}{
+		{
+			name:    "only removes annotation without field managers",
+			wantOps: []string{"remove"},
+		},
+		{
+			name:          "moves field ownership to field manager",
+			fieldManagers: []string{"metacontroller"},
+			wantOps:       []string{"replace", "replace", "remove"},
+			wantManager:   "metacontroller-ssa",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch []map[string]interface{}
+			simpleDynClient := fake.NewSimpleDynamicClient(scheme, observed.DeepCopy())
+			simpleDynClient.PrependReactor("patch", "*", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				if err := json.Unmarshal(action.(clientgotesting.PatchAction).GetPatch(), &patch); err != nil {
+					return true, nil, err
+				}
+				return true, observed, nil
+			})
+			client, err := NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient).Kind(TestAPIVersion, TestKind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			applier := &ServerSideApply{
+				baseApply:  &baseApply{client: client},
+				ssaOptions: &ApplyOptions{FieldManager: "metacontroller-ssa", MigrateFieldManagers: tt.fieldManagers},
+			}
+
+			err = applier.migrateFromDynamicApply(context.TODO(), &ApplyOperation{observed: observed, desired: NewDefaultUnstructured()})
+			if err != nil {
+				t.Fatalf("migrateFromDynamicApply() error = %v", err)
+			}
+
+			var ops []string
+			for _, op := range patch {
+				ops = append(ops, op["op"].(string))
+			}
+			if !reflect.DeepEqual(ops, tt.wantOps) {
+				t.Errorf("patch ops = %v, want %v", ops, tt.wantOps)
+			}
+			if tt.wantManager != "" {
+				managedFields := patch[0]["value"].([]interface{})
+				entry := managedFields[0].(map[string]interface{})
+				if entry["manager"] != tt.wantManager || entry["operation"] != string(metav1.ManagedFieldsOperationApply) {
+					t.Errorf("managed fields = %v, want %v owned by Apply", managedFields, tt.wantManager)
+				}
+			}
+		})
+	}
+}
+
+func TestManageChildren_dryRun(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	orphan := NewDefaultUnstructured()
+	orphan.SetName("orphan")
+	desired := NewDefaultUnstructured()
+	desired.SetName("desired")
+	desired.Object["spec"] = map[string]interface{}{"password": "secret"}
+
+	var writes []string
+	simpleDynClient := fake.NewSimpleDynamicClient(scheme, orphan.DeepCopy())
+	simpleDynClient.PrependReactor("*", "*", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		var dryRun []string
+		switch action := action.(type) {
+		case clientgotesting.CreateActionImpl:
+			dryRun = action.GetCreateOptions().DryRun
+		case clientgotesting.DeleteActionImpl:
+			dryRun = action.GetDeleteOptions().DryRun
+		default:
+			return false, nil, nil
+		}
+		writes = append(writes, action.GetVerb())
+		if !reflect.DeepEqual(dryRun, []string{metav1.DryRunAll}) {
+			t.Errorf("%s sent with DryRun %v, want [All]", action.GetVerb(), dryRun)
+		}
+		return true, nil, nil
+	})
+	recorder := record.NewFakeRecorder(10)
+	redactor, err := redact.New([]v1alpha1.RedactionRule{{Kind: TestKind, Paths: []string{"{.spec.password}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := &ApplyOptions{Strategy: ApplyStrategyDynamicApply, DryRun: NewDryRun("CompositeController", "test", recorder, redactor)}
+
+	err = ManageChildren(context.TODO(), NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient), childUpdateInPlaceStrategy{}, parent,
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{orphan}),
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{desired}),
+		options)
+	if err != nil {
+		t.Fatalf("ManageChildren() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(writes, []string{"delete", "create"}) {
+		t.Errorf("writes = %v, want [delete create]", writes)
+	}
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	if len(events) != 2 || !strings.HasPrefix(events[0], "Normal DryRun Dry run, not persisted: Delete TestKind testns/orphan") ||
+		!strings.HasPrefix(events[1], "Normal DryRun Dry run, not persisted: Create TestKind testns/desired: {") ||
+		!strings.Contains(events[1], `"password":"REDACTED"`) {
+		t.Errorf("events = %q", events)
+	}
+	if got := testutil.ToFloat64(dryRunOperations.WithLabelValues("CompositeController/test", TestGroup, TestKind, string(DryRunCreate))); got != 1 {
+		t.Errorf("dry run creates = %v, want 1", got)
+	}
+}
+
+func TestManageChildren_collectingDryRun(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	orphan := NewDefaultUnstructured()
+	orphan.SetName("orphan")
+	desired := NewDefaultUnstructured()
+	desired.SetName("desired")
+
+	simpleDynClient := fake.NewSimpleDynamicClient(scheme, orphan.DeepCopy())
+	var writes []DryRunWrite
+	options := &ApplyOptions{
+		Strategy: ApplyStrategyDynamicApply,
+		DryRun: NewCollectingDryRun("CompositeController", "collecting", func(write DryRunWrite) {
+			writes = append(writes, write)
+		}),
+	}
+
+	err := ManageChildren(context.TODO(), NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient), childUpdateInPlaceStrategy{}, parent,
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{orphan}),
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{desired}),
+		options)
+	if err != nil {
+		t.Fatalf("ManageChildren() error = %v", err)
+	}
+
+	if len(writes) != 2 {
+		t.Fatalf("writes = %v, want a delete and a create", writes)
+	}
+	if writes[0].Action != DryRunDelete || writes[0].Original.GetName() != "orphan" || writes[0].Updated != nil {
+		t.Errorf("writes[0] = %+v, want the delete of orphan", writes[0])
+	}
+	if writes[1].Action != DryRunCreate || writes[1].Original != nil || writes[1].Updated.GetName() != "desired" {
+		t.Errorf("writes[1] = %+v, want the create of desired", writes[1])
+	}
+	if got := testutil.ToFloat64(dryRunOperations.WithLabelValues("CompositeController/collecting", TestGroup, TestKind, string(DryRunCreate))); got != 0 {
+		t.Errorf("dry run creates = %v, want 0", got)
+	}
+}
+
+func TestManageChildren_recreateOnImmutableFieldError(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	observed := NewDefaultUnstructured()
+	observed.SetName("child")
+	observed.Object["spec"] = map[string]interface{}{"selector": "old"}
+	desired := observed.DeepCopy()
+	desired.Object["spec"] = map[string]interface{}{"selector": "new"}
+
+	immutable := apierrors.NewInvalid(schema.GroupKind{Group: TestGroup, Kind: TestKind}, "child", field.ErrorList{
+		field.Invalid(field.NewPath("spec", "selector"), "new", "field is immutable"),
+	})
+
+	tests := []struct {
+		name           string
+		updateStrategy ChildUpdateStrategy
+		wantErr        bool
+		wantEvents     int
+	}{
+		{
+			name:           "fail",
+			updateStrategy: childUpdateInPlaceStrategy{},
+			wantErr:        true,
+		},
+		{
+			name:           "recreate",
+			updateStrategy: childUpdateInPlaceOrRecreateStrategy{},
+			wantEvents:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted bool
+			simpleDynClient := fake.NewSimpleDynamicClient(scheme, observed.DeepCopy())
+			simpleDynClient.PrependReactor("update", "*", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, nil, immutable
+			})
+			simpleDynClient.PrependReactor("delete", "*", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				deleted = true
+				return false, nil, nil
+			})
+			recorder := record.NewFakeRecorder(10)
+			options := &ApplyOptions{Strategy: ApplyStrategyDynamicApply, EventRecorder: recorder}
+
+			err := ManageChildren(context.TODO(), NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient), tt.updateStrategy, parent,
+				commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{observed}),
+				commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{desired}),
+				options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ManageChildren() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if deleted == tt.wantErr {
+				t.Errorf("deleted = %v, want %v", deleted, !tt.wantErr)
+			}
+			if len(recorder.Events) != tt.wantEvents {
+				t.Fatalf("events = %d, want %d", len(recorder.Events), tt.wantEvents)
+			}
+			if tt.wantEvents > 0 {
+				if event := <-recorder.Events; !strings.HasPrefix(event, "Warning RecreatingChild") || !strings.Contains(event, "spec.selector") {
+					t.Errorf("event = %q, want it to name spec.selector", event)
+				}
+			}
+		})
+	}
+}
+
+func TestManageChildren_manageStatus(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultStatusAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	observed := NewDefaultUnstructured()
+	observed.SetName("child")
+	observed.Object["status"] = map[string]interface{}{"phase": "Pending"}
+	desired := observed.DeepCopy()
+	desired.SetLabels(map[string]string{"version": "2"})
+	desired.Object["status"] = map[string]interface{}{"phase": "Ready"}
+
+	tests := []struct {
+		name           string
+		updateStrategy ChildUpdateStrategy
+		manageStatus   bool
+		wantStatus     interface{}
+		wantDeleted    bool
+	}{
+		{
+			name:           "unmanaged",
+			updateStrategy: childUpdateInPlaceStrategy{},
+			wantStatus:     map[string]interface{}{"phase": "Pending"},
+		},
+		{
+			name:           "managed",
+			updateStrategy: childUpdateInPlaceStrategy{},
+			manageStatus:   true,
+			wantStatus:     map[string]interface{}{"phase": "Ready"},
+		},
+		{
+			name:           "recreated",
+			updateStrategy: childUpdateRecreateStrategy{},
+			manageStatus:   true,
+			wantDeleted:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			simpleDynClient := fake.NewSimpleDynamicClient(scheme, observed.DeepCopy())
+			dynClient := NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient)
+			options := &ApplyOptions{Strategy: ApplyStrategyDynamicApply, ManageStatus: tt.manageStatus}
+
+			err := ManageChildren(context.TODO(), dynClient, tt.updateStrategy, parent,
+				commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{observed}),
+				commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{desired}),
+				options)
+			if err != nil {
+				t.Fatalf("ManageChildren() error = %v", err)
+			}
+
+			if tt.wantDeleted {
+				// Nothing is done with the child once it's deleted.
+				actions := simpleDynClient.Actions()
+				if last := actions[len(actions)-1]; last.GetVerb() != "delete" {
+					t.Errorf("last action = %v, want delete", last)
+				}
+			}
+			client, err := dynClient.Kind(TestAPIVersion, TestKind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			child, err := client.Namespace(observed.GetNamespace()).Get(context.TODO(), observed.GetName(), metav1.GetOptions{})
+			if tt.wantDeleted {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("child wasn't deleted: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantStatus, child.Object["status"]); diff != "" {
+				t.Errorf("status mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestImmutableField(t *testing.T) {
+	gk := schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	tests := []struct {
+		name      string
+		err       error
+		wantField string
+		wantOK    bool
+	}{
+		{
+			name: "immutable",
+			err: apierrors.NewInvalid(gk, "test", field.ErrorList{
+				field.Invalid(field.NewPath("spec", "selector"), nil, "field is immutable"),
+			}),
+			wantField: "spec.selector",
+			wantOK:    true,
+		},
+		{
+			name: "forbidden update",
+			err: apierrors.NewInvalid(gk, "test", field.ErrorList{
+				field.Forbidden(field.NewPath("spec"), "updates to statefulset spec for fields other than 'replicas' are forbidden"),
+			}),
+			wantField: "spec",
+			wantOK:    true,
+		},
+		{
+			name: "other invalid",
+			err: apierrors.NewInvalid(gk, "test", field.ErrorList{
+				field.Required(field.NewPath("spec", "template"), ""),
+			}),
+		},
+		{
+			name: "not invalid",
+			err:  apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "statefulsets"}, "test", nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotField, gotOK := immutableField(tt.err)
+			if gotField != tt.wantField || gotOK != tt.wantOK {
+				t.Errorf("immutableField() = %q, %v, want %q, %v", gotField, gotOK, tt.wantField, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestApplyUpdate_lastAppliedEncoding(t *testing.T) {
+	desired := NewDefaultUnstructured()
+	desired.Object["data"] = map[string]interface{}{"key": "value"}
+	observed := desired.DeepCopy()
+	if err := dynamicapply.SetLastApplied(observed, desired.UnstructuredContent()); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unchanged configuration keeps its encoding.
+	updated, err := ApplyUpdate(observed, desired.DeepCopy(), nil, dynamicapply.LastAppliedEncodingGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !DeepEqual(observed.UnstructuredContent(), updated.UnstructuredContent()) {
+		t.Errorf("ApplyUpdate() changed the child: %v", updated)
+	}
+
+	// A changed configuration is stored with the new encoding.
+	desired.Object["data"] = map[string]interface{}{"key": "other"}
+	updated, err = ApplyUpdate(observed, desired.DeepCopy(), nil, dynamicapply.LastAppliedEncodingGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if annotation := updated.GetAnnotations()[dynamicapply.LastAppliedAnnotation]; !strings.HasPrefix(annotation, "gzip+base64:") {
+		t.Errorf("last-applied annotation = %q, want it compressed", annotation)
+	}
+	lastApplied, err := dynamicapply.GetLastApplied(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lastApplied["data"], desired.Object["data"]) {
+		t.Errorf("last-applied data = %v, want %v", lastApplied["data"], desired.Object["data"])
+	}
+}
+
+func TestManageChildren_stripped(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	desired := NewDefaultUnstructured()
+	desired.SetName("child")
+	live := desired.DeepCopy()
+	if err := dynamicapply.SetLastApplied(live, desired.UnstructuredContent()); err != nil {
+		t.Fatal(err)
+	}
+	annotations := live.GetAnnotations()
+	annotations["example.com/big"] = "data"
+	live.SetAnnotations(annotations)
+	labeled := desired.DeepCopy()
+	labeled.SetLabels(map[string]string{"app": "test"})
+
+	tests := []struct {
+		name     string
+		stripped []string
+		desired  *unstructured.Unstructured
+		// gets is how many times the child is read, in each of two syncs.
+		gets           int
+		wantLabels     bool
+		wantAnnotation bool
+	}{
+		{
+			name:           "not stripped",
+			desired:        labeled,
+			wantLabels:     true,
+			wantAnnotation: true,
+		},
+		{
+			name:           "unchanged",
+			stripped:       []string{"example.com/big"},
+			desired:        desired,
+			wantAnnotation: true,
+		},
+		{
+			name:           "changed",
+			stripped:       []string{"example.com/big"},
+			desired:        labeled,
+			gets:           1,
+			wantLabels:     true,
+			wantAnnotation: true,
+		},
+		{
+			name:           "last-applied stripped",
+			stripped:       []string{"example.com/big", dynamicapply.LastAppliedAnnotation},
+			desired:        desired,
+			gets:           1,
+			wantAnnotation: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastAppliedRecords = NewLastUpdateCache()
+			simpleDynClient := fake.NewSimpleDynamicClient(scheme, live.DeepCopy())
+			dynClient := NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient)
+			options := &ApplyOptions{Strategy: ApplyStrategyDynamicApply, Stripped: dynamicinformer.StripOptions{Annotations: tt.stripped}}
+			client, err := dynClient.Kind(TestAPIVersion, TestKind)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for sync := 0; sync < 2; sync++ {
+				// The informer cache drops the annotations, like it would.
+				cached, err := client.Namespace(live.GetNamespace()).Get(context.TODO(), live.GetName(), metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				options.Stripped.Strip(cached.UnstructuredContent())
+				simpleDynClient.ClearActions()
+
+				err = ManageChildren(context.TODO(), dynClient, childUpdateInPlaceStrategy{}, parent,
+					commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{cached}),
+					commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{tt.desired.DeepCopy()}),
+					options)
+				if err != nil {
+					t.Fatalf("ManageChildren() error = %v", err)
+				}
+				gets := 0
+				for _, action := range simpleDynClient.Actions() {
+					if action.GetVerb() == "get" {
+						gets++
+					}
+				}
+				// Once the child is updated, or its last-applied configuration
+				// is known, the next sync doesn't read it.
+				if want := tt.gets * (1 - sync); gets != want {
+					t.Errorf("sync %d: gets = %d, want %d", sync, gets, want)
+				}
+			}
+
+			child, err := client.Namespace(live.GetNamespace()).Get(context.TODO(), live.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := child.GetLabels()["app"] == "test"; got != tt.wantLabels {
+				t.Errorf("labels = %v, want the desired labels: %v", child.GetLabels(), tt.wantLabels)
+			}
+			if _, ok := child.GetAnnotations()["example.com/big"]; ok != tt.wantAnnotation {
+				t.Errorf("annotations = %v, want annotation kept: %v", child.GetAnnotations(), tt.wantAnnotation)
+			}
+		})
+	}
+}
+
+func TestManageChildren_metrics(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	observed := NewDefaultUnstructured()
+	observed.SetName("observed")
+	orphan := NewDefaultUnstructured()
+	orphan.SetName("orphan")
+	updated := observed.DeepCopy()
+	updated.SetLabels(map[string]string{"app": "test"})
+	created := NewDefaultUnstructured()
+	created.SetName("created")
+
+	simpleDynClient := fake.NewSimpleDynamicClient(scheme, observed.DeepCopy(), orphan.DeepCopy())
+	dynClient := NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient)
+	metrics := NewControllerMetrics(CompositeController, "test-operations")
+	defer metrics.Delete()
+	options := &ApplyOptions{Strategy: ApplyStrategyDynamicApply, Metrics: metrics}
+
+	err := ManageChildren(context.TODO(), dynClient, childUpdateInPlaceStrategy{}, parent,
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{observed, orphan}),
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{updated, created}),
+		options)
+	if err != nil {
+		t.Fatalf("ManageChildren() error = %v", err)
+	}
+
+	gv := schema.FromAPIVersionAndKind(TestAPIVersion, TestKind)
+	for _, operation := range []string{childCreate, childUpdate, childDelete} {
+		counter := childOperations.WithLabelValues("CompositeController", "test-operations", gv.Group, gv.Version, gv.Kind, operation)
+		if got := testutil.ToFloat64(counter); got != 1 {
+			t.Errorf("%s operations = %v, want 1", operation, got)
+		}
+	}
+}
+
+func TestManageChildren_audit(t *testing.T) {
+	logging.InitLogging(&zap.Options{})
+	testResourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+
+	parent := NewDefaultUnstructured()
+	observed := NewDefaultUnstructured()
+	observed.SetName("observed")
+	orphan := NewDefaultUnstructured()
+	orphan.SetName("orphan")
+	updated := observed.DeepCopy()
+	updated.SetLabels(map[string]string{"app": "test"})
+	created := NewDefaultUnstructured()
+	created.SetName("created")
+	created.Object["spec"] = map[string]interface{}{"password": "secret"}
+
+	simpleDynClient := fake.NewSimpleDynamicClient(scheme, observed.DeepCopy(), orphan.DeepCopy())
+	dynClient := NewClientset(NewDefaultRestConfig(), testResourceMap, simpleDynClient)
+	var out bytes.Buffer
+	redactor, err := redact.New([]v1alpha1.RedactionRule{{Kind: TestKind, Paths: []string{"{.spec.password}"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := &ApplyOptions{
+		Strategy: ApplyStrategyDynamicApply,
+		Audit:    NewAudit(audit.NewSink(&out)).ForController("CompositeController", "test-audit", redactor),
+	}
+
+	ctx := audit.WithSync(context.TODO())
+	audit.SetHookResponseETag(ctx, `"etag"`)
+	err = ManageChildren(ctx, dynClient, childUpdateInPlaceStrategy{}, parent,
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{observed, orphan}),
+		commonv2.MakeUniformObjectMap(parent, []*unstructured.Unstructured{updated, created}),
+		options)
+	if err != nil {
+		t.Fatalf("ManageChildren() error = %v", err)
+	}
+
+	records := make(map[string]audit.Record)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record audit.Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Controller != "CompositeController/test-audit" || record.Parent == nil || record.HookResponseETag != `"etag"` {
+			t.Errorf("record = %+v, want one of the controller, parent and hook response", record)
+		}
+		records[record.Object.Name] = record
+	}
+	tests := []struct {
+		name      string
+		operation string
+		patch     string
+	}{
+		{name: "orphan", operation: AuditDelete},
+		{name: "observed", operation: AuditUpdate, patch: `"labels":{"app":"test"}`},
+		{name: "created", operation: AuditCreate, patch: `"spec":{"password":"REDACTED"}`},
+	}
+	if len(records) != len(tests) {
+		t.Errorf("got %d records, want %d", len(records), len(tests))
+	}
+	for _, tt := range tests {
+		record := records[tt.name]
+		if record.Operation != tt.operation {
+			t.Errorf("%s: operation = %q, want %q", tt.name, record.Operation, tt.operation)
+		}
+		if !strings.Contains(string(record.MergePatch), tt.patch) || (tt.patch == "") != (len(record.MergePatch) == 0) {
+			t.Errorf("%s: merge patch = %s, want it to contain %s", tt.name, record.MergePatch, tt.patch)
+		}
+	}
+}
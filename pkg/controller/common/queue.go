@@ -0,0 +1,117 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Queue is a rate-limited workqueue, like the one of
+// workqueue.NewTypedRateLimitingQueueWithConfig, that also remembers since
+// when its items are waiting to be processed.
+type Queue struct {
+	workqueue.TypedDelayingInterface[string]
+	rateLimiter workqueue.TypedRateLimiter[string]
+
+	mutex sync.Mutex
+	// waiting maps the items to the time they are, or will be, ready to be
+	// processed.
+	waiting map[string]time.Time
+	now     func() time.Time
+}
+
+var _ workqueue.TypedRateLimitingInterface[string] = &Queue{}
+
+// NewQueue returns a new rate-limited workqueue with the given name, which
+// is used in metrics.
+func NewQueue(name string) *Queue {
+	return &Queue{
+		TypedDelayingInterface: workqueue.NewTypedDelayingQueueWithConfig(workqueue.TypedDelayingQueueConfig[string]{
+			Name: name,
+		}),
+		rateLimiter: workqueue.DefaultTypedControllerRateLimiter[string](),
+		waiting:     make(map[string]time.Time),
+		now:         time.Now,
+	}
+}
+
+func (q *Queue) Add(item string) {
+	q.wait(item, q.now())
+	q.TypedDelayingInterface.Add(item)
+}
+
+func (q *Queue) AddAfter(item string, duration time.Duration) {
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	q.wait(item, q.now().Add(duration))
+	q.TypedDelayingInterface.AddAfter(item, duration)
+}
+
+func (q *Queue) AddRateLimited(item string) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+func (q *Queue) Forget(item string) {
+	q.rateLimiter.Forget(item)
+}
+
+func (q *Queue) NumRequeues(item string) int {
+	return q.rateLimiter.NumRequeues(item)
+}
+
+func (q *Queue) Get() (string, bool) {
+	item, shutdown := q.TypedDelayingInterface.Get()
+	if !shutdown {
+		q.mutex.Lock()
+		delete(q.waiting, item)
+		q.mutex.Unlock()
+	}
+	return item, shutdown
+}
+
+// OldestItemAge returns how long the item that is ready for the longest time
+// has been waiting to be processed, or 0 if no item is ready.
+func (q *Queue) OldestItemAge() time.Duration {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := q.now()
+	var oldest time.Duration
+	for _, ready := range q.waiting {
+		if age := now.Sub(ready); age > oldest {
+			oldest = age
+		}
+	}
+	return oldest
+}
+
+// wait records that the item is ready at the given time, unless it's ready
+// earlier already.
+func (q *Queue) wait(item string, ready time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if earlier, ok := q.waiting[item]; ok && !earlier.After(ready) {
+		return
+	}
+	q.waiting[item] = ready
+}
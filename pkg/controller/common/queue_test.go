@@ -0,0 +1,64 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueOldestItemAge(t *testing.T) {
+	now := time.Now()
+	q := NewQueue("test")
+	defer q.ShutDown()
+	q.now = func() time.Time { return now }
+
+	if age := q.OldestItemAge(); age != 0 {
+		t.Errorf("empty queue: OldestItemAge() = %v, want 0", age)
+	}
+
+	q.Add("a")
+	now = now.Add(time.Second)
+	q.Add("b")
+	q.AddAfter("c", time.Hour)
+	now = now.Add(time.Second)
+	// Adding an item again doesn't reset its age.
+	q.Add("a")
+	if age := q.OldestItemAge(); age != 2*time.Second {
+		t.Errorf("OldestItemAge() = %v, want 2s", age)
+	}
+
+	if item, _ := q.Get(); item != "a" {
+		t.Fatalf("Get() = %q, want a", item)
+	}
+	q.Done("a")
+	if age := q.OldestItemAge(); age != time.Second {
+		t.Errorf("after Get: OldestItemAge() = %v, want 1s", age)
+	}
+
+	// Delayed items only age once they are ready.
+	now = now.Add(time.Hour + time.Minute)
+	if age := q.OldestItemAge(); age != time.Hour+time.Minute+time.Second {
+		t.Errorf("OldestItemAge() = %v, want the age of b", age)
+	}
+	if item, _ := q.Get(); item != "b" {
+		t.Fatalf("Get() = %q, want b", item)
+	}
+	if age := q.OldestItemAge(); age != time.Minute+time.Second {
+		t.Errorf("OldestItemAge() = %v, want the age of c", age)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+const (
+	ssaMigrationMigrated = "migrated"
+	ssaMigrationFailed   = "failed"
+)
+
+var ssaMigrations = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "metacontroller",
+		Subsystem: "ssa_migration",
+		Name:      "children_total",
+		Help:      "A counter for children migrated from dynamic apply to server-side apply, by result.",
+	},
+	[]string{"group", "kind", "result"},
+)
+
+func init() {
+	controllerruntimemetrics.Registry.MustRegister(ssaMigrations)
+}
+
+func recordSSAMigration(client *dynamicclientset.ResourceClient, result string) {
+	ssaMigrations.WithLabelValues(client.Group, client.Kind, result).Inc()
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"time"
+)
+
+// ControllerStatus describes the state of a running CompositeController or
+// DecoratorController, for introspection.
+type ControllerStatus struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
+	// QueueDepth is the number of parents waiting to be synced.
+	QueueDepth int `json:"queueDepth"`
+	// OldestItemAgeSeconds is how long the parent that waits the longest has
+	// been ready to be synced.
+	OldestItemAgeSeconds float64 `json:"oldestItemAgeSeconds"`
+	// CustomizeCacheSize is the number of cached customize hook responses.
+	CustomizeCacheSize int `json:"customizeCacheSize"`
+	// ETagCacheSize is the number of hook responses cached for ETags.
+	ETagCacheSize int `json:"eTagCacheSize"`
+}
+
+// SetQueue fills in the status of the workqueue of the controller.
+func (s *ControllerStatus) SetQueue(queue interface{ Len() int }) {
+	s.QueueDepth = queue.Len()
+	if q, ok := queue.(interface{ OldestItemAge() time.Duration }); ok {
+		s.OldestItemAgeSeconds = q.OldestItemAge().Seconds()
+	}
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"metacontroller/pkg/controller/common/api"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// SyncPhase is a part of the sync of a parent that is timed on its own.
+type SyncPhase string
+
+const (
+	// SyncPhaseHook is a call of the customize, sync or finalize hook.
+	SyncPhaseHook SyncPhase = "hook"
+	// SyncPhaseApply is the reconciliation of the children.
+	SyncPhaseApply SyncPhase = "apply"
+	// SyncPhaseStatus is the update of the parent status.
+	SyncPhaseStatus SyncPhase = "status"
+)
+
+// Operations on children, as counted by metacontroller_child_operations_total.
+const (
+	childCreate       = "create"
+	childUpdate       = "update"
+	childUpdateStatus = "update_status"
+	childDelete       = "delete"
+)
+
+var controllerLabels = []string{"controller_type", "controller_name"}
+
+var (
+	syncDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "metacontroller",
+			Subsystem: "sync",
+			Name:      "duration_seconds",
+			Help:      "A histogram of the duration of parent syncs.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		controllerLabels,
+	)
+	syncPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "metacontroller",
+			Subsystem: "sync",
+			Name:      "phase_duration_seconds",
+			Help:      "A histogram of the duration of the hook, apply and status phases of parent syncs.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		append(controllerLabels, "phase"),
+	)
+	syncErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "metacontroller",
+			Subsystem: "sync",
+			Name:      "errors_total",
+			Help:      "A counter for failed parent syncs, by reason.",
+		},
+		append(controllerLabels, "reason"),
+	)
+	childOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "metacontroller",
+			Subsystem: "child",
+			Name:      "operations_total",
+			Help:      "A counter for children created, updated and deleted by controllers.",
+		},
+		append(controllerLabels, "group", "version", "kind", "operation"),
+	)
+	managedParents = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "metacontroller",
+			Name:      "managed_parents",
+			Help:      "The number of parents that controllers manage.",
+		},
+		controllerLabels,
+	)
+	managedChildren = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "metacontroller",
+			Name:      "managed_children",
+			Help:      "The number of children that controllers manage, as last observed.",
+		},
+		append(controllerLabels, "group", "version", "kind"),
+	)
+	rollingUpdatePendingChildren = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "metacontroller",
+			Subsystem: "rolling_update",
+			Name:      "pending_children",
+			Help:      "The number of children that wait to be moved to the latest ControllerRevision.",
+		},
+		append(controllerLabels, "group", "kind"),
+	)
+	rollingUpdateMovedChildren = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "metacontroller",
+			Subsystem: "rolling_update",
+			Name:      "moved_children_total",
+			Help:      "A counter for children moved to the latest ControllerRevision.",
+		},
+		append(controllerLabels, "group", "kind"),
+	)
+
+	controllerMetrics = []interface {
+		prometheus.Collector
+		DeletePartialMatch(labels prometheus.Labels) int
+	}{
+		syncDuration,
+		syncPhaseDuration,
+		syncErrors,
+		childOperations,
+		managedParents,
+		managedChildren,
+		rollingUpdatePendingChildren,
+		rollingUpdateMovedChildren,
+	}
+)
+
+func init() {
+	for _, metric := range controllerMetrics {
+		controllerruntimemetrics.Registry.MustRegister(metric)
+	}
+}
+
+// SyncErrorReasoner is implemented by errors that are counted under a reason
+// of their own in metacontroller_sync_errors_total.
+type SyncErrorReasoner interface {
+	SyncErrorReason() string
+}
+
+// phaseError is an error that happened in a phase of a sync.
+type phaseError struct {
+	phase SyncPhase
+	err   error
+}
+
+func (e *phaseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *phaseError) Unwrap() error {
+	return e.err
+}
+
+// syncErrorReason returns the reason a sync failed with the given error: the
+// reason of the innermost SyncErrorReasoner, or else the phase it failed in.
+func syncErrorReason(err error) string {
+	var reasoner SyncErrorReasoner
+	if errors.As(err, &reasoner) {
+		return reasoner.SyncErrorReason()
+	}
+	var phaseErr *phaseError
+	if errors.As(err, &phaseErr) {
+		switch phaseErr.phase {
+		case SyncPhaseHook:
+			return "HookFailed"
+		case SyncPhaseApply:
+			return "ApplyFailed"
+		case SyncPhaseStatus:
+			return "StatusUpdateFailed"
+		}
+	}
+	return "SyncFailed"
+}
+
+// parentCounts are the numbers of children of a parent that were last
+// observed.
+type parentCounts struct {
+	children map[schema.GroupVersionKind]int
+	pending  map[schema.GroupKind]int
+}
+
+// ControllerMetrics records the metrics of the syncs of a CompositeController
+// or DecoratorController. A nil *ControllerMetrics records nothing.
+type ControllerMetrics struct {
+	labels []string
+
+	mutex   sync.Mutex
+	parents map[string]*parentCounts
+	// children and pending are the sums of the counts of all parents.
+	children map[schema.GroupVersionKind]int
+	pending  map[schema.GroupKind]int
+}
+
+// NewControllerMetrics returns the metrics of the given controller.
+func NewControllerMetrics(controllerType ControllerType, controllerName string) *ControllerMetrics {
+	return &ControllerMetrics{
+		labels:   []string{controllerType.String(), controllerName},
+		parents:  make(map[string]*parentCounts),
+		children: make(map[schema.GroupVersionKind]int),
+		pending:  make(map[schema.GroupKind]int),
+	}
+}
+
+func (m *ControllerMetrics) withLabels(values ...string) []string {
+	return append(append(make([]string, 0, len(m.labels)+len(values)), m.labels...), values...)
+}
+
+// ObserveSync records the duration of a sync that started at the given time,
+// and its error, if any.
+func (m *ControllerMetrics) ObserveSync(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	syncDuration.WithLabelValues(m.labels...).Observe(time.Since(start).Seconds())
+	if err != nil {
+		syncErrors.WithLabelValues(m.withLabels(syncErrorReason(err))...).Inc()
+	}
+}
+
+// ObservePhase records the duration of a sync phase that started at the given
+// time. It returns the error of the phase, marked with the phase, so that a
+// failed sync is counted under the reason of the phase.
+func (m *ControllerMetrics) ObservePhase(phase SyncPhase, start time.Time, err error) error {
+	if err != nil {
+		err = &phaseError{phase: phase, err: err}
+	}
+	if m == nil {
+		return err
+	}
+	syncPhaseDuration.WithLabelValues(m.withLabels(string(phase))...).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// ObserveChildren records the children last observed for the parent with the
+// given key.
+func (m *ControllerMetrics) ObserveChildren(parentKey string, children api.ObjectMap) {
+	if m == nil {
+		return
+	}
+	counts := make(map[schema.GroupVersionKind]int)
+	if children != nil {
+		for _, gvk := range children.GetAllGVKs() {
+			if n := len(children.GetObjectsByGVK(gvk)); n > 0 {
+				counts[gvk] = n
+			}
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	parent := m.parent(parentKey)
+	m.updateChildren(parent.children, counts)
+	parent.children = counts
+	managedParents.WithLabelValues(m.labels...).Set(float64(len(m.parents)))
+}
+
+// ObserveRollout records the number of children of the parent with the given
+// key that wait to be moved to the latest ControllerRevision.
+func (m *ControllerMetrics) ObserveRollout(parentKey string, pending map[schema.GroupKind]int) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	parent := m.parent(parentKey)
+	m.updatePending(parent.pending, pending)
+	parent.pending = pending
+}
+
+// ChildMoved records that a child was moved to the latest ControllerRevision.
+func (m *ControllerMetrics) ChildMoved(apiGroup, kind string) {
+	if m == nil {
+		return
+	}
+	rollingUpdateMovedChildren.WithLabelValues(m.withLabels(apiGroup, kind)...).Inc()
+}
+
+// ForgetParent stops counting the parent with the given key, once it's gone.
+func (m *ControllerMetrics) ForgetParent(parentKey string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	parent, ok := m.parents[parentKey]
+	if !ok {
+		return
+	}
+	m.updateChildren(parent.children, nil)
+	m.updatePending(parent.pending, nil)
+	delete(m.parents, parentKey)
+	managedParents.WithLabelValues(m.labels...).Set(float64(len(m.parents)))
+}
+
+// Delete removes all metrics of the controller, once it's stopped.
+func (m *ControllerMetrics) Delete() {
+	if m == nil {
+		return
+	}
+	labels := prometheus.Labels{controllerLabels[0]: m.labels[0], controllerLabels[1]: m.labels[1]}
+	for _, metric := range controllerMetrics {
+		metric.DeletePartialMatch(labels)
+	}
+}
+
+// childOperation records a write to a child.
+func (m *ControllerMetrics) childOperation(client *dynamicclientset.ResourceClient, operation string) {
+	if m == nil {
+		return
+	}
+	childOperations.WithLabelValues(m.withLabels(client.Group, client.Version, client.Kind, operation)...).Inc()
+}
+
+func (m *ControllerMetrics) parent(parentKey string) *parentCounts {
+	parent, ok := m.parents[parentKey]
+	if !ok {
+		parent = &parentCounts{}
+		m.parents[parentKey] = parent
+	}
+	return parent
+}
+
+func (m *ControllerMetrics) updateChildren(old, cur map[schema.GroupVersionKind]int) {
+	for gvk, n := range old {
+		m.children[gvk] -= n
+	}
+	for gvk, n := range cur {
+		m.children[gvk] += n
+	}
+	for gvk := range merge(old, cur) {
+		managedChildren.WithLabelValues(m.withLabels(gvk.Group, gvk.Version, gvk.Kind)...).Set(float64(m.children[gvk]))
+		if m.children[gvk] == 0 {
+			delete(m.children, gvk)
+		}
+	}
+}
+
+func (m *ControllerMetrics) updatePending(old, cur map[schema.GroupKind]int) {
+	for gk, n := range old {
+		m.pending[gk] -= n
+	}
+	for gk, n := range cur {
+		m.pending[gk] += n
+	}
+	for gk := range merge(old, cur) {
+		rollingUpdatePendingChildren.WithLabelValues(m.withLabels(gk.Group, gk.Kind)...).Set(float64(m.pending[gk]))
+		if m.pending[gk] == 0 {
+			delete(m.pending, gk)
+		}
+	}
+}
+
+// merge returns the union of the keys of two maps.
+func merge[K comparable](a, b map[K]int) map[K]bool {
+	keys := make(map[K]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	commonv2 "metacontroller/pkg/controller/common/api/v2"
+)
+
+type reasonError struct{}
+
+func (reasonError) Error() string           { return "reason" }
+func (reasonError) SyncErrorReason() string { return "Custom" }
+
+func TestSyncErrorReason(t *testing.T) {
+	var m *ControllerMetrics
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "plain error", err: errors.New("failed"), want: "SyncFailed"},
+		{name: "hook phase", err: m.ObservePhase(SyncPhaseHook, time.Now(), errors.New("failed")), want: "HookFailed"},
+		{
+			name: "wrapped apply phase",
+			err:  fmt.Errorf("can't reconcile: %w", m.ObservePhase(SyncPhaseApply, time.Now(), errors.New("failed"))),
+			want: "ApplyFailed",
+		},
+		{name: "reasoner in phase", err: m.ObservePhase(SyncPhaseHook, time.Now(), reasonError{}), want: "Custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncErrorReason(tt.err); got != tt.want {
+				t.Errorf("syncErrorReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if err := m.ObservePhase(SyncPhaseStatus, time.Now(), nil); err != nil {
+		t.Errorf("ObservePhase() = %v, want nil", err)
+	}
+}
+
+func newChildren(parent *unstructured.Unstructured, kind string, n int) commonv2.UniformObjectMap {
+	var children []*unstructured.Unstructured
+	for i := range n {
+		child := &unstructured.Unstructured{}
+		child.SetAPIVersion("v1")
+		child.SetKind(kind)
+		child.SetName(fmt.Sprintf("child-%d", i))
+		children = append(children, child)
+	}
+	return commonv2.MakeUniformObjectMap(parent, children)
+}
+
+func TestControllerMetrics(t *testing.T) {
+	m := NewControllerMetrics(CompositeController, "test-metrics")
+	defer m.Delete()
+	parent := &unstructured.Unstructured{}
+	pods := managedChildren.WithLabelValues("CompositeController", "test-metrics", "", "v1", "Pod")
+	parents := managedParents.WithLabelValues("CompositeController", "test-metrics")
+
+	m.ObserveChildren("a", newChildren(parent, "Pod", 2))
+	m.ObserveChildren("b", newChildren(parent, "Pod", 3))
+	if got := testutil.ToFloat64(pods); got != 5 {
+		t.Errorf("managed children = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(parents); got != 2 {
+		t.Errorf("managed parents = %v, want 2", got)
+	}
+
+	m.ObserveChildren("a", newChildren(parent, "Pod", 1))
+	if got := testutil.ToFloat64(pods); got != 4 {
+		t.Errorf("after resync: managed children = %v, want 4", got)
+	}
+
+	pending := rollingUpdatePendingChildren.WithLabelValues("CompositeController", "test-metrics", "", "Pod")
+	m.ObserveRollout("b", map[schema.GroupKind]int{{Kind: "Pod"}: 2})
+	if got := testutil.ToFloat64(pending); got != 2 {
+		t.Errorf("pending children = %v, want 2", got)
+	}
+
+	m.ForgetParent("b")
+	if got := testutil.ToFloat64(pods); got != 1 {
+		t.Errorf("after forgetting a parent: managed children = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(pending); got != 0 {
+		t.Errorf("after forgetting a parent: pending children = %v, want 0", got)
+	}
+	if got := testutil.ToFloat64(parents); got != 1 {
+		t.Errorf("after forgetting a parent: managed parents = %v, want 1", got)
+	}
+
+	m.ObserveSync(time.Now(), m.ObservePhase(SyncPhaseStatus, time.Now(), errors.New("failed")))
+	if got := testutil.ToFloat64(syncErrors.WithLabelValues("CompositeController", "test-metrics", "StatusUpdateFailed")); got != 1 {
+		t.Errorf("sync errors = %v, want 1", got)
+	}
+
+	m.Delete()
+	if got := testutil.CollectAndCount(managedChildren); got != 0 {
+		t.Errorf("after Delete: %d managed children series, want 0", got)
+	}
+}
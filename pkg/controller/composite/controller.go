@@ -24,10 +24,12 @@ import (
 	"sync"
 	"time"
 
+	"metacontroller/pkg/audit"
 	"metacontroller/pkg/controller/common/api"
 	commonv2 "metacontroller/pkg/controller/common/api/v2"
 	"metacontroller/pkg/hooks"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -63,6 +65,7 @@ import (
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicinformer "metacontroller/pkg/dynamic/informer"
 	k8s "metacontroller/pkg/third_party/kubernetes"
+	"metacontroller/pkg/tracing"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -73,6 +76,7 @@ type parentController struct {
 	parentResource *dynamicdiscovery.APIResource
 
 	mcClient       mcclientset.Interface
+	resources      *dynamicdiscovery.ResourceMap
 	dynClient      *dynamicclientset.Clientset
 	parentClient   *dynamicclientset.ResourceClient
 	parentInformer *dynamicinformer.ResourceInformer
@@ -83,12 +87,15 @@ type parentController struct {
 	doneCh   chan struct{}
 	stopOnce sync.Once
 	queue    workqueue.TypedRateLimitingInterface[string]
+	// queueProgress tracks the progress of the queue while the controller runs.
+	queueProgress *common.QueueProgress
+	metrics       *common.ControllerMetrics
 
 	updateStrategy updateStrategyMap
 	childInformers *common.InformerMap
 
 	numWorkers    int
-	ssaOptions    *common.ApplyOptions
+	applyOptions  *common.ApplyOptionsMap
 	eventRecorder record.EventRecorder
 
 	finalizer    *finalizer.Manager
@@ -96,6 +103,10 @@ type parentController struct {
 	syncHook     hooks.Hook
 	finalizeHook hooks.Hook
 
+	// dryRunRollbacks holds the rollback-to annotation of each parent whose
+	// rollback was reported in dry-run mode, by parent UID.
+	dryRunRollbacks sync.Map
+
 	logger logr.Logger
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -126,6 +137,12 @@ func newParentController(
 	if err != nil {
 		return nil, err
 	}
+	applyOptions, err := makeApplyOptionsMap(resources, cc, ssaOptions, eventRecorder)
+	if err != nil {
+		return nil, err
+	}
+	metrics := common.NewControllerMetrics(common.CompositeController, cc.Name)
+	applyOptions.SetMetrics(metrics)
 
 	// Create informer for the parent resource.
 	parentInformer, err := dynInformers.Resource(ctx, cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
@@ -146,7 +163,14 @@ func newParentController(
 		}
 	}()
 	for _, child := range cc.Spec.ChildResources {
-		childInformer, err := dynInformers.Resource(ctx, child.APIVersion, child.Resource)
+		var method v1alpha1.ChildUpdateMethod
+		if child.UpdateStrategy != nil {
+			method = child.UpdateStrategy.Method
+		}
+		if err := common.ValidateChildCacheMode(child.CacheMode, method); err != nil {
+			return nil, fmt.Errorf("invalid cache mode for child resource %q in %v: %w", child.Resource, child.APIVersion, err)
+		}
+		childInformer, err := common.NewResourceInformer(ctx, dynInformers, child.APIVersion, child.Resource, child.CacheMode)
 		if err != nil {
 			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
 		}
@@ -166,6 +190,14 @@ func newParentController(
 	if cc.Spec.Hooks == nil {
 		return nil, fmt.Errorf("no hooks defined")
 	}
+	logger = logger.WithName(cc.Name)
+	if cc.Spec.LogLevel != "" {
+		level, err := logging.ParseLevel(cc.Spec.LogLevel)
+		if err != nil {
+			return nil, err
+		}
+		logger = logging.WithLevel(logger, level)
+	}
 	syncCfg, err := hooks.ResolveEndpointConfig(ctx, k8sClient, syncWebhook(cc), cc.GetEndpointConfigs())
 	if err != nil {
 		return nil, fmt.Errorf("can't resolve endpoint config for sync hook: %w", err)
@@ -182,18 +214,15 @@ func newParentController(
 	if err != nil {
 		return nil, err
 	}
-	parentSelector := labels.Everything()
-	// for backward compatibility - if not set, handle all resources
-	if cc.Spec.ParentResource.LabelSelector != nil {
-		parentSelector, err = metav1.LabelSelectorAsSelector(cc.Spec.ParentResource.LabelSelector)
-		if err != nil {
-			return nil, err
-		}
+	parentSelector, err := makeParentSelector(cc)
+	if err != nil {
+		return nil, err
 	}
 
 	pc = &parentController{
 		cc:             cc,
 		mcClient:       mcClient,
+		resources:      resources,
 		dynClient:      dynClient,
 		childInformers: childInformers,
 		parentClient:   parentClient,
@@ -202,23 +231,16 @@ func newParentController(
 		parentResource: parentResource,
 		revisionLister: revisionLister,
 		updateStrategy: updateStrategy,
-		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
-			workqueue.DefaultTypedControllerRateLimiter[string](),
-			workqueue.TypedRateLimitingQueueConfig[string]{
-				Name: common.CompositeController.String() + "-" + cc.Name,
-			},
-		),
-		numWorkers:    numWorkers,
-		ssaOptions:    ssaOptions,
-		eventRecorder: eventRecorder,
-		finalizer: finalizer.NewManager(
-			"metacontroller.io/compositecontroller-"+cc.Name,
-			cc.Spec.Hooks.Finalize != nil,
-		),
-		syncHook:     syncHook,
-		finalizeHook: finalizeHook,
-		logger:       logger.WithName(cc.Name),
-		ctx:          ctx,
+		queue:          common.NewQueue(common.CompositeController.String() + "-" + cc.Name),
+		metrics:        metrics,
+		numWorkers:     numWorkers,
+		applyOptions:   applyOptions,
+		eventRecorder:  eventRecorder,
+		finalizer:      newFinalizerManager(cc),
+		syncHook:       syncHook,
+		finalizeHook:   finalizeHook,
+		logger:         logger,
+		ctx:            ctx,
 	}
 
 	pc.customize, err = customize.NewCustomizeManager(
@@ -241,6 +263,24 @@ func newParentController(
 	return pc, nil
 }
 
+// makeParentSelector returns the selector of the parents that the controller
+// handles, which selects all parents if the controller has none.
+func makeParentSelector(cc *v1alpha1.CompositeController) (labels.Selector, error) {
+	// for backward compatibility - if not set, handle all resources
+	if cc.Spec.ParentResource.LabelSelector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(cc.Spec.ParentResource.LabelSelector)
+}
+
+// newFinalizerManager returns the manager of the finalizer of the controller.
+func newFinalizerManager(cc *v1alpha1.CompositeController) *finalizer.Manager {
+	return finalizer.NewManager(
+		"metacontroller.io/compositecontroller-"+cc.Name,
+		cc.Spec.Hooks.Finalize != nil,
+	)
+}
+
 // syncWebhook extracts the Webhook from the sync hook spec.
 func syncWebhook(cc *v1alpha1.CompositeController) *v1alpha1.Webhook {
 	if cc.Spec.Hooks == nil || cc.Spec.Hooks.Sync == nil {
@@ -260,6 +300,7 @@ func finalizeWebhook(cc *v1alpha1.CompositeController) *v1alpha1.Webhook {
 func (pc *parentController) Start() {
 	pc.ctx, pc.cancel = context.WithCancel(pc.ctx)
 	pc.doneCh = make(chan struct{})
+	pc.queueProgress = common.TrackQueue(common.CompositeController.String()+"-"+pc.cc.Name, pc.queue)
 
 	pc.customize.Start(pc.ctx)
 
@@ -333,11 +374,27 @@ func (pc *parentController) Start() {
 	}()
 }
 
+// status returns the state of the controller, for introspection.
+func (pc *parentController) status() common.ControllerStatus {
+	status := common.ControllerStatus{
+		Kind:               common.CompositeController.String(),
+		Name:               pc.cc.Name,
+		Generation:         pc.cc.Generation,
+		CustomizeCacheSize: pc.customize.CacheSize(),
+		ETagCacheSize: hooks.CacheSize(pc.syncHook) +
+			hooks.CacheSize(pc.finalizeHook) +
+			pc.customize.ETagCacheSize(),
+	}
+	status.SetQueue(pc.queue)
+	return status
+}
+
 func (pc *parentController) Stop() {
 	pc.stopOnce.Do(func() {
 		pc.cancel()
 		pc.queue.ShutDown()
 		<-pc.doneCh
+		pc.queueProgress.Untrack()
 
 		// Remove event handlers and close informers for all child resources.
 		pc.childInformers.ForEach(func(_ schema.GroupVersionResource, informer *dynamicinformer.ResourceInformer) {
@@ -348,6 +405,7 @@ func (pc *parentController) Stop() {
 		pc.parentInformer.Informer().RemoveEventHandlers()
 		pc.parentInformer.Close()
 		pc.customize.Stop()
+		pc.metrics.Delete()
 	})
 }
 
@@ -362,8 +420,16 @@ func (pc *parentController) processNextWorkItem(ctx context.Context) bool {
 		return false
 	}
 	defer pc.queue.Done(key)
-
-	if err := pc.sync(ctx, key); err != nil {
+	defer pc.queueProgress.Progress()
+
+	start := time.Now()
+	// Hooks log with the logger and the redactor of the controller.
+	ctx = redact.NewContext(logging.NewContext(ctx, pc.logger), pc.applyOptions.Redactor())
+	ctx, span := tracing.StartSync(audit.WithSync(ctx), common.CompositeController.String(), pc.cc.Name, key)
+	err := pc.sync(ctx, key)
+	tracing.End(span, err)
+	pc.metrics.ObserveSync(start, err)
+	if err != nil {
 		var tooManyRequestError *hooks.TooManyRequestError
 		if errors.As(err, &tooManyRequestError) {
 			pc.queue.AddAfter(key, time.Duration(tooManyRequestError.AfterSecond)*time.Second)
@@ -595,6 +661,7 @@ func (pc *parentController) sync(ctx context.Context, key string) error {
 		if apierrors.IsNotFound(err) {
 			// Swallow the error since there's no point retrying if the parent is gone.
 			pc.logger.V(4).Info("Parent object has been deleted", "parent_kind", pc.parentResource.Kind, "object", klog.KRef(namespace, name))
+			pc.metrics.ForgetParent(key)
 			return nil
 		} else {
 			return err
@@ -624,46 +691,70 @@ func (pc *parentController) syncParentObject(ctx context.Context, parent *unstru
 		return err
 	}
 
+	parent, ok, err := pc.syncParentFinalizer(ctx, parent)
+	if err != nil || !ok {
+		return err
+	}
+
+	// Claim all matching child resources, including orphan/adopt as necessary.
+	observedChildren, err := pc.claimChildren(ctx, parent)
+	if err != nil {
+		return err
+	}
+	pc.metrics.ObserveChildren(cache.MetaObjectToName(parent).String(), observedChildren)
+
+	start := time.Now()
+	relatedObjects, err := pc.customize.GetRelatedObjects(ctx, parent)
+	if err = pc.metrics.ObservePhase(common.SyncPhaseHook, start, err); err != nil {
+		return err
+	}
+
+	_, err = pc.syncObservedParent(ctx, parent, observedChildren, relatedObjects)
+	return err
+}
+
+// syncParentFinalizer adds or removes our finalizer on the parent as
+// necessary. It returns the parent as it's left, and false if the controller
+// doesn't handle the parent, in which case the sync stops there.
+func (pc *parentController) syncParentFinalizer(ctx context.Context, parent *unstructured.Unstructured) (*unstructured.Unstructured, bool, error) {
 	// If the parent doesn't match our selector, and it doesn't have our
 	// finalizer, we don't care about it.
+	parentKey := cache.MetaObjectToName(parent).String()
 	if !controllerutil.ContainsFinalizer(parent, pc.finalizer.Name) && pc.doNotMatchLabels(parent.GetLabels()) {
-		return nil
+		pc.metrics.ForgetParent(parentKey)
+		return parent, false, nil
 	}
 
 	// Before taking any other action, add our finalizer (if desired).
 	// This ensures we have a chance to clean up after any action we later take.
-	updatedParent, err := pc.finalizer.SyncObject(ctx, pc.parentClient, parent)
+	updatedParent, err := pc.syncFinalizer(ctx, parent)
 	if err != nil {
 		// If we fail to do this, abort before doing anything else and requeue.
-		return fmt.Errorf("can't sync finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+		return parent, false, fmt.Errorf("can't sync finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 	}
 	parent = updatedParent
 
 	// Check the finalizer again in case we just removed it.
 	if !controllerutil.ContainsFinalizer(parent, pc.finalizer.Name) && pc.doNotMatchLabels(parent.GetLabels()) {
-		return nil
-	}
-
-	// Claim all matching child resources, including orphan/adopt as necessary.
-	observedChildren, err := pc.claimChildren(ctx, parent)
-	if err != nil {
-		return err
-	}
-
-	relatedObjects, err := pc.customize.GetRelatedObjects(ctx, parent)
-	if err != nil {
-		return err
+		pc.metrics.ForgetParent(parentKey)
+		return parent, false, nil
 	}
+	return parent, true, nil
+}
 
+// syncObservedParent syncs the parent against its observed children and
+// related objects: it calls the hooks, reconciles the children and updates
+// the status of the parent. It returns the desired children.
+func (pc *parentController) syncObservedParent(ctx context.Context, parent *unstructured.Unstructured, observedChildren, relatedObjects api.ObjectMap) ([]*unstructured.Unstructured, error) {
 	// Reconcile ControllerRevisions belonging to this parent.
 	// Call the sync hook for each revision, then compute the overall status and
 	// desired children, accounting for any rollout in progress.
 	syncResult, err := pc.syncRevisions(ctx, parent, observedChildren, relatedObjects)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if syncResult == nil {
-		return nil
+		return nil, nil
 	}
 	desiredChildren := commonv2.MakeUniformObjectMap(parent, syncResult.Children)
 
@@ -675,14 +766,59 @@ func (pc *parentController) syncParentObject(ctx context.Context, parent *unstru
 	// If all revisions agree that they've finished finalizing,
 	// remove our finalizer.
 	if syncResult.Finalized {
-		updatedParent, err := pc.parentClient.Namespace(parent.GetNamespace()).RemoveFinalizer(ctx, parent, pc.finalizer.Name)
+		updatedParent, err := pc.removeFinalizer(ctx, parent)
 		if err != nil {
-			return fmt.Errorf("can't remove finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+			return nil, fmt.Errorf("can't remove finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 		}
 		parent = updatedParent
 	}
 
 	// Enforce invariants between parent selector and child labels.
+	if err := pc.enforceSelector(parent, desiredChildren); err != nil {
+		return nil, err
+	}
+
+	// Reconcile child objects belonging to this parent.
+	// Remember manage error, but continue to update status regardless.
+	//
+	// We only manage children if the parent is "alive" (not pending deletion),
+	// or if it's pending deletion and we have a `finalize` hook.
+	var manageErr error
+	if parent.GetDeletionTimestamp() == nil || pc.finalizer.ShouldFinalize(parent) {
+		// Reconcile children.
+		start := time.Now()
+		err := common.ManageChildren(ctx, pc.dynClient, pc.updateStrategy, parent, observedChildren, desiredChildren, pc.applyOptions)
+		if err = pc.metrics.ObservePhase(common.SyncPhaseApply, start, err); err != nil {
+			manageErr = fmt.Errorf("can't reconcile children for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+	}
+
+	// Update parent status.
+	// We'll want to make sure this happens after manageChildren once we support observedGeneration.
+	start := time.Now()
+	statusCtx, span := tracing.Start(ctx, "update parent status", tracing.Object("parent", parent)...)
+	_, err = pc.updateParentStatus(statusCtx, parent, syncResult.Status)
+	tracing.End(span, err)
+	if err = pc.metrics.ObservePhase(common.SyncPhaseStatus, start, err); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Swallow the error since there's no point retrying if the parent is gone.
+			pc.logger.V(4).Info("Parent object has been deleted", "parent_kind", pc.parentResource.Kind, "object", klog.KRef(parent.GetNamespace(), parent.GetName()))
+			return syncResult.Children, nil
+		} else if apierrors.IsConflict(err) {
+			// it is possible that the object was modified after this sync was started, ignore conflict since we will reconcile again
+			pc.logger.V(4).Info("Parent ignoring update due to outdated resourceVersion", "parent_kind", pc.parentResource.Kind, "object", klog.KRef(parent.GetNamespace(), parent.GetName()))
+			return syncResult.Children, nil
+		}
+		return nil, fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+
+	return syncResult.Children, manageErr
+}
+
+// enforceSelector makes sure that all desired children match the selector of
+// the parent, adding the controller-uid label if selector generation is
+// enabled.
+func (pc *parentController) enforceSelector(parent *unstructured.Unstructured, desiredChildren commonv2.UniformObjectMap) error {
 	selector, err := pc.makeSelector(parent, nil)
 	if err != nil {
 		return err
@@ -713,36 +849,7 @@ func (pc *parentController) syncParentObject(ctx context.Context, parent *unstru
 			}
 		}
 	}
-
-	// Reconcile child objects belonging to this parent.
-	// Remember manage error, but continue to update status regardless.
-	//
-	// We only manage children if the parent is "alive" (not pending deletion),
-	// or if it's pending deletion and we have a `finalize` hook.
-	var manageErr error
-	if parent.GetDeletionTimestamp() == nil || pc.finalizer.ShouldFinalize(parent) {
-		// Reconcile children.
-		if err := common.ManageChildren(ctx, pc.dynClient, pc.updateStrategy, parent, observedChildren, desiredChildren, pc.ssaOptions); err != nil {
-			manageErr = fmt.Errorf("can't reconcile children for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
-		}
-	}
-
-	// Update parent status.
-	// We'll want to make sure this happens after manageChildren once we support observedGeneration.
-	if _, err := pc.updateParentStatus(ctx, parent, syncResult.Status); err != nil {
-		if apierrors.IsNotFound(err) {
-			// Swallow the error since there's no point retrying if the parent is gone.
-			pc.logger.V(4).Info("Parent object has been deleted", "parent_kind", pc.parentResource.Kind, "object", klog.KRef(parent.GetNamespace(), parent.GetName()))
-			return nil
-		} else if apierrors.IsConflict(err) {
-			// it is possible that the object was modified after this sync was started, ignore conflict since we will reconcile again
-			pc.logger.V(4).Info("Parent ignoring update due to outdated resourceVersion", "parent_kind", pc.parentResource.Kind, "object", klog.KRef(parent.GetNamespace(), parent.GetName()))
-			return nil
-		}
-		return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
-	}
-
-	return manageErr
+	return nil
 }
 
 func (pc *parentController) isUsingGeneratedLabelSelector() bool {
@@ -866,6 +973,10 @@ func (pc *parentController) claimChildren(ctx context.Context, parent *unstructu
 		if err != nil {
 			return nil, fmt.Errorf("can't claim %v children: %w", childClient.Kind, err)
 		}
+		children, err = common.FetchObjects(ctx, childClient, child.CacheMode, children)
+		if err != nil {
+			return nil, err
+		}
 
 		// Add children to map by name.
 		// Note that we limit each parent to only working within its own namespace.
@@ -884,18 +995,36 @@ func (pc *parentController) updateParentStatus(ctx context.Context, parent *unst
 	}
 	status["observedGeneration"] = parent.GetGeneration()
 
+	if dryRun := pc.applyOptions.DryRun(); dryRun.Enabled() {
+		if common.DeepEqual(parent.UnstructuredContent()["status"], status) {
+			return parent, nil
+		}
+		updated := parent.DeepCopy()
+		updated.UnstructuredContent()["status"] = status
+		dryRun.Report(parent, common.DryRunUpdateStatus, parent, updated)
+		return updated, nil
+	}
+
 	// Overwrite .status field of parent object without touching other parts.
 	// We can't use Patch() because we need to ensure that the UID matches.
-	return pc.parentClient.Namespace(parent.GetNamespace()).AtomicStatusUpdate(ctx, parent, func(obj *unstructured.Unstructured) bool {
+	var original, changed *unstructured.Unstructured
+	updated, err := pc.parentClient.Namespace(parent.GetNamespace()).AtomicStatusUpdate(ctx, parent, func(obj *unstructured.Unstructured) bool {
 		oldStatus := obj.UnstructuredContent()["status"]
 		if common.DeepEqual(oldStatus, status) {
 			// Nothing to do.
+			changed = nil
 			return false
 		}
 
+		original = obj.DeepCopy()
 		obj.UnstructuredContent()["status"] = status
+		changed = obj
 		return true
 	})
+	if err == nil && changed != nil {
+		pc.applyOptions.Audit().Record(ctx, parent, common.AuditUpdateStatus, original, changed)
+	}
+	return updated, err
 }
 
 func (pc *parentController) doNotMatchLabels(labelsMap map[string]string) bool {
@@ -25,11 +25,16 @@ import (
 	commonv1 "metacontroller/pkg/controller/common/api/v1"
 	v1 "metacontroller/pkg/controller/composite/api/v1"
 	"metacontroller/pkg/logging"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,6 +50,14 @@ import (
 const (
 	labelKeyAPIGroup = "metacontroller.k8s.io/apiGroup"
 	labelKeyResource = "metacontroller.k8s.io/resource"
+
+	// annotationKeyRevision records the sequence number of a ControllerRevision
+	// among all revisions of its parent, similar to the Deployment revision.
+	annotationKeyRevision = "metacontroller.k8s.io/revision"
+
+	// revisionsStatusField is the field of the parent status that reports the
+	// progress of a rollout.
+	revisionsStatusField = "revisions"
 )
 
 func (pc *parentController) claimRevisions(ctx context.Context, parent *unstructured.Unstructured) ([]*v1alpha1.ControllerRevision, error) {
@@ -84,8 +97,9 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 	// children anyway.
 	if !pc.updateStrategy.anyRolling() ||
 		(parent.GetDeletionTimestamp() != nil && !pc.finalizer.ShouldFinalize(parent)) {
+		start := time.Now()
 		syncResult, err := pc.callHook(ctx, parent, observedChildren, relatedObjects)
-		if err != nil {
+		if err = pc.metrics.ObservePhase(common.SyncPhaseHook, start, err); err != nil {
 			return nil, fmt.Errorf("sync hook failed for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 		}
 		if syncResult == nil {
@@ -114,11 +128,31 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 		return nil, err
 	}
 
+	// Roll back to a previous revision, if requested. Updating the parent
+	// triggers another sync, which rolls out the restored revision.
+	if _, ok := parent.GetAnnotations()[annotationKeyRollbackTo]; ok && parent.GetDeletionTimestamp() == nil {
+		if !pc.applyOptions.DryRun().Enabled() {
+			_, err := pc.rollback(ctx, parent, observedRevisions, latestPatch, fieldPaths)
+			return nil, err
+		}
+		// In dry-run mode, there's no update to trigger another sync, so
+		// go on with the parent as the rollback would leave it.
+		if parent, err = pc.dryRunRollback(ctx, parent, observedRevisions, latestPatch, fieldPaths); err != nil {
+			return nil, err
+		}
+		if latestPatch, err = makePatch(parent.UnstructuredContent(), fieldPaths); err != nil {
+			return nil, err
+		}
+	}
+
 	// The first item in the list is always the latest parent.
 	// The rest are in no particular order.
 	latest := &parentRevision{parent: parent}
 	parentRevisions := make([]*parentRevision, 0, len(observedRevisions)+1)
 	parentRevisions = append(parentRevisions, latest)
+	// Old revisions that no longer own any children are only kept as history,
+	// so there's no need to call the sync hook for them.
+	var historyRevisions []*v1alpha1.ControllerRevision
 
 	// Materialize the parent object that each revision represents
 	// by applying its parentPatch to the current parent object.
@@ -133,6 +167,10 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 			latest.revision = revision.DeepCopy()
 			continue
 		}
+		if len(revision.Children) == 0 {
+			historyRevisions = append(historyRevisions, revision.DeepCopy())
+			continue
+		}
 		// Also deep copy parent, so we can apply the patch to it.
 		pr := &parentRevision{parent: latest.parent.DeepCopy(), revision: revision.DeepCopy()}
 		if err := applyPatch(pr.parent.UnstructuredContent(), patch, fieldPaths); err != nil {
@@ -149,6 +187,13 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 		}
 		latest.revision = revision
 	}
+	// Make sure the latest revision has the highest revision number, even if
+	// the parent went back to the state of an older revision.
+	if maxRevision := maxRevisionNumber(observedRevisions, latest.revision.Name); revisionNumber(latest.revision) <= maxRevision {
+		setRevisionNumber(latest.revision, maxRevision+1)
+		// The parent went back to this revision without a rollback request.
+		delete(latest.revision.Annotations, annotationKeyRollbackFrom)
+	}
 
 	// Call the sync hook to get each parent revision's idea of the desired children.
 	var wg sync.WaitGroup
@@ -156,13 +201,15 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 		wg.Add(1)
 		go func(pr *parentRevision) {
 			defer wg.Done()
+			start := time.Now()
 			related, err := pc.customize.GetRelatedObjects(ctx, pr.parent)
-			if err != nil {
+			if err = pc.metrics.ObservePhase(common.SyncPhaseHook, start, err); err != nil {
 				pr.syncError = err
 				return
 			}
+			start = time.Now()
 			syncResult, err := pc.callHook(ctx, pr.parent, observedChildren, related)
-			if err != nil {
+			if err = pc.metrics.ObservePhase(common.SyncPhaseHook, start, err); err != nil {
 				pr.syncError = err
 				return
 			}
@@ -180,30 +227,55 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 	}
 
 	// Manipulate revisions to proceed with any ongoing rollout, if possible.
-	if err := pc.syncRollingUpdate(parentRevisions, observedChildren); err != nil {
+	rollingChildren, err := pc.observeRollingChildren(ctx, parent, parentRevisions[0], observedChildren)
+	if err != nil {
+		return nil, err
+	}
+	rollbackTo, err := pc.syncRollingUpdate(parentRevisions, rollingChildren, time.Now())
+	if err != nil {
 		return nil, err
 	}
 
-	// Remove any ControllerRevisions that no longer have any children.
-	// We don't remember previous revisions that we finished migrating away from.
-	// The user is responsible for recovering an old config from source control
-	// if a rollback is necessary.
-	parentRevisions = pruneParentRevisions(parentRevisions)
+	// Stop tracking any ControllerRevisions that no longer have any children.
+	// We only remember up to revisionHistoryLimit of the most recent revisions
+	// that we finished migrating away from, the rest get deleted.
+	parentRevisions, prunedRevisions := pruneParentRevisions(parentRevisions)
+	for _, pr := range prunedRevisions {
+		historyRevisions = append(historyRevisions, pr.revision)
+	}
+	historyRevisions = limitRevisionHistory(historyRevisions, pc.revisionHistoryLimit())
 
 	// Reconcile any changes to ControllerRevision objects.
 	// For now, we require these changes to all commit before we start managing
 	// children.
 	// We don't want to start acting before we persist our desired end state.
-	desiredRevisions := make([]*v1alpha1.ControllerRevision, 0, len(parentRevisions))
+	desiredRevisions := make([]*v1alpha1.ControllerRevision, 0, len(parentRevisions)+len(historyRevisions))
 	for _, pr := range parentRevisions {
 		if pr.revision != nil {
 			desiredRevisions = append(desiredRevisions, pr.revision)
 		}
 	}
+	desiredRevisions = append(desiredRevisions, historyRevisions...)
 	if err := pc.manageRevisions(ctx, parent, observedRevisions, desiredRevisions); err != nil {
 		return nil, fmt.Errorf("%v %v/%v: can't reconcile ControllerRevisions: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 	}
 
+	// Request a rollback if the rollout failed. The next sync performs it.
+	if rollbackTo != "" {
+		_, err := pc.updateParent(ctx, parent, func(obj *unstructured.Unstructured) bool {
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[annotationKeyRollbackTo] = rollbackTo
+			obj.SetAnnotations(annotations)
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't request rollback of %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+	}
+
 	// We now know which revision ought to be responsible for which children.
 	// Start with the latest revision's desired children.
 	// Then overwrite any children that are still claimed by other revisions.
@@ -225,8 +297,16 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 
 	// Build a single, aggregated syncResult.
 	// We only take parent status from the latest revision.
+	status := latest.syncResult.Status
+	if status == nil {
+		status = make(map[string]interface{})
+	}
+	setRevisionStatus(parent, status, parentRevisions)
+	if err := pc.setRollbackCondition(parent, status, parentRevisions); err != nil {
+		return nil, err
+	}
 	syncResult := &v1.CompositeHookResponse{
-		Status:   latest.syncResult.Status,
+		Status:   status,
 		Children: desiredChildren.List(),
 	}
 
@@ -253,6 +333,7 @@ func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructu
 
 func (pc *parentController) manageRevisions(ctx context.Context, parent *unstructured.Unstructured, observedRevisions, desiredRevisions []*v1alpha1.ControllerRevision) error {
 	client := pc.mcClient.MetacontrollerV1alpha1().ControllerRevisions(parent.GetNamespace())
+	dryRun := pc.applyOptions.DryRun()
 
 	// Build maps for convenient lookup by object name.
 	observedMap := make(map[string]*v1alpha1.ControllerRevision, len(observedRevisions))
@@ -269,11 +350,16 @@ func (pc *parentController) manageRevisions(ctx context.Context, parent *unstruc
 			uid := revision.UID
 			opts := metav1.DeleteOptions{
 				Preconditions: &metav1.Preconditions{UID: &uid},
+				DryRun:        dryRun.Options(),
 			}
 			logging.Logger.Info("Deleting ControllerRevision", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName())
-			if err := client.Delete(ctx, revision.Name, opts); err != nil {
+			spanCtx, span := tracing.Start(ctx, "delete ControllerRevision", revisionAttributes(parent, revision)...)
+			err := client.Delete(spanCtx, revision.Name, opts)
+			tracing.End(span, err)
+			if err != nil {
 				return fmt.Errorf("can't delete ControllerRevision %v for %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 			}
+			reportRevision(dryRun, parent, common.DryRunDelete, revision, nil)
 		}
 	}
 
@@ -289,23 +375,36 @@ func (pc *parentController) manageRevisions(ctx context.Context, parent *unstruc
 				revision.SetResourceVersion(oldObj.GetResourceVersion())
 				logging.Logger.V(6).Info("ControllerRevision's resource version updated", "old", oldObj.GetObjectMeta().GetResourceVersion(), "new", revision.GetObjectMeta().GetResourceVersion())
 			}
-			updated, err := client.Update(ctx, revision, metav1.UpdateOptions{})
+			spanCtx, span := tracing.Start(ctx, "update ControllerRevision", revisionAttributes(parent, revision)...)
+			updated, err := client.Update(spanCtx, revision, metav1.UpdateOptions{DryRun: dryRun.Options()})
+			tracing.End(span, err)
 			if err != nil {
 				return fmt.Errorf("can't update ControllerRevision %v for %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 			}
+			reportRevision(dryRun, parent, common.DryRunUpdate, oldObj, revision)
 			logging.Logger.Info("ControllerRevision updated", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName(), "resource_version", updated.GetResourceVersion())
 		} else {
 			// Create
 			logging.Logger.Info("Creating ControllerRevision", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName())
-			if _, err := client.Create(ctx, revision, metav1.CreateOptions{}); err != nil {
+			spanCtx, span := tracing.Start(ctx, "create ControllerRevision", revisionAttributes(parent, revision)...)
+			_, err := client.Create(spanCtx, revision, metav1.CreateOptions{DryRun: dryRun.Options()})
+			tracing.End(span, err)
+			if err != nil {
 				return fmt.Errorf("can't create ControllerRevision %v for %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 			}
+			reportRevision(dryRun, parent, common.DryRunCreate, nil, revision)
 		}
 	}
 
 	return nil
 }
 
+// revisionAttributes returns the trace attributes of an operation on a
+// ControllerRevision of the parent.
+func revisionAttributes(parent *unstructured.Unstructured, revision *v1alpha1.ControllerRevision) []attribute.KeyValue {
+	return append(tracing.Object("parent", parent), attribute.String("metacontroller.revision.name", revision.Name))
+}
+
 func (pc *parentController) newControllerRevision(parent *unstructured.Unstructured, patch map[string]interface{}) (*v1alpha1.ControllerRevision, error) {
 	patchData, err := json.Marshal(patch)
 	if err != nil {
@@ -484,17 +583,107 @@ func (pr *parentRevision) removeChild(apiGroup, kind, name string) {
 	children.Names = append(children.Names[:pos], children.Names[pos+1:]...)
 }
 
-func pruneParentRevisions(parentRevisions []*parentRevision) []*parentRevision {
-	result := make([]*parentRevision, 0, len(parentRevisions))
+// pruneParentRevisions splits the given revisions into the ones that still
+// have children, and the ones that don't.
+func pruneParentRevisions(parentRevisions []*parentRevision) (active, pruned []*parentRevision) {
+	active = make([]*parentRevision, 0, len(parentRevisions))
 	// Always include the first item (the latest revision).
-	result = append(result, parentRevisions[0])
+	active = append(active, parentRevisions[0])
 	// Include the rest only if they have remaining children.
 	for _, pr := range parentRevisions[1:] {
 		if pr.countChildren() > 0 {
-			result = append(result, pr)
+			active = append(active, pr)
+		} else {
+			pruned = append(pruned, pr)
+		}
+	}
+	return active, pruned
+}
+
+func (pc *parentController) revisionHistoryLimit() int {
+	if rh := pc.cc.Spec.ParentResource.RevisionHistory; rh != nil && rh.RevisionHistoryLimit != nil {
+		return int(*rh.RevisionHistoryLimit)
+	}
+	return 0
+}
+
+// limitRevisionHistory returns the given number of most recent revisions,
+// ordered from newest to oldest.
+func limitRevisionHistory(revisions []*v1alpha1.ControllerRevision, limit int) []*v1alpha1.ControllerRevision {
+	sort.SliceStable(revisions, func(i, j int) bool {
+		ni, nj := revisionNumber(revisions[i]), revisionNumber(revisions[j])
+		if ni != nj {
+			return ni > nj
 		}
+		// Revisions created before numbering was introduced are all 0.
+		return revisions[i].CreationTimestamp.After(revisions[j].CreationTimestamp.Time)
+	})
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+	return revisions
+}
+
+// revisionNumber returns the number recorded in the revision annotation,
+// or 0 if there is none.
+func revisionNumber(revision *v1alpha1.ControllerRevision) int64 {
+	number, err := strconv.ParseInt(revision.GetAnnotations()[annotationKeyRevision], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+func setRevisionNumber(revision *v1alpha1.ControllerRevision, number int64) {
+	annotations := revision.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[annotationKeyRevision] = strconv.FormatInt(number, 10)
+	revision.SetAnnotations(annotations)
+}
+
+// maxRevisionNumber returns the highest revision number of the given revisions,
+// ignoring the revision with the given name.
+func maxRevisionNumber(revisions []*v1alpha1.ControllerRevision, ignoreName string) int64 {
+	var maxRevision int64
+	for _, revision := range revisions {
+		if revision.Name == ignoreName {
+			continue
+		}
+		maxRevision = max(maxRevision, revisionNumber(revision))
+	}
+	return maxRevision
+}
+
+// setRevisionStatus exposes the progress of a rollout in the revisions field
+// of the parent status, similar to StatefulSet. The update revision is the
+// latest revision, while the current revision is the oldest revision that
+// still owns children. If the sync hook returns a revisions field of its own,
+// it's left alone. A field that the parent already has is taken to be the
+// one reported by the last sync, which hooks that copy the observed status
+// return.
+func setRevisionStatus(parent *unstructured.Unstructured, status map[string]interface{}, parentRevisions []*parentRevision) {
+	if value, ok := status[revisionsStatusField]; ok {
+		observed, _, _ := unstructured.NestedFieldNoCopy(parent.UnstructuredContent(), "status", revisionsStatusField)
+		if !common.DeepEqual(value, observed) {
+			return
+		}
+	}
+
+	latest := parentRevisions[0]
+	current := latest
+	for _, pr := range parentRevisions[1:] {
+		if current == latest || revisionNumber(pr.revision) < revisionNumber(current.revision) {
+			current = pr
+		}
+	}
+	status[revisionsStatusField] = map[string]interface{}{
+		"updateRevision":  latest.revision.Name,
+		"updatedChildren": int64(latest.countChildren()),
+		"currentRevision": current.revision.Name,
+		"currentChildren": int64(current.countChildren()),
 	}
-	return result
 }
 
 type childClaimMap map[string]map[string]*parentRevision
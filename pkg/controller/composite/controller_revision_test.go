@@ -0,0 +1,126 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newNumberedRevision(name string, number int64, childNames ...string) *v1alpha1.ControllerRevision {
+	revision := &v1alpha1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if number > 0 {
+		setRevisionNumber(revision, number)
+	}
+	if len(childNames) > 0 {
+		revision.Children = []v1alpha1.ControllerRevisionChildren{{Kind: "Pod", Names: childNames}}
+	}
+	return revision
+}
+
+func revisionNames(revisions []*v1alpha1.ControllerRevision) []string {
+	names := make([]string, 0, len(revisions))
+	for _, revision := range revisions {
+		names = append(names, revision.Name)
+	}
+	return names
+}
+
+func TestLimitRevisionHistory(t *testing.T) {
+	history := func() []*v1alpha1.ControllerRevision {
+		return []*v1alpha1.ControllerRevision{
+			newNumberedRevision("two", 2),
+			newNumberedRevision("legacy", 0),
+			newNumberedRevision("five", 5),
+			newNumberedRevision("three", 3),
+		}
+	}
+
+	assert.Empty(t, limitRevisionHistory(history(), 0))
+	assert.Equal(t, []string{"five", "three"}, revisionNames(limitRevisionHistory(history(), 2)))
+	assert.Equal(t, []string{"five", "three", "two", "legacy"}, revisionNames(limitRevisionHistory(history(), 10)))
+}
+
+func TestMaxRevisionNumber_ignoresGivenRevision(t *testing.T) {
+	revisions := []*v1alpha1.ControllerRevision{
+		newNumberedRevision("latest", 7),
+		newNumberedRevision("old", 4),
+	}
+
+	assert.Equal(t, int64(4), maxRevisionNumber(revisions, "latest"))
+	assert.Equal(t, int64(7), maxRevisionNumber(revisions, ""))
+}
+
+func TestPruneParentRevisions(t *testing.T) {
+	latest := &parentRevision{revision: newNumberedRevision("latest", 3)}
+	withChildren := &parentRevision{revision: newNumberedRevision("with-children", 2, "pod-1")}
+	withoutChildren := &parentRevision{revision: newNumberedRevision("without-children", 1)}
+
+	active, pruned := pruneParentRevisions([]*parentRevision{latest, withChildren, withoutChildren})
+
+	assert.Equal(t, []*parentRevision{latest, withChildren}, active)
+	assert.Equal(t, []*parentRevision{withoutChildren}, pruned)
+}
+
+func TestSetRevisionStatus(t *testing.T) {
+	latest := &parentRevision{revision: newNumberedRevision("latest", 3, "pod-1")}
+	previous := &parentRevision{revision: newNumberedRevision("previous", 2, "pod-2")}
+	oldest := &parentRevision{revision: newNumberedRevision("oldest", 1, "pod-3", "pod-4")}
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{}}
+
+	status := make(map[string]interface{})
+	setRevisionStatus(parent, status, []*parentRevision{latest, previous, oldest})
+
+	assert.Equal(t, map[string]interface{}{
+		"revisions": map[string]interface{}{
+			"updateRevision":  "latest",
+			"updatedChildren": int64(1),
+			"currentRevision": "oldest",
+			"currentChildren": int64(2),
+		},
+	}, status)
+
+	// The fields the hook returned are kept.
+	status = map[string]interface{}{"currentRevision": "hook"}
+	setRevisionStatus(parent, status, []*parentRevision{latest})
+
+	assert.Equal(t, "hook", status["currentRevision"])
+	revisions := status["revisions"].(map[string]interface{})
+	assert.Equal(t, "latest", revisions["currentRevision"])
+	assert.Equal(t, int64(1), revisions["currentChildren"])
+
+	// A revisions field of the hook's own is left alone.
+	status = map[string]interface{}{"revisions": []interface{}{"hook"}}
+	setRevisionStatus(parent, status, []*parentRevision{latest})
+
+	assert.Equal(t, []interface{}{"hook"}, status["revisions"])
+
+	// The field reported by the last sync, which the hook copied from the
+	// observed status, is updated.
+	reported := map[string]interface{}{"updateRevision": "previous"}
+	parent.Object["status"] = map[string]interface{}{"revisions": reported}
+	status = map[string]interface{}{"revisions": runtime.DeepCopyJSONValue(reported)}
+	setRevisionStatus(parent, status, []*parentRevision{latest})
+
+	revisions = status["revisions"].(map[string]interface{})
+	assert.Equal(t, "latest", revisions["updateRevision"])
+}
@@ -360,3 +360,26 @@ func Test_parentController_sync_requeue_item_when_hook_throw_TooManyRequestError
 	pc.processNextWorkItem(context.TODO())
 	assert.Equal(t, 1, pc.queue.Len())
 }
+
+func TestMakeApplyOptionsMap_manageStatusCacheMode(t *testing.T) {
+	resourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+	for _, cacheMode := range []v1alpha1.CacheMode{"", v1alpha1.CacheModeFull, v1alpha1.CacheModeMetadata, v1alpha1.CacheModeMetadataOnly} {
+		t.Run(string(cacheMode), func(t *testing.T) {
+			cc := &v1alpha1.CompositeController{
+				Spec: v1alpha1.CompositeControllerSpec{
+					ChildResources: []v1alpha1.CompositeControllerChildResourceRule{{
+						ResourceRule: v1alpha1.ResourceRule{APIVersion: TestAPIVersion, Resource: TestResource},
+						ManageStatus: true,
+						CacheMode:    cacheMode,
+					}},
+				},
+			}
+			_, err := makeApplyOptionsMap(resourceMap, cc, &common.ApplyOptions{}, nil)
+			if cacheMode == "" || cacheMode == v1alpha1.CacheModeFull {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// syncFinalizer adds or removes our finalizer on the parent as necessary. In
+// dry-run mode, it only reports the change and returns the parent as it
+// would be.
+func (pc *parentController) syncFinalizer(ctx context.Context, parent *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	dryRun := pc.applyOptions.DryRun()
+	if !dryRun.Enabled() {
+		updated, changed, err := pc.finalizer.SyncObject(ctx, pc.parentClient, parent)
+		if changed {
+			pc.applyOptions.Audit().RecordFinalizers(ctx, parent, updated)
+		}
+		return updated, err
+	}
+	updated, changed := pc.finalizer.DryRunSyncObject(parent)
+	if changed {
+		dryRun.Report(parent, common.DryRunUpdate, parent, updated)
+	}
+	return updated, nil
+}
+
+// removeFinalizer removes our finalizer from the parent. In dry-run mode, it
+// only reports the change and returns the parent as it would be.
+func (pc *parentController) removeFinalizer(ctx context.Context, parent *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	dryRun := pc.applyOptions.DryRun()
+	if !dryRun.Enabled() {
+		changed := false
+		updated, err := pc.parentClient.Namespace(parent.GetNamespace()).AtomicUpdate(ctx, parent, func(obj *unstructured.Unstructured) bool {
+			changed = controllerutil.RemoveFinalizer(obj, pc.finalizer.Name)
+			return changed
+		})
+		if err == nil && changed {
+			pc.applyOptions.Audit().RecordFinalizers(ctx, parent, updated)
+		}
+		return updated, err
+	}
+	if !controllerutil.ContainsFinalizer(parent, pc.finalizer.Name) {
+		return parent, nil
+	}
+	updated := parent.DeepCopy()
+	controllerutil.RemoveFinalizer(updated, pc.finalizer.Name)
+	dryRun.Report(parent, common.DryRunUpdate, parent, updated)
+	return updated, nil
+}
+
+// updateParent atomically updates the parent, like AtomicUpdate, and records
+// the change in the audit log. In dry-run mode, it only reports the change.
+func (pc *parentController) updateParent(ctx context.Context, parent *unstructured.Unstructured, update func(obj *unstructured.Unstructured) bool) (*unstructured.Unstructured, error) {
+	dryRun := pc.applyOptions.DryRun()
+	if !dryRun.Enabled() {
+		var original, changed *unstructured.Unstructured
+		updated, err := pc.parentClient.Namespace(parent.GetNamespace()).AtomicUpdate(ctx, parent, func(obj *unstructured.Unstructured) bool {
+			original, changed = obj.DeepCopy(), nil
+			if !update(obj) {
+				return false
+			}
+			changed = obj
+			return true
+		})
+		if err == nil && changed != nil {
+			pc.applyOptions.Audit().Record(ctx, parent, common.AuditUpdate, original, changed)
+		}
+		return updated, err
+	}
+	updated := parent.DeepCopy()
+	if update(updated) {
+		dryRun.Report(parent, common.DryRunUpdate, parent, updated)
+	}
+	return updated, nil
+}
+
+// reportRevision reports a write of a ControllerRevision in dry-run mode.
+// The original revision is nil for creates, and updated is nil for deletes.
+func reportRevision(dryRun *common.DryRun, parent *unstructured.Unstructured, action common.DryRunAction, original, updated *v1alpha1.ControllerRevision) {
+	if !dryRun.Enabled() {
+		return
+	}
+	dryRun.Report(parent, action, revisionToUnstructured(original), revisionToUnstructured(updated))
+}
+
+func revisionToUnstructured(revision *v1alpha1.ControllerRevision) *unstructured.Unstructured {
+	if revision == nil {
+		return nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(revision)
+	if err != nil {
+		// The conversion of a typed object can't fail in practice, so we at
+		// least report the name.
+		content = map[string]interface{}{}
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("ControllerRevision"))
+	obj.SetNamespace(revision.Namespace)
+	obj.SetName(revision.Name)
+	return obj
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/finalizer"
+	. "metacontroller/pkg/internal/testutils/common"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func newDryRunController(t *testing.T, recorder record.EventRecorder) *parentController {
+	_, _, _, parentClient, _ := newDefaultControllerClientsAndInformers(func(client *fake.FakeDynamicClient) {
+		client.PrependReactor("update", "*", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+			t.Errorf("unexpected update of the parent in dry-run mode")
+			return true, nil, nil
+		})
+	}, false)
+	applyOptions, err := common.NewApplyOptionsMap(&common.ApplyOptions{}, nil)
+	assert.NoError(t, err)
+	applyOptions.SetDryRun(common.NewDryRun("CompositeController", "test", recorder, nil))
+	return &parentController{
+		parentClient: parentClient,
+		applyOptions: applyOptions,
+		finalizer:    finalizer.NewManager("metacontroller.io/compositecontroller-test", true),
+	}
+}
+
+func TestUpdateParentStatus_dryRun(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	pc := newDryRunController(t, recorder)
+	parent := NewDefaultUnstructured()
+
+	updated, err := pc.updateParentStatus(context.TODO(), parent, map[string]interface{}{"ready": true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"ready": true, "observedGeneration": int64(0)}, updated.Object["status"])
+	assert.Nil(t, parent.Object["status"])
+	event := <-recorder.Events
+	assert.True(t, strings.HasPrefix(event, "Normal DryRun Dry run, not persisted: UpdateStatus TestKind testns/testname: "), event)
+}
+
+func TestSyncFinalizer_dryRun(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	pc := newDryRunController(t, recorder)
+	parent := NewDefaultUnstructured()
+
+	updated, err := pc.syncFinalizer(context.TODO(), parent)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metacontroller.io/compositecontroller-test"}, updated.GetFinalizers())
+	assert.Empty(t, parent.GetFinalizers())
+	assert.Contains(t, <-recorder.Events, `"finalizers":["metacontroller.io/compositecontroller-test"]`)
+}
@@ -18,6 +18,7 @@ package composite
 
 import (
 	"context"
+	"sort"
 
 	"metacontroller/pkg/logging"
 
@@ -41,6 +42,7 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	mcclientset "metacontroller/pkg/client/generated/clientset/internalclientset"
@@ -48,6 +50,7 @@ import (
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -65,6 +68,7 @@ type Metacontroller struct {
 	revisionInformer cache.SharedIndexInformer
 
 	parentControllers common.SyncMap[string, *parentController]
+	retrier           *common.DiscoveryRetrier
 
 	numWorkers int
 	ssaOptions *common.ApplyOptions
@@ -90,11 +94,45 @@ func NewMetacontroller(ctx context.Context, controllerContext common.ControllerC
 		logger:     logging.Logger.WithName("composite"),
 		ctx:        ctx,
 	}
+	mc.retrier = common.NewDiscoveryRetrier(ctx, controllerContext.Resources, func(name string) client.Object {
+		return &v1alpha1.CompositeController{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	})
 
 	return mc
 }
 
+// RetrySource returns the source of the events that retry CompositeControllers
+// that failed to sync, as soon as API discovery changes.
+func (mc *Metacontroller) RetrySource() source.Source {
+	return mc.retrier.Source()
+}
+
+// FailedControllers returns the names of the CompositeControllers that failed to sync
+// last time, sorted.
+func (mc *Metacontroller) FailedControllers() []string {
+	return mc.retrier.Failed()
+}
+
+// ControllerStatuses returns the state of the running CompositeControllers, sorted by
+// name.
+func (mc *Metacontroller) ControllerStatuses() []common.ControllerStatus {
+	var statuses []common.ControllerStatus
+	mc.parentControllers.ForEach(func(_ string, pc *parentController) {
+		statuses = append(statuses, pc.status())
+	})
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
 func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	result, err := mc.reconcile(ctx, request)
+	mc.retrier.Observe(request.Name, err)
+	return result, err
+}
+
+func (mc *Metacontroller) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	compositeControllerName := request.Name
 	mc.logger.Info("Sync CompositeController", "name", compositeControllerName)
 
@@ -0,0 +1,170 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"metacontroller/pkg/events"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+)
+
+const (
+	// annotationKeyPaused can be set to "true" on a parent to pause any rolling
+	// update of its children.
+	annotationKeyPaused = "metacontroller.k8s.io/paused"
+	// annotationKeyLastProgressTime records on the latest ControllerRevision
+	// when the last child was moved to it.
+	annotationKeyLastProgressTime = "metacontroller.k8s.io/last-progress-time"
+
+	progressingConditionType = "Progressing"
+)
+
+func isPaused(parent *unstructured.Unstructured) bool {
+	paused, _ := strconv.ParseBool(parent.GetAnnotations()[annotationKeyPaused])
+	return paused
+}
+
+// syncProgress checks the progress deadlines of all child types with children
+// that are still waiting to be moved to the latest revision, and reports the
+// result in the Progressing condition. It returns the name of the revision to
+// roll back to, if the deadline of a child type with autoRollback is exceeded.
+func (pc *parentController) syncProgress(parentRevisions []*parentRevision, paused, moved bool, pendingKinds map[string]bool, now time.Time) (string, error) {
+	if !pc.updateStrategy.anyProgressDeadline() {
+		return "", nil
+	}
+	latest := parentRevisions[0]
+	previous, _ := dynamicobject.GetStatusCondition(latest.parent.UnstructuredContent(), progressingConditionType)
+	wasPaused := previous != nil && previous.Reason == "RolloutPaused"
+
+	// Pausing the rollout also pauses the progress deadline: the time is reset
+	// when the rollout is paused and again when it's resumed, so the deadline
+	// counts from the resume. The time is only written when it changes, so that
+	// the revision isn't updated on every sync while paused.
+	lastProgress, ok := lastProgressTime(latest.revision)
+	if !ok || moved || paused != wasPaused {
+		setLastProgressTime(latest.revision, now)
+		lastProgress = now
+	}
+
+	var exceeded []string
+	autoRollback := false
+	for key := range pendingKinds {
+		strategy := pc.updateStrategy[key]
+		if paused || strategy == nil || strategy.ProgressDeadlineSeconds == nil {
+			continue
+		}
+		deadline := lastProgress.Add(time.Duration(*strategy.ProgressDeadlineSeconds) * time.Second)
+		if !now.Before(deadline) {
+			// Keys of core kinds end with a dot, since the API group is empty.
+			exceeded = append(exceeded, strings.TrimSuffix(key, "."))
+			autoRollback = autoRollback || (strategy.AutoRollback != nil && *strategy.AutoRollback)
+			continue
+		}
+		// Make sure we check again once the deadline has passed.
+		resync := deadline.Sub(now).Seconds()
+		if latest.syncResult.ResyncAfterSeconds == 0 || resync < latest.syncResult.ResyncAfterSeconds {
+			latest.syncResult.ResyncAfterSeconds = resync
+		}
+	}
+
+	condition := &dynamicobject.StatusCondition{
+		Type:    progressingConditionType,
+		Status:  "True",
+		Reason:  "RolloutProgressing",
+		Message: fmt.Sprintf("rolling out ControllerRevision %v", latest.revision.Name),
+	}
+	rollbackTo := ""
+	switch {
+	case paused:
+		condition.Status = "Unknown"
+		condition.Reason = "RolloutPaused"
+		condition.Message = "rollout is paused"
+	case len(exceeded) > 0:
+		sort.Strings(exceeded)
+		condition.Status = "False"
+		condition.Reason = "ProgressDeadlineExceeded"
+		condition.Message = fmt.Sprintf("rollout of ControllerRevision %v has not made progress since %v for %v",
+			latest.revision.Name, lastProgress.Format(time.RFC3339), strings.Join(exceeded, ", "))
+
+		// Only act once, when the deadline is first exceeded.
+		if previous != nil && previous.Reason == condition.Reason {
+			break
+		}
+		if autoRollback {
+			rollbackTo = previousRevisionName(parentRevisions)
+		}
+		if rollbackTo != "" {
+			pc.eventRecorder.Eventf(latest.parent, corev1.EventTypeWarning, events.ReasonProgressDeadlineExceeded,
+				"%s, rolling back to ControllerRevision %v", condition.Message, rollbackTo)
+		} else {
+			pc.eventRecorder.Event(latest.parent, corev1.EventTypeWarning, events.ReasonProgressDeadlineExceeded, condition.Message)
+		}
+	case len(pendingKinds) == 0:
+		condition.Reason = "RolloutComplete"
+		condition.Message = fmt.Sprintf("ControllerRevision %v has been rolled out", latest.revision.Name)
+	}
+	return rollbackTo, dynamicobject.SetCondition(latest.syncResult.Status, condition)
+}
+
+// previousRevisionName returns the most recent revision before the latest one,
+// unless the latest revision is itself the result of a rollback, so that two
+// failing revisions don't keep rolling back to each other.
+func previousRevisionName(parentRevisions []*parentRevision) string {
+	if _, ok := parentRevisions[0].revision.GetAnnotations()[annotationKeyRollbackFrom]; ok {
+		return ""
+	}
+	var previous *v1alpha1.ControllerRevision
+	for _, pr := range parentRevisions[1:] {
+		if previous == nil || revisionNumber(pr.revision) > revisionNumber(previous) {
+			previous = pr.revision
+		}
+	}
+	if previous == nil {
+		return ""
+	}
+	return previous.Name
+}
+
+func lastProgressTime(revision *v1alpha1.ControllerRevision) (time.Time, bool) {
+	value, ok := revision.GetAnnotations()[annotationKeyLastProgressTime]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func setLastProgressTime(revision *v1alpha1.ControllerRevision, t time.Time) {
+	annotations := revision.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[annotationKeyLastProgressTime] = t.UTC().Format(time.RFC3339)
+	revision.SetAnnotations(annotations)
+}
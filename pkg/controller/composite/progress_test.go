@@ -0,0 +1,188 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	dynamicobject "metacontroller/pkg/dynamic/object"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	commonv1 "metacontroller/pkg/controller/common/api/v1"
+)
+
+func newDeadlineController(autoRollback bool) (*parentController, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	pc := newRollingController(nil)
+	pc.eventRecorder = recorder
+	strategy := pc.updateStrategy.get("", "Pod")
+	strategy.ProgressDeadlineSeconds = ptr.To[int32](60)
+	strategy.AutoRollback = ptr.To(autoRollback)
+	return pc, recorder
+}
+
+func progressingCondition(t *testing.T, pr *parentRevision) *dynamicobject.StatusCondition {
+	condition, err := dynamicobject.GetStatusCondition(map[string]interface{}{"status": pr.syncResult.Status}, progressingConditionType)
+	assert.NoError(t, err)
+	return condition
+}
+
+// setPausedCondition reports in the status of the parent that its rollout was
+// paused by the previous sync.
+func setPausedCondition(t *testing.T, pr *parentRevision) {
+	status := make(map[string]interface{})
+	assert.NoError(t, dynamicobject.SetCondition(status, &dynamicobject.StatusCondition{
+		Type: progressingConditionType, Status: "Unknown", Reason: "RolloutPaused",
+	}))
+	pr.parent.Object["status"] = status
+}
+
+// stuckRollout returns the revisions and observed children of a rollout of pod-0
+// and pod-1, where pod-0 was updated but never became ready.
+func stuckRollout() ([]*parentRevision, commonv1.RelativeObjectMap) {
+	parentRevisions := newRollout("pod-0", "pod-1")
+	parentRevisions[0].addChild("", "Pod", "pod-0")
+	parentRevisions[1].removeChild("", "Pod", "pod-0")
+	observed := commonv1.MakeRelativeObjectMap(newRollingParent(), []*unstructured.Unstructured{
+		newRollingPod("pod-0", "new", false),
+		newRollingPod("pod-1", "old", true),
+	})
+	return parentRevisions, observed
+}
+
+func TestSyncRollingUpdate_paused(t *testing.T) {
+	pc := newRollingController(nil)
+	parentRevisions := newRollout("pod-0", "pod-1")
+	parentRevisions[0].parent.SetAnnotations(map[string]string{annotationKeyPaused: "true"})
+	observed := commonv1.MakeRelativeObjectMap(newRollingParent(), []*unstructured.Unstructured{
+		newRollingPod("pod-0", "old", true),
+		newRollingPod("pod-1", "old", true),
+	})
+
+	_, err := pc.syncRollingUpdate(parentRevisions, observed, time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, parentRevisions[0].countChildren())
+	assert.Equal(t, "RolloutPaused", updatedCondition(t, parentRevisions[0]).Reason)
+	assert.Nil(t, progressingCondition(t, parentRevisions[0]))
+}
+
+func TestSyncRollingUpdate_withinProgressDeadline(t *testing.T) {
+	pc, recorder := newDeadlineController(true)
+	parentRevisions, observed := stuckRollout()
+	now := time.Now()
+	setLastProgressTime(parentRevisions[0].revision, now.Add(-30*time.Second))
+
+	rollbackTo, err := pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	assert.Empty(t, rollbackTo)
+	assert.Equal(t, "RolloutProgressing", progressingCondition(t, parentRevisions[0]).Reason)
+	assert.InDelta(t, 30, parentRevisions[0].syncResult.ResyncAfterSeconds, 1)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestSyncRollingUpdate_progressDeadlineExceeded(t *testing.T) {
+	pc, recorder := newDeadlineController(false)
+	parentRevisions, observed := stuckRollout()
+	now := time.Now()
+	setLastProgressTime(parentRevisions[0].revision, now.Add(-2*time.Minute))
+
+	rollbackTo, err := pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	assert.Empty(t, rollbackTo)
+	condition := progressingCondition(t, parentRevisions[0])
+	assert.Equal(t, "False", condition.Status)
+	assert.Equal(t, "ProgressDeadlineExceeded", condition.Reason)
+	assert.Len(t, recorder.Events, 1)
+}
+
+func TestSyncRollingUpdate_progressDeadlineExceededAutoRollback(t *testing.T) {
+	pc, _ := newDeadlineController(true)
+	parentRevisions, observed := stuckRollout()
+	now := time.Now()
+	setLastProgressTime(parentRevisions[0].revision, now.Add(-2*time.Minute))
+
+	rollbackTo, err := pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "old", rollbackTo)
+
+	// A revision that is the result of a rollback is never rolled back again.
+	parentRevisions, observed = stuckRollout()
+	parentRevisions[0].revision.Annotations[annotationKeyRollbackFrom] = "old"
+	setLastProgressTime(parentRevisions[0].revision, now.Add(-2*time.Minute))
+
+	rollbackTo, err = pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	assert.Empty(t, rollbackTo)
+}
+
+func TestSyncRollingUpdate_pauseResetsProgressDeadline(t *testing.T) {
+	pc, _ := newDeadlineController(true)
+	parentRevisions, observed := stuckRollout()
+	parentRevisions[0].parent.SetAnnotations(map[string]string{annotationKeyPaused: "true"})
+	now := time.Now()
+	setLastProgressTime(parentRevisions[0].revision, now.Add(-2*time.Minute))
+
+	rollbackTo, err := pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	assert.Empty(t, rollbackTo)
+	assert.Equal(t, "RolloutPaused", progressingCondition(t, parentRevisions[0]).Reason)
+	lastProgress, _ := lastProgressTime(parentRevisions[0].revision)
+	assert.Equal(t, now.Unix(), lastProgress.Unix())
+}
+
+func TestSyncRollingUpdate_stayingPausedKeepsProgressTime(t *testing.T) {
+	pc, _ := newDeadlineController(true)
+	parentRevisions, observed := stuckRollout()
+	parentRevisions[0].parent.SetAnnotations(map[string]string{annotationKeyPaused: "true"})
+	setPausedCondition(t, parentRevisions[0])
+	now := time.Now()
+	pausedAt := now.Add(-2 * time.Minute)
+	setLastProgressTime(parentRevisions[0].revision, pausedAt)
+
+	_, err := pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	lastProgress, _ := lastProgressTime(parentRevisions[0].revision)
+	assert.Equal(t, pausedAt.Unix(), lastProgress.Unix())
+}
+
+func TestSyncRollingUpdate_resumeResetsProgressDeadline(t *testing.T) {
+	pc, recorder := newDeadlineController(true)
+	parentRevisions, observed := stuckRollout()
+	setPausedCondition(t, parentRevisions[0])
+	now := time.Now()
+	setLastProgressTime(parentRevisions[0].revision, now.Add(-2*time.Minute))
+
+	rollbackTo, err := pc.syncRollingUpdate(parentRevisions, observed, now)
+
+	assert.NoError(t, err)
+	assert.Empty(t, rollbackTo)
+	assert.Equal(t, "RolloutProgressing", progressingCondition(t, parentRevisions[0]).Reason)
+	lastProgress, _ := lastProgressTime(parentRevisions[0].revision)
+	assert.Equal(t, now.Unix(), lastProgress.Unix())
+	assert.Empty(t, recorder.Events)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	mcclientset "metacontroller/pkg/client/generated/clientset/internalclientset"
+	mclisters "metacontroller/pkg/client/generated/lister/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/api"
+	"metacontroller/pkg/controller/common/customize"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
+)
+
+// Render syncs the parent like the controller would, against the given
+// observed children and related objects, and returns the desired children.
+// The controller runs in dry-run mode and passes its writes to collect
+// instead of reporting them. Its dry-run writes go to the given clients,
+// which should hold the parent and its observed children, and the resources
+// map the resources of the controller to kinds, like discovery does.
+//
+// The parent has no ControllerRevisions yet, so a rollout starts over.
+func Render(
+	ctx context.Context,
+	resources *dynamicdiscovery.ResourceMap,
+	dynClient *dynamicclientset.Clientset,
+	mcClient mcclientset.Interface,
+	cc *v1alpha1.CompositeController,
+	syncHook, finalizeHook hooks.Hook,
+	collect func(write common.DryRunWrite),
+	parent *unstructured.Unstructured,
+	observedChildren, relatedObjects api.ObjectMap,
+) ([]*unstructured.Unstructured, error) {
+	if cc.Spec.Hooks == nil {
+		return nil, fmt.Errorf("no hooks defined")
+	}
+	parentClient, err := dynClient.Resource(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
+	if err != nil {
+		return nil, err
+	}
+	parentSelector, err := makeParentSelector(cc)
+	if err != nil {
+		return nil, err
+	}
+	updateStrategy, err := makeUpdateStrategyMap(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	applyOptions, err := makeApplyOptionsMap(resources, cc, &common.ApplyOptions{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyOptions.SetDryRun(common.NewCollectingDryRun("CompositeController", cc.Name, collect))
+	revisions := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	queue := common.NewQueue("")
+	defer queue.ShutDown()
+
+	pc := &parentController{
+		cc:             cc,
+		mcClient:       mcClient,
+		resources:      resources,
+		dynClient:      dynClient,
+		parentClient:   parentClient,
+		parentSelector: parentSelector,
+		parentResource: parentClient.APIResource,
+		revisionLister: mclisters.NewControllerRevisionLister(revisions),
+		updateStrategy: updateStrategy,
+		queue:          queue,
+		applyOptions:   applyOptions,
+		eventRecorder:  &record.FakeRecorder{},
+		finalizer:      newFinalizerManager(cc),
+		customize:      customize.NewStaticManager(relatedObjects),
+		syncHook:       syncHook,
+		finalizeHook:   finalizeHook,
+		logger:         logging.Logger.WithName(cc.Name),
+		ctx:            ctx,
+	}
+	ctx = redact.NewContext(ctx, applyOptions.Redactor())
+
+	parent, ok, err := pc.syncParentFinalizer(ctx, parent)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return pc.syncObservedParent(ctx, parent, observedChildren, relatedObjects)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/events"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+)
+
+const (
+	// annotationKeyRollbackTo can be set on a parent to roll it back to one of
+	// its ControllerRevisions, given either by name or by revision number.
+	annotationKeyRollbackTo = "metacontroller.k8s.io/rollback-to"
+	// annotationKeyRollbackFrom is set on the ControllerRevision a parent was
+	// rolled back to, and records the revision it was rolled back from.
+	annotationKeyRollbackFrom = "metacontroller.k8s.io/rollback-from"
+
+	rolledBackConditionType = "RolledBack"
+)
+
+// rollback restores the revisioned fields of the parent from the revision
+// requested in the rollback-to annotation, and removes the annotation.
+// The next sync of the parent then rolls children out to that revision
+// according to the normal child update strategy. It returns the parent as
+// the rollback left it.
+func (pc *parentController) rollback(ctx context.Context, parent *unstructured.Unstructured, observedRevisions []*v1alpha1.ControllerRevision, latestPatch map[string]interface{}, fieldPaths []string) (*unstructured.Unstructured, error) {
+	rollbackTo := parent.GetAnnotations()[annotationKeyRollbackTo]
+	target := findRevision(observedRevisions, rollbackTo)
+	if target == nil {
+		pc.eventRecorder.Eventf(parent, corev1.EventTypeWarning, events.ReasonRollbackFailed,
+			"Can't roll back: ControllerRevision %q not found", rollbackTo)
+		return pc.removeRollbackAnnotation(ctx, parent, nil, nil)
+	}
+
+	patch, err := revisionPatch(target)
+	if err != nil {
+		return nil, err
+	}
+	if common.DeepEqual(patch, latestPatch) {
+		pc.eventRecorder.Eventf(parent, corev1.EventTypeNormal, events.ReasonRollbackSkipped,
+			"Not rolling back: already at ControllerRevision %v", target.Name)
+		return pc.removeRollbackAnnotation(ctx, parent, nil, nil)
+	}
+
+	// Record the rollback on the target revision, and make it the most recent
+	// revision in the history.
+	var from string
+	if current := latestRevision(observedRevisions); current != nil {
+		from = current.Name
+	}
+	revision := target.DeepCopy()
+	setRevisionNumber(revision, maxRevisionNumber(observedRevisions, revision.Name)+1)
+	annotations := revision.GetAnnotations()
+	annotations[annotationKeyRollbackFrom] = from
+	revision.SetAnnotations(annotations)
+	client := pc.mcClient.MetacontrollerV1alpha1().ControllerRevisions(parent.GetNamespace())
+	dryRun := pc.applyOptions.DryRun()
+	if _, err := client.Update(ctx, revision, metav1.UpdateOptions{DryRun: dryRun.Options()}); err != nil {
+		return nil, fmt.Errorf("can't update ControllerRevision %v for rollback of %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	reportRevision(dryRun, parent, common.DryRunUpdate, target, revision)
+
+	updated, err := pc.removeRollbackAnnotation(ctx, parent, patch, fieldPaths)
+	if err != nil {
+		return nil, err
+	}
+	pc.eventRecorder.Eventf(parent, corev1.EventTypeNormal, events.ReasonRollbackStarted,
+		"Rolling back to ControllerRevision %v (revision %d)", revision.Name, revisionNumber(revision))
+	return updated, nil
+}
+
+// dryRunRollback is like rollback, for dry-run mode. The rollback-to
+// annotation is never removed in dry-run mode, so every sync requests the
+// rollback again: it's only reported the first time, and later syncs
+// quietly return the parent as the rollback would leave it.
+func (pc *parentController) dryRunRollback(ctx context.Context, parent *unstructured.Unstructured, observedRevisions []*v1alpha1.ControllerRevision, latestPatch map[string]interface{}, fieldPaths []string) (*unstructured.Unstructured, error) {
+	rollbackTo := parent.GetAnnotations()[annotationKeyRollbackTo]
+	if reported, ok := pc.dryRunRollbacks.Load(parent.GetUID()); !ok || reported != rollbackTo {
+		pc.dryRunRollbacks.Store(parent.GetUID(), rollbackTo)
+		return pc.rollback(ctx, parent, observedRevisions, latestPatch, fieldPaths)
+	}
+
+	updated := parent.DeepCopy()
+	if target := findRevision(observedRevisions, rollbackTo); target != nil {
+		patch, err := revisionPatch(target)
+		if err != nil {
+			return nil, err
+		}
+		if !common.DeepEqual(patch, latestPatch) {
+			if err := restorePatch(updated.UnstructuredContent(), patch, fieldPaths); err != nil {
+				return nil, fmt.Errorf("can't roll back %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+			}
+		}
+	}
+	annotations := updated.GetAnnotations()
+	delete(annotations, annotationKeyRollbackTo)
+	updated.SetAnnotations(annotations)
+	return updated, nil
+}
+
+// removeRollbackAnnotation removes the rollback-to annotation from the parent,
+// and restores the given revisioned fields from the patch, if any.
+func (pc *parentController) removeRollbackAnnotation(ctx context.Context, parent *unstructured.Unstructured, patch map[string]interface{}, fieldPaths []string) (*unstructured.Unstructured, error) {
+	var restoreErr error
+	updated, err := pc.updateParent(ctx, parent, func(obj *unstructured.Unstructured) bool {
+		if patch != nil {
+			if restoreErr = restorePatch(obj.UnstructuredContent(), patch, fieldPaths); restoreErr != nil {
+				return false
+			}
+		}
+		annotations := obj.GetAnnotations()
+		delete(annotations, annotationKeyRollbackTo)
+		obj.SetAnnotations(annotations)
+		return true
+	})
+	if err == nil {
+		err = restoreErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't roll back %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	return updated, nil
+}
+
+// revisionPatch returns the parent patch of a revision.
+func revisionPatch(revision *v1alpha1.ControllerRevision) (map[string]interface{}, error) {
+	patch := make(map[string]interface{})
+	if err := json.Unmarshal(revision.ParentPatch.Raw, &patch); err != nil {
+		return nil, fmt.Errorf("can't unmarshal ControllerRevision parentPatch: %w", err)
+	}
+	return patch, nil
+}
+
+// setRollbackCondition reports the progress of a rollback in the parent status,
+// if the latest revision is the result of a rollback.
+func (pc *parentController) setRollbackCondition(parent *unstructured.Unstructured, status map[string]interface{}, parentRevisions []*parentRevision) error {
+	latest := parentRevisions[0]
+	from, ok := latest.revision.GetAnnotations()[annotationKeyRollbackFrom]
+	if !ok {
+		return nil
+	}
+	condition := &dynamicobject.StatusCondition{
+		Type:    rolledBackConditionType,
+		Status:  "False",
+		Reason:  "RollbackProgressing",
+		Message: fmt.Sprintf("rolling back from ControllerRevision %v to %v", from, latest.revision.Name),
+	}
+	if len(parentRevisions) == 1 {
+		condition.Status = "True"
+		condition.Reason = "RollbackComplete"
+		condition.Message = fmt.Sprintf("rolled back from ControllerRevision %v to %v", from, latest.revision.Name)
+
+		// Only emit the event once, when the rollback completes.
+		if previous, _ := dynamicobject.GetStatusCondition(parent.UnstructuredContent(), rolledBackConditionType); previous == nil || previous.Status != condition.Status {
+			pc.eventRecorder.Eventf(parent, corev1.EventTypeNormal, events.ReasonRollbackComplete,
+				"Rolled back to ControllerRevision %v", latest.revision.Name)
+		}
+	}
+	return dynamicobject.SetCondition(status, condition)
+}
+
+// findRevision returns the revision with the given name or revision number.
+func findRevision(revisions []*v1alpha1.ControllerRevision, nameOrNumber string) *v1alpha1.ControllerRevision {
+	nameOrNumber = strings.TrimSpace(nameOrNumber)
+	number, err := strconv.ParseInt(nameOrNumber, 10, 64)
+	for _, revision := range revisions {
+		if revision.Name == nameOrNumber || (err == nil && revisionNumber(revision) == number) {
+			return revision
+		}
+	}
+	return nil
+}
+
+// latestRevision returns the revision with the highest revision number.
+func latestRevision(revisions []*v1alpha1.ControllerRevision) *v1alpha1.ControllerRevision {
+	var latest *v1alpha1.ControllerRevision
+	for _, revision := range revisions {
+		if latest == nil || revisionNumber(revision) > revisionNumber(latest) {
+			latest = revision
+		}
+	}
+	return latest
+}
+
+// restorePatch is like applyPatch, but also removes fields that are missing
+// from the patch, so that the result fully reflects the revision.
+func restorePatch(dest, patch map[string]interface{}, fieldPaths []string) error {
+	for _, fieldPath := range fieldPaths {
+		pathParts := strings.Split(fieldPath, ".")
+		value, found, err := unstructured.NestedFieldCopy(patch, pathParts...)
+		if err != nil {
+			return err
+		}
+		if !found {
+			unstructured.RemoveNestedField(dest, pathParts...)
+			continue
+		}
+		if err := unstructured.SetNestedField(dest, value, pathParts...); err != nil {
+			return fmt.Errorf("can't restore field %v: %w", fieldPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+	. "metacontroller/pkg/internal/testutils/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestFindRevision(t *testing.T) {
+	revisions := []*v1alpha1.ControllerRevision{
+		newNumberedRevision("first", 1),
+		newNumberedRevision("second", 2),
+	}
+
+	assert.Equal(t, "second", findRevision(revisions, "2").Name)
+	assert.Equal(t, "first", findRevision(revisions, " first ").Name)
+	assert.Nil(t, findRevision(revisions, "3"))
+	assert.Equal(t, "second", latestRevision(revisions).Name)
+}
+
+func TestRestorePatch(t *testing.T) {
+	dest := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{"image": "new"},
+			"extra":    "added-later",
+		},
+	}
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{"image": "old"},
+		},
+	}
+
+	err := restorePatch(dest, patch, []string{"spec.template", "spec.extra"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{"image": "old"},
+		},
+	}, dest)
+}
+
+func TestSetRollbackCondition(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	pc := &parentController{eventRecorder: recorder}
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	latest := &parentRevision{revision: newNumberedRevision("target", 3, "pod-1")}
+	latest.revision.Annotations[annotationKeyRollbackFrom] = "broken"
+	old := &parentRevision{revision: newNumberedRevision("broken", 2, "pod-2")}
+
+	status := make(map[string]interface{})
+	err := pc.setRollbackCondition(parent, status, []*parentRevision{latest, old})
+	assert.NoError(t, err)
+	condition, _ := dynamicobject.GetStatusCondition(map[string]interface{}{"status": status}, rolledBackConditionType)
+	assert.Equal(t, "False", condition.Status)
+	assert.Equal(t, "RollbackProgressing", condition.Reason)
+	assert.Empty(t, recorder.Events)
+
+	status = make(map[string]interface{})
+	err = pc.setRollbackCondition(parent, status, []*parentRevision{latest})
+	assert.NoError(t, err)
+	condition, _ = dynamicobject.GetStatusCondition(map[string]interface{}{"status": status}, rolledBackConditionType)
+	assert.Equal(t, "True", condition.Status)
+	assert.Equal(t, "RollbackComplete", condition.Reason)
+	assert.Len(t, recorder.Events, 1)
+
+	// No further event once the parent status reflects the completed rollback.
+	parent.Object["status"] = status
+	err = pc.setRollbackCondition(parent, make(map[string]interface{}), []*parentRevision{latest})
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Events, 1)
+}
+
+func TestSetRollbackCondition_notRolledBack(t *testing.T) {
+	pc := &parentController{eventRecorder: record.NewFakeRecorder(10)}
+	status := make(map[string]interface{})
+
+	err := pc.setRollbackCondition(&unstructured.Unstructured{}, status, []*parentRevision{{revision: newNumberedRevision("latest", 1)}})
+
+	assert.NoError(t, err)
+	assert.Empty(t, status)
+}
+
+func TestDryRunRollback_reportsOnce(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	pc := newDryRunController(t, recorder)
+	pc.eventRecorder = recorder
+	parent := NewDefaultUnstructured()
+	parent.SetAnnotations(map[string]string{annotationKeyRollbackTo: "missing"})
+	revisions := []*v1alpha1.ControllerRevision{newNumberedRevision("latest", 1)}
+
+	for i := 0; i < 2; i++ {
+		updated, err := pc.dryRunRollback(context.TODO(), parent, revisions, nil, nil)
+
+		assert.NoError(t, err)
+		assert.NotContains(t, updated.GetAnnotations(), annotationKeyRollbackTo)
+		assert.Contains(t, parent.GetAnnotations(), annotationKeyRollbackTo)
+	}
+	// The failed rollback and the dry-run update of the parent, only once.
+	assert.Len(t, recorder.Events, 2)
+}
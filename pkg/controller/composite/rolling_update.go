@@ -17,22 +17,34 @@ limitations under the License.
 package composite
 
 import (
+	"context"
 	"fmt"
 	"metacontroller/pkg/controller/common"
 	"metacontroller/pkg/controller/common/api"
+	commonv2 "metacontroller/pkg/controller/common/api/v2"
+	"metacontroller/pkg/redact"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicobject "metacontroller/pkg/dynamic/object"
 )
 
 const updatedConditionType = "Updated"
 
-func (pc *parentController) syncRollingUpdate(parentRevisions []*parentRevision, observedChildren api.ObjectMap) error {
+// syncRollingUpdate moves children to the latest revision as far as the
+// update strategies allow. It returns the name of a revision to roll back to,
+// if the rollout failed and should be rolled back automatically.
+func (pc *parentController) syncRollingUpdate(parentRevisions []*parentRevision, observedChildren api.ObjectMap, now time.Time) (string, error) {
 	// Reconcile the set of existing child claims in ControllerRevisions.
 	claimed := pc.syncRevisionClaims(parentRevisions)
 
@@ -68,7 +80,7 @@ func (pc *parentController) syncRollingUpdate(parentRevisions []*parentRevision,
 				// The child wasn't observed, so we don't know if it'll match latest.
 				continue
 			}
-			updated, err := common.ApplyUpdate(child, desiredChild)
+			updated, err := pc.applyUpdate(child, desiredChild)
 			if err != nil {
 				// We can't prove it'll be a no-op, so don't move it to latest.
 				continue
@@ -82,13 +94,27 @@ func (pc *parentController) syncRollingUpdate(parentRevisions []*parentRevision,
 				latest.addChild(apiGroup, gvk.Kind, name)
 				pr.removeChild(apiGroup, gvk.Kind, name)
 				claimed.setParentRevision(apiGroup, gvk.Kind, name, latest)
+				pc.metrics.ChildMoved(apiGroup, gvk.Kind)
 			}
 		}
 	}
 
-	// Look for the next child to update, if any.
-	// We go one by one, in the order in which the controller returned them
-	// in the latest sync hook result.
+	// Look for the next children to update, if any.
+	// We go in the order in which the controller returned them in the latest
+	// sync hook result. Child types without rollingUpdate parameters move one
+	// child per sync pass, the others move as many as their budget allows.
+	budgets := pc.makeRolloutBudgets(latest, observedChildren)
+	ordinals := make(map[string]int)
+	pendingKinds := make(map[string]bool)
+	pendingChildren := make(map[schema.GroupKind]int)
+	paused := isPaused(latest.parent)
+	var moved []string
+	var waiting error
+	heldBack := 0
+	pausedChildren := 0
+	legacyMoved := false
+	var legacyErr error
+	legacyChecked := false
 	for _, child := range latest.syncResult.Children {
 		apiGroup, _ := common.ParseAPIVersion(child.GetAPIVersion())
 		kind := child.GetKind()
@@ -98,64 +124,133 @@ func (pc *parentController) syncRollingUpdate(parentRevisions []*parentRevision,
 		if !pc.updateStrategy.isRolling(apiGroup, kind) {
 			continue
 		}
+		key := claimMapKey(apiGroup, kind)
+		ordinal := ordinals[key]
+		ordinals[key]++
 
 		// Look up which revision claims this child, if any.
 		var pr *parentRevision
 		if claimMap := claimed.getKind(apiGroup, kind); claimMap != nil {
 			pr = claimMap[name]
 		}
+		if pr == latest {
+			continue
+		}
 
-		// Move the first child that isn't in the latest revision to the latest.
-		if pr != latest {
+		budget, hasBudget := budgets[key]
+		// Children below the partition stay at their current revision.
+		if hasBudget && ordinal < budget.partition {
+			heldBack++
+			continue
+		}
+		pendingKinds[key] = true
+		pendingChildren[schema.GroupKind{Group: apiGroup, Kind: kind}]++
+		if paused {
+			pausedChildren++
+			continue
+		}
+
+		if hasBudget {
+			if budget.remaining <= 0 {
+				waiting = budget.waitingFor
+				continue
+			}
+			budget.remaining--
+		} else {
+			if legacyMoved {
+				continue
+			}
 			// We only continue to push more children into the latest revision if all
 			// the children already in the latest revision are happy, where "happy" is
 			// defined by the statusChecks in each child type's updateStrategy.
-			if err := pc.shouldContinueRolling(latest, observedChildren); err != nil {
-				// Add status condition to explain what we're waiting for.
-				updatedCondition := &dynamicobject.StatusCondition{
-					Type:    updatedConditionType,
-					Status:  "False",
-					Reason:  "RolloutWaiting",
-					Message: err.Error(),
-				}
-				if err := dynamicobject.SetCondition(latest.syncResult.Status, updatedCondition); err != nil {
-					return err
-				}
-				return nil
+			if !legacyChecked {
+				legacyErr = pc.shouldContinueRolling(latest, observedChildren)
+				legacyChecked = true
 			}
-
-			latest.addChild(apiGroup, kind, name)
-			// Remove it from all other revisions.
-			for _, pr := range parentRevisions[1:] {
-				pr.removeChild(apiGroup, kind, name)
+			if legacyErr != nil {
+				waiting = legacyErr
+				continue
 			}
+			legacyMoved = true
+		}
 
-			// We've done our one move for this sync pass.
-			// Add status condition to explain what we're doing next.
-			updatedCondition := &dynamicobject.StatusCondition{
-				Type:    updatedConditionType,
-				Status:  "False",
-				Reason:  "RolloutProgressing",
-				Message: fmt.Sprintf("updating %v %v", kind, name),
-			}
-			if err := dynamicobject.SetCondition(latest.syncResult.Status, updatedCondition); err != nil {
-				return err
-			}
-			return nil
+		latest.addChild(apiGroup, kind, name)
+		// Remove it from all other revisions.
+		for _, pr := range parentRevisions[1:] {
+			pr.removeChild(apiGroup, kind, name)
+		}
+		moved = append(moved, fmt.Sprintf("%v %v", kind, name))
+		pendingChildren[schema.GroupKind{Group: apiGroup, Kind: kind}]--
+		pc.metrics.ChildMoved(apiGroup, kind)
+	}
+	pc.metrics.ObserveRollout(cache.MetaObjectToName(latest.parent).String(), pendingChildren)
+	// Kinds are no longer pending if the last children were just moved.
+	for key := range pendingKinds {
+		if !hasPendingChildren(parentRevisions[1:], key) {
+			delete(pendingKinds, key)
 		}
 	}
 
-	// Everything is already on the latest revision.
-	updatedCondition := &dynamicobject.StatusCondition{
-		Type:    updatedConditionType,
-		Status:  "True",
-		Reason:  "OnLatestRevision",
-		Message: fmt.Sprintf("latest ControllerRevision: %v", latest.revision.Name),
+	rollbackTo, err := pc.syncProgress(parentRevisions, paused, len(moved) > 0, pendingKinds, now)
+	if err != nil {
+		return "", err
+	}
+
+	var updatedCondition *dynamicobject.StatusCondition
+	switch {
+	case len(moved) > 0:
+		// Add status condition to explain what we're doing next.
+		updatedCondition = &dynamicobject.StatusCondition{
+			Type:    updatedConditionType,
+			Status:  "False",
+			Reason:  "RolloutProgressing",
+			Message: fmt.Sprintf("updating %v", strings.Join(moved, ", ")),
+		}
+	case pausedChildren > 0:
+		updatedCondition = &dynamicobject.StatusCondition{
+			Type:    updatedConditionType,
+			Status:  "False",
+			Reason:  "RolloutPaused",
+			Message: fmt.Sprintf("rollout is paused with %d children waiting to be updated", pausedChildren),
+		}
+	case waiting != nil:
+		// Add status condition to explain what we're waiting for.
+		updatedCondition = &dynamicobject.StatusCondition{
+			Type:    updatedConditionType,
+			Status:  "False",
+			Reason:  "RolloutWaiting",
+			Message: waiting.Error(),
+		}
+	case heldBack > 0:
+		updatedCondition = &dynamicobject.StatusCondition{
+			Type:    updatedConditionType,
+			Status:  "False",
+			Reason:  "RolloutPartitioned",
+			Message: fmt.Sprintf("%d children held back by partition", heldBack),
+		}
+	default:
+		// Everything is already on the latest revision.
+		updatedCondition = &dynamicobject.StatusCondition{
+			Type:    updatedConditionType,
+			Status:  "True",
+			Reason:  "OnLatestRevision",
+			Message: fmt.Sprintf("latest ControllerRevision: %v", latest.revision.Name),
+		}
 	}
-	if err := dynamicobject.SetCondition(latest.syncResult.Status, updatedCondition); err != nil {
-		return err
+	return rollbackTo, dynamicobject.SetCondition(latest.syncResult.Status, updatedCondition)
+}
+
+// hasPendingChildren returns true if any of the given old revisions still
+// claims children of the given kind.
+func hasPendingChildren(oldRevisions []*parentRevision, key string) bool {
+	for _, pr := range oldRevisions {
+		for _, ck := range pr.revision.Children {
+			if claimMapKey(ck.APIGroup, ck.Kind) == key && len(ck.Names) > 0 {
+				return true
+			}
+		}
 	}
-	return nil
+	return false
 }
 
 func (pc *parentController) shouldContinueRolling(latest *parentRevision, observedChildren api.ObjectMap) error {
@@ -170,49 +265,171 @@ func (pc *parentController) shouldContinueRolling(latest *parentRevision, observ
 		}
 
 		for _, name := range ck.Names {
-			groupKind := schema.GroupKind{
-				Group: ck.APIGroup,
-				Kind:  ck.Kind}
-			child := observedChildren.FindGroupKindName(groupKind, name)
-			if child == nil {
-				// We didn't observe this child at all, so it's not happy.
-				return fmt.Errorf("missing child %v %v", ck.Kind, name)
+			if _, err := pc.checkUpdatedChild(latest, strategy, ck.APIGroup, ck.Kind, name, observedChildren); err != nil {
+				return err
 			}
-			// Is this child up-to-date with what the latest revision wants?
-			// Apply the latest update to it and see if anything changes.
-			update := latest.desiredChildMap.FindGroupKindName(groupKind, name)
-			updated, err := common.ApplyUpdate(child, update)
+		}
+	}
+	return nil
+}
+
+// checkUpdatedChild checks whether a child claimed by the latest revision is
+// updated and passes its status checks. If not, it also reports whether the
+// child is still in the process of being updated, as opposed to having been
+// updated but failing its status checks.
+func (pc *parentController) checkUpdatedChild(latest *parentRevision, strategy *v1alpha1.CompositeControllerChildUpdateStrategy, apiGroup, kind, name string, observedChildren api.ObjectMap) (updating bool, err error) {
+	groupKind := schema.GroupKind{
+		Group: apiGroup,
+		Kind:  kind}
+	child := observedChildren.FindGroupKindName(groupKind, name)
+	if child == nil {
+		// We didn't observe this child at all, so it's not happy.
+		return true, fmt.Errorf("missing child %v %v", kind, name)
+	}
+	// Is this child up-to-date with what the latest revision wants?
+	// Apply the latest update to it and see if anything changes.
+	update := latest.desiredChildMap.FindGroupKindName(groupKind, name)
+	updated, err := pc.applyUpdate(child, update)
+	if err != nil {
+		return true, fmt.Errorf("can't check if child %v %v is updated: %w", kind, name, err)
+	}
+	if !common.DeepEqual(child, updated) {
+		return true, fmt.Errorf("child %v %v is not updated yet", kind, name)
+	}
+	// For RollingInPlace, we should check ObservedGeneration (if possible)
+	// before checking status, to make sure status reflects the latest spec.
+	if strategy.Method == v1alpha1.ChildUpdateRollingInPlace {
+		// Ideally every controller would support ObservedGeneration, but not
+		// all do, so we have to ignore it if it's not present.
+		if observedGeneration, _, err := dynamicobject.GetObservedGeneration(child.UnstructuredContent()); observedGeneration > 0 {
 			if err != nil {
-				return fmt.Errorf("can't check if child %v %v is updated: %w", ck.Kind, name, err)
+				return true, err
 			}
-			if !common.DeepEqual(child, updated) {
-				return fmt.Errorf("child %v %v is not updated yet", ck.Kind, name)
+			// Ideally we would remember the Generation from our own last Update,
+			// but we don't have a good place to persist that.
+			// Instead, we compare with the latest Generation, which should be
+			// fine as long as the object spec is not updated frequently.
+			if observedGeneration < child.GetGeneration() {
+				return true, fmt.Errorf("child %v %v with RollingInPlace update strategy hasn't observed latest spec", kind, name)
 			}
-			// For RollingInPlace, we should check ObservedGeneration (if possible)
-			// before checking status, to make sure status reflects the latest spec.
-			if strategy.Method == v1alpha1.ChildUpdateRollingInPlace {
-				// Ideally every controller would support ObservedGeneration, but not
-				// all do, so we have to ignore it if it's not present.
-				if observedGeneration, _, err := dynamicobject.GetObservedGeneration(child.UnstructuredContent()); observedGeneration > 0 {
-					if err != nil {
-						return err
-					}
-					// Ideally we would remember the Generation from our own last Update,
-					// but we don't have a good place to persist that.
-					// Instead, we compare with the latest Generation, which should be
-					// fine as long as the object spec is not updated frequently.
-					if observedGeneration < child.GetGeneration() {
-						return fmt.Errorf("child %v %v with RollingInPlace update strategy hasn't observed latest spec", ck.Kind, name)
-					}
-				}
+		}
+	}
+	// Check the child status according to the updateStrategy.
+	if err := childStatusCheck(&strategy.StatusChecks, child); err != nil {
+		// If any child already on the latest revision fails the status check,
+		// pause the rollout.
+		return false, fmt.Errorf("child %v %v failed status check: %w", kind, name, err)
+	}
+	return false, nil
+}
+
+// rolloutBudget tracks how many more children of a type may be moved to the
+// latest revision in the current sync pass.
+type rolloutBudget struct {
+	partition  int
+	remaining  int
+	waitingFor error
+}
+
+// makeRolloutBudgets computes the rollout budget of each child type that has
+// rollingUpdate parameters.
+func (pc *parentController) makeRolloutBudgets(latest *parentRevision, observedChildren api.ObjectMap) map[string]*rolloutBudget {
+	// Count the desired children of each type.
+	totals := make(map[string]int)
+	for _, child := range latest.syncResult.Children {
+		apiGroup, _ := common.ParseAPIVersion(child.GetAPIVersion())
+		totals[claimMapKey(apiGroup, child.GetKind())]++
+	}
+
+	budgets := make(map[string]*rolloutBudget)
+	for key, strategy := range pc.updateStrategy {
+		if !isRollingStrategy(strategy) || strategy.RollingUpdate == nil {
+			continue
+		}
+		maxUnavailable, maxSurge, partition := resolveRollingUpdate(strategy, totals[key])
+		budgets[key] = &rolloutBudget{partition: partition, remaining: maxUnavailable + maxSurge}
+	}
+
+	// Subtract the children of the latest revision that aren't happy yet.
+	for _, ck := range latest.revision.Children {
+		key := claimMapKey(ck.APIGroup, ck.Kind)
+		budget, ok := budgets[key]
+		if !ok {
+			continue
+		}
+		strategy := pc.updateStrategy.get(ck.APIGroup, ck.Kind)
+		maxUnavailable, _, _ := resolveRollingUpdate(strategy, totals[key])
+		unavailable := 0
+		for _, name := range ck.Names {
+			updating, err := pc.checkUpdatedChild(latest, strategy, ck.APIGroup, ck.Kind, name, observedChildren)
+			if err == nil {
+				continue
 			}
-			// Check the child status according to the updateStrategy.
-			if err := childStatusCheck(&strategy.StatusChecks, child); err != nil {
-				// If any child already on the latest revision fails the status check,
-				// pause the rollout.
-				return fmt.Errorf("child %v %v failed status check: %w", ck.Kind, name, err)
+			budget.remaining--
+			budget.waitingFor = err
+			if !updating {
+				unavailable++
 			}
 		}
+		// The surge only makes room for children being recreated, not for
+		// children which were updated but fail their status checks.
+		if unavailable > maxUnavailable {
+			budget.remaining = 0
+		}
+	}
+	return budgets
+}
+
+// resolveRollingUpdate returns the absolute maxUnavailable, maxSurge and
+// partition for the given number of desired children.
+func resolveRollingUpdate(strategy *v1alpha1.CompositeControllerChildUpdateStrategy, total int) (maxUnavailable, maxSurge, partition int) {
+	maxUnavailable = 1
+	ru := strategy.RollingUpdate
+	if ru == nil {
+		return maxUnavailable, 0, 0
+	}
+	// The values have been validated when the controller was created.
+	if ru.MaxUnavailable != nil {
+		maxUnavailable, _ = intstr.GetScaledValueFromIntOrPercent(ru.MaxUnavailable, total, false)
+	}
+	if ru.MaxSurge != nil && strategy.Method == v1alpha1.ChildUpdateRollingRecreate {
+		maxSurge, _ = intstr.GetScaledValueFromIntOrPercent(ru.MaxSurge, total, true)
+	}
+	if ru.Partition != nil {
+		partition, _ = intstr.GetScaledValueFromIntOrPercent(ru.Partition, total, false)
+	}
+	// Make sure the rollout can always make progress.
+	if maxUnavailable <= 0 && maxSurge <= 0 {
+		maxUnavailable = 1
+	}
+	return maxUnavailable, maxSurge, partition
+}
+
+// validateRollingUpdate checks the rollingUpdate parameters of a child update
+// strategy.
+func validateRollingUpdate(strategy *v1alpha1.CompositeControllerChildUpdateStrategy) error {
+	ru := strategy.RollingUpdate
+	if ru == nil {
+		return nil
+	}
+	for field, value := range map[string]*intstr.IntOrString{
+		"maxUnavailable": ru.MaxUnavailable,
+		"maxSurge":       ru.MaxSurge,
+		"partition":      ru.Partition,
+	} {
+		if value == nil {
+			continue
+		}
+		scaled, err := intstr.GetScaledValueFromIntOrPercent(value, 100, false)
+		if err != nil {
+			return fmt.Errorf("invalid rollingUpdate.%s: %w", field, err)
+		}
+		if scaled < 0 {
+			return fmt.Errorf("invalid rollingUpdate.%s: must not be negative", field)
+		}
+	}
+	if ru.MaxSurge != nil && strategy.Method != v1alpha1.ChildUpdateRollingRecreate {
+		return fmt.Errorf("rollingUpdate.maxSurge is only supported with the %s method", v1alpha1.ChildUpdateRollingRecreate)
 	}
 	return nil
 }
@@ -301,6 +518,16 @@ func childStatusCheck(checks *v1alpha1.ChildUpdateStatusChecks, child *unstructu
 			}
 		}
 	}
+	for i := range checks.Fields {
+		if err := fieldStatusCheck(&checks.Fields[i], child); err != nil {
+			return err
+		}
+	}
+	if checks.Preset != "" {
+		if err := presetStatusCheck(checks.Preset, child); err != nil {
+			return fmt.Errorf("%s is not ready: %w", checks.Preset, err)
+		}
+	}
 	return nil
 }
 
@@ -314,6 +541,14 @@ func (m updateStrategyMap) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdate
 	return strategy.Method
 }
 
+func (m updateStrategyMap) GetOnImmutableFieldError(apiGroup, kind string) v1alpha1.ImmutableFieldErrorPolicy {
+	strategy := m.get(apiGroup, kind)
+	if strategy == nil || strategy.OnImmutableFieldError == "" {
+		return v1alpha1.ImmutableFieldErrorFail
+	}
+	return strategy.OnImmutableFieldError
+}
+
 func (m updateStrategyMap) get(apiGroup, kind string) *v1alpha1.CompositeControllerChildUpdateStrategy {
 	return m[claimMapKey(apiGroup, kind)]
 }
@@ -331,6 +566,15 @@ func (m updateStrategyMap) anyRolling() bool {
 	return false
 }
 
+func (m updateStrategyMap) anyProgressDeadline() bool {
+	for _, strategy := range m {
+		if isRollingStrategy(strategy) && strategy.ProgressDeadlineSeconds != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func isRollingStrategy(strategy *v1alpha1.CompositeControllerChildUpdateStrategy) bool {
 	if strategy == nil {
 		// This child kind uses OnDelete (don't update at all).
@@ -353,11 +597,129 @@ func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1
 			if resource == nil {
 				return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
 			}
+			if err := validateRollingUpdate(child.UpdateStrategy); err != nil {
+				return nil, fmt.Errorf("invalid update strategy for child resource %q in %v: %w", child.Resource, child.APIVersion, err)
+			}
 			// Ignore API version.
 			apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+			groupKind := schema.GroupKind{Group: apiGroup, Kind: resource.Kind}
+			if err := validateStatusChecks(&child.UpdateStrategy.StatusChecks, groupKind); err != nil {
+				return nil, fmt.Errorf("invalid status checks for child resource %q in %v: %w", child.Resource, child.APIVersion, err)
+			}
 			key := claimMapKey(apiGroup, resource.Kind)
 			m[key] = child.UpdateStrategy
 		}
 	}
 	return m, nil
 }
+
+// observeRollingChildren returns the observed children to check the rollout
+// with. Checking whether a rolling child matches the desired state of the
+// latest revision may depend on data that the caches drop, and so may its
+// status checks. Those children are replaced by the ones to compare, which
+// ObservedForApply returns, or by the live ones if the status checks read
+// stripped fields.
+func (pc *parentController) observeRollingChildren(ctx context.Context, parent *unstructured.Unstructured, latest *parentRevision, observedChildren api.ObjectMap) (api.ObjectMap, error) {
+	stripped := pc.applyOptions.Stripped()
+	if !stripped.Lossy() {
+		return observedChildren, nil
+	}
+	var rollingChildren api.ObjectMap
+	for gvk, objects := range latest.desiredChildMap {
+		strategy := pc.updateStrategy.get(gvk.Group, gvk.Kind)
+		if !isRollingStrategy(strategy) {
+			continue
+		}
+		for name, desired := range objects {
+			child := observedChildren.FindGroupKindName(gvk.GroupKind(), name)
+			if child == nil {
+				continue
+			}
+			client, err := pc.dynClient.Kind(child.GetAPIVersion(), child.GetKind())
+			if err != nil {
+				return nil, err
+			}
+			var observed *unstructured.Unstructured
+			if stripped.StripsWithin("status") && hasStatusChecks(strategy) {
+				observed, err = common.GetLive(ctx, client, child)
+			} else {
+				encoding := pc.applyOptions.GetApplyOptions(gvk.Group, gvk.Kind).LastAppliedEncoding
+				observed, _, err = common.ObservedForApply(ctx, client, stripped, child, desired, encoding)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if observed == nil || observed == child {
+				// The child is gone or was replaced, or the cached one will do.
+				continue
+			}
+			if rollingChildren == nil {
+				rollingChildren = commonv2.MakeUniformObjectMap(parent, observedChildren.List())
+			}
+			rollingChildren.ReplaceObjectIfExists(parent, observed)
+		}
+	}
+	if rollingChildren == nil {
+		return observedChildren, nil
+	}
+	return rollingChildren, nil
+}
+
+func hasStatusChecks(strategy *v1alpha1.CompositeControllerChildUpdateStrategy) bool {
+	checks := strategy.StatusChecks
+	return len(checks.Conditions) != 0 || len(checks.Fields) != 0 || checks.Preset != "" ||
+		strategy.Method == v1alpha1.ChildUpdateRollingInPlace
+}
+
+// applyUpdate returns the child as dynamic apply would update it to match
+// the update.
+func (pc *parentController) applyUpdate(child, update *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	var encoding dynamicapply.LastAppliedEncoding
+	if pc.applyOptions != nil {
+		encoding = pc.applyOptions.GetApplyOptions(child.GroupVersionKind().Group, child.GetKind()).LastAppliedEncoding
+	}
+	return common.ApplyUpdate(child, update, pc.resources.GetSchema(child.GetAPIVersion(), child.GetKind()), encoding)
+}
+
+func makeApplyOptionsMap(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CompositeController, global *common.ApplyOptions, eventRecorder record.EventRecorder) (*common.ApplyOptionsMap, error) {
+	m, err := common.NewApplyOptionsMap(global, cc.Spec.ApplyStrategy)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range cc.Spec.ChildResources {
+		if child.ApplyStrategy == nil && !child.ManageStatus {
+			continue
+		}
+		// Map resource name to kind name.
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		// Ignore API version.
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := m.Set(apiGroup, resource.Kind, child.ApplyStrategy); err != nil {
+			return nil, fmt.Errorf("invalid apply strategy for child resource %q in %v: %w", child.Resource, child.APIVersion, err)
+		}
+		if child.ManageStatus {
+			// The status is compared with the observed children, which these
+			// cache modes observe without status.
+			if child.CacheMode == v1alpha1.CacheModeMetadata || child.CacheMode == v1alpha1.CacheModeMetadataOnly {
+				return nil, fmt.Errorf("child resource %q in %v: manageStatus requires cache mode %q, got %q", child.Resource, child.APIVersion, v1alpha1.CacheModeFull, child.CacheMode)
+			}
+			m.SetManageStatus(apiGroup, resource.Kind)
+		}
+	}
+	redactor, err := redact.New(cc.Spec.RedactionRules)
+	if err != nil {
+		return nil, err
+	}
+	m.SetRedactor(redactor)
+	m.SetEventRecorder(eventRecorder)
+	m.SetAudit(global.Audit.ForController("CompositeController", cc.Name, redactor))
+	if cc.Spec.DryRun || global.DryRun.Enabled() {
+		m.SetDryRun(common.NewDryRun("CompositeController", cc.Name, eventRecorder, redactor))
+		// Nothing is written in dry-run mode, so there's nothing to audit.
+		m.SetAudit(nil)
+	}
+	return m, nil
+}
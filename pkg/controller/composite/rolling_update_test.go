@@ -0,0 +1,249 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	commonv1 "metacontroller/pkg/controller/common/api/v1"
+	v1 "metacontroller/pkg/controller/composite/api/v1"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func newRollingPod(name, image string, ready bool) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"image": image},
+	}}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace("default")
+	pod.SetName(name)
+	if ready {
+		pod.Object["status"] = map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+		}
+	}
+	return pod
+}
+
+func newRollingParent() *unstructured.Unstructured {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("default")
+	parent.SetName("parent")
+	return parent
+}
+
+// newRollout returns the latest and an old revision, where the old revision
+// claims all the given pods and the latest revision wants to update them.
+func newRollout(names ...string) []*parentRevision {
+	parent := newRollingParent()
+	desired := make([]*unstructured.Unstructured, 0, len(names))
+	for _, name := range names {
+		desired = append(desired, newRollingPod(name, "new", false))
+	}
+	latest := &parentRevision{
+		parent:          parent,
+		revision:        newNumberedRevision("latest", 2),
+		syncResult:      &v1.CompositeHookResponse{Status: map[string]interface{}{}, Children: desired},
+		desiredChildMap: commonv1.MakeRelativeObjectMap(parent, desired),
+	}
+	old := &parentRevision{
+		parent:          parent,
+		revision:        newNumberedRevision("old", 1, names...),
+		syncResult:      &v1.CompositeHookResponse{},
+		desiredChildMap: commonv1.MakeRelativeObjectMap(parent, nil),
+	}
+	return []*parentRevision{latest, old}
+}
+
+func newRollingController(rollingUpdate *v1alpha1.ChildRollingUpdate) *parentController {
+	return &parentController{
+		updateStrategy: updateStrategyMap{
+			claimMapKey("", "Pod"): {
+				Method: v1alpha1.ChildUpdateRollingInPlace,
+				StatusChecks: v1alpha1.ChildUpdateStatusChecks{
+					Conditions: []v1alpha1.StatusConditionCheck{{Type: "Ready", Status: ptr.To("True")}},
+				},
+				RollingUpdate: rollingUpdate,
+			},
+		},
+	}
+}
+
+func updatedCondition(t *testing.T, pr *parentRevision) *dynamicobject.StatusCondition {
+	condition, err := dynamicobject.GetStatusCondition(map[string]interface{}{"status": pr.syncResult.Status}, updatedConditionType)
+	assert.NoError(t, err)
+	return condition
+}
+
+func TestSyncRollingUpdate_legacyMovesOneChild(t *testing.T) {
+	pc := newRollingController(nil)
+	parentRevisions := newRollout("pod-0", "pod-1", "pod-2")
+	observed := commonv1.MakeRelativeObjectMap(newRollingParent(), []*unstructured.Unstructured{
+		newRollingPod("pod-0", "old", true),
+		newRollingPod("pod-1", "old", true),
+		newRollingPod("pod-2", "old", true),
+	})
+
+	_, err := pc.syncRollingUpdate(parentRevisions, observed, time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, parentRevisions[0].countChildren())
+	assert.Equal(t, "updating Pod pod-0", updatedCondition(t, parentRevisions[0]).Message)
+}
+
+func TestSyncRollingUpdate_maxUnavailableAndPartition(t *testing.T) {
+	pc := newRollingController(&v1alpha1.ChildRollingUpdate{
+		MaxUnavailable: ptr.To(intstr.FromString("50%")),
+		Partition:      ptr.To(intstr.FromInt32(1)),
+	})
+	parentRevisions := newRollout("pod-0", "pod-1", "pod-2", "pod-3")
+	observed := commonv1.MakeRelativeObjectMap(newRollingParent(), []*unstructured.Unstructured{
+		newRollingPod("pod-0", "old", true),
+		newRollingPod("pod-1", "old", true),
+		newRollingPod("pod-2", "old", true),
+		newRollingPod("pod-3", "old", true),
+	})
+
+	_, err := pc.syncRollingUpdate(parentRevisions, observed, time.Now())
+
+	assert.NoError(t, err)
+	latest, old := parentRevisions[0], parentRevisions[1]
+	assert.Equal(t, []v1alpha1.ControllerRevisionChildren{{Kind: "Pod", Names: []string{"pod-1", "pod-2"}}}, latest.revision.Children)
+	assert.Equal(t, []v1alpha1.ControllerRevisionChildren{{Kind: "Pod", Names: []string{"pod-0", "pod-3"}}}, old.revision.Children)
+	condition := updatedCondition(t, latest)
+	assert.Equal(t, "RolloutProgressing", condition.Reason)
+	assert.Equal(t, "updating Pod pod-1, Pod pod-2", condition.Message)
+}
+
+func TestSyncRollingUpdate_waitsForUnavailableChildren(t *testing.T) {
+	pc := newRollingController(&v1alpha1.ChildRollingUpdate{MaxUnavailable: ptr.To(intstr.FromInt32(2))})
+	parentRevisions := newRollout("pod-0", "pod-1", "pod-2")
+	parentRevisions[0].addChild("", "Pod", "pod-0")
+	parentRevisions[0].addChild("", "Pod", "pod-1")
+	parentRevisions[1].removeChild("", "Pod", "pod-0")
+	parentRevisions[1].removeChild("", "Pod", "pod-1")
+	observed := commonv1.MakeRelativeObjectMap(newRollingParent(), []*unstructured.Unstructured{
+		newRollingPod("pod-0", "new", false),
+		newRollingPod("pod-2", "old", true),
+	})
+
+	_, err := pc.syncRollingUpdate(parentRevisions, observed, time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, parentRevisions[0].countChildren())
+	assert.Equal(t, "RolloutWaiting", updatedCondition(t, parentRevisions[0]).Reason)
+}
+
+func TestSyncRollingUpdate_partitionHoldsBackRollout(t *testing.T) {
+	pc := newRollingController(&v1alpha1.ChildRollingUpdate{Partition: ptr.To(intstr.FromString("100%"))})
+	parentRevisions := newRollout("pod-0", "pod-1")
+	observed := commonv1.MakeRelativeObjectMap(newRollingParent(), []*unstructured.Unstructured{
+		newRollingPod("pod-0", "old", true),
+		newRollingPod("pod-1", "old", true),
+	})
+
+	_, err := pc.syncRollingUpdate(parentRevisions, observed, time.Now())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, parentRevisions[0].countChildren())
+	assert.Equal(t, "RolloutPartitioned", updatedCondition(t, parentRevisions[0]).Reason)
+}
+
+func TestResolveRollingUpdate(t *testing.T) {
+	tests := []struct {
+		name                                            string
+		strategy                                        *v1alpha1.CompositeControllerChildUpdateStrategy
+		total                                           int
+		wantMaxUnavailable, wantMaxSurge, wantPartition int
+	}{
+		{
+			name:               "defaults",
+			strategy:           &v1alpha1.CompositeControllerChildUpdateStrategy{Method: v1alpha1.ChildUpdateRollingInPlace},
+			total:              10,
+			wantMaxUnavailable: 1,
+		},
+		{
+			name: "percentages",
+			strategy: &v1alpha1.CompositeControllerChildUpdateStrategy{
+				Method: v1alpha1.ChildUpdateRollingRecreate,
+				RollingUpdate: &v1alpha1.ChildRollingUpdate{
+					MaxUnavailable: ptr.To(intstr.FromString("25%")),
+					MaxSurge:       ptr.To(intstr.FromString("25%")),
+					Partition:      ptr.To(intstr.FromString("50%")),
+				},
+			},
+			total:              10,
+			wantMaxUnavailable: 2,
+			wantMaxSurge:       3,
+			wantPartition:      5,
+		},
+		{
+			name: "maxSurge is ignored for in-place updates",
+			strategy: &v1alpha1.CompositeControllerChildUpdateStrategy{
+				Method:        v1alpha1.ChildUpdateRollingInPlace,
+				RollingUpdate: &v1alpha1.ChildRollingUpdate{MaxSurge: ptr.To(intstr.FromInt32(3))},
+			},
+			total:              10,
+			wantMaxUnavailable: 1,
+		},
+		{
+			name: "zero maxUnavailable without surge still makes progress",
+			strategy: &v1alpha1.CompositeControllerChildUpdateStrategy{
+				Method:        v1alpha1.ChildUpdateRollingInPlace,
+				RollingUpdate: &v1alpha1.ChildRollingUpdate{MaxUnavailable: ptr.To(intstr.FromString("10%"))},
+			},
+			total:              5,
+			wantMaxUnavailable: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxUnavailable, maxSurge, partition := resolveRollingUpdate(tt.strategy, tt.total)
+			assert.Equal(t, tt.wantMaxUnavailable, maxUnavailable)
+			assert.Equal(t, tt.wantMaxSurge, maxSurge)
+			assert.Equal(t, tt.wantPartition, partition)
+		})
+	}
+}
+
+func TestValidateRollingUpdate(t *testing.T) {
+	assert.NoError(t, validateRollingUpdate(&v1alpha1.CompositeControllerChildUpdateStrategy{
+		Method:        v1alpha1.ChildUpdateRollingRecreate,
+		RollingUpdate: &v1alpha1.ChildRollingUpdate{MaxSurge: ptr.To(intstr.FromInt32(1))},
+	}))
+	assert.Error(t, validateRollingUpdate(&v1alpha1.CompositeControllerChildUpdateStrategy{
+		Method:        v1alpha1.ChildUpdateRollingInPlace,
+		RollingUpdate: &v1alpha1.ChildRollingUpdate{MaxSurge: ptr.To(intstr.FromInt32(1))},
+	}))
+	assert.Error(t, validateRollingUpdate(&v1alpha1.CompositeControllerChildUpdateStrategy{
+		Method:        v1alpha1.ChildUpdateRollingInPlace,
+		RollingUpdate: &v1alpha1.ChildRollingUpdate{MaxUnavailable: ptr.To(intstr.FromString("many"))},
+	}))
+	assert.Error(t, validateRollingUpdate(&v1alpha1.CompositeControllerChildUpdateStrategy{
+		Method:        v1alpha1.ChildUpdateRollingInPlace,
+		RollingUpdate: &v1alpha1.ChildRollingUpdate{Partition: ptr.To(intstr.FromInt32(-1))},
+	}))
+}
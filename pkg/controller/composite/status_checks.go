@@ -0,0 +1,289 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+)
+
+// presetKinds maps each readiness preset to the kind it can check.
+var presetKinds = map[v1alpha1.ReadinessPreset]schema.GroupKind{
+	v1alpha1.ReadinessPresetDeployment:            {Group: "apps", Kind: "Deployment"},
+	v1alpha1.ReadinessPresetStatefulSet:           {Group: "apps", Kind: "StatefulSet"},
+	v1alpha1.ReadinessPresetDaemonSet:             {Group: "apps", Kind: "DaemonSet"},
+	v1alpha1.ReadinessPresetJob:                   {Group: "batch", Kind: "Job"},
+	v1alpha1.ReadinessPresetPersistentVolumeClaim: {Group: "", Kind: "PersistentVolumeClaim"},
+}
+
+// validateStatusChecks checks the field checks and preset of the status checks
+// for children of the given kind.
+func validateStatusChecks(checks *v1alpha1.ChildUpdateStatusChecks, groupKind schema.GroupKind) error {
+	for _, check := range checks.Fields {
+		paths := []string{check.Path}
+		if check.ValuePath != "" {
+			paths = append(paths, check.ValuePath)
+		}
+		for _, path := range paths {
+			if !strings.Contains(path, "{") {
+				return fmt.Errorf("invalid field check path %q: must be a JSONPath template like {.status.readyReplicas}", path)
+			}
+			if err := jsonpath.New("statusCheck").Parse(path); err != nil {
+				return fmt.Errorf("invalid field check path %q: %w", path, err)
+			}
+		}
+		switch check.Operator {
+		case v1alpha1.FieldCheckExists, v1alpha1.FieldCheckDoesNotExist:
+		default:
+			if (check.Value == nil) == (check.ValuePath == "") {
+				return fmt.Errorf("field check for %q with operator %s needs either value or valuePath", check.Path, check.Operator)
+			}
+		}
+	}
+	if checks.Preset != "" {
+		presetKind, ok := presetKinds[checks.Preset]
+		if !ok {
+			return fmt.Errorf("unknown readiness preset %q", checks.Preset)
+		}
+		if presetKind != groupKind {
+			return fmt.Errorf("readiness preset %q can't be used for %v", checks.Preset, groupKind)
+		}
+	}
+	return nil
+}
+
+// evaluateFieldPath renders a JSONPath template against the child. It returns
+// false if the selected field doesn't exist.
+func evaluateFieldPath(path string, child *unstructured.Unstructured) (string, bool, error) {
+	jp := jsonpath.New("statusCheck").AllowMissingKeys(true)
+	if err := jp.Parse(path); err != nil {
+		return "", false, err
+	}
+	buf := &bytes.Buffer{}
+	if err := jp.Execute(buf, child.UnstructuredContent()); err != nil {
+		return "", false, err
+	}
+	value := strings.TrimSpace(buf.String())
+	return value, value != "", nil
+}
+
+func fieldStatusCheck(check *v1alpha1.StatusFieldCheck, child *unstructured.Unstructured) error {
+	actual, found, err := evaluateFieldPath(check.Path, child)
+	if err != nil {
+		return fmt.Errorf("can't evaluate %q: %w", check.Path, err)
+	}
+	switch check.Operator {
+	case v1alpha1.FieldCheckExists:
+		if !found {
+			return fmt.Errorf("field %q is missing", check.Path)
+		}
+		return nil
+	case v1alpha1.FieldCheckDoesNotExist:
+		if found {
+			return fmt.Errorf("field %q is %q (want missing)", check.Path, actual)
+		}
+		return nil
+	}
+
+	var expected string
+	if check.ValuePath != "" {
+		if expected, _, err = evaluateFieldPath(check.ValuePath, child); err != nil {
+			return fmt.Errorf("can't evaluate %q: %w", check.ValuePath, err)
+		}
+	} else if check.Value != nil {
+		expected = *check.Value
+	}
+	ok, err := compareFieldValues(actual, expected, check.Operator)
+	if err != nil {
+		return fmt.Errorf("can't compare field %q: %w", check.Path, err)
+	}
+	if !ok {
+		return fmt.Errorf("field %q is %q (want %s %q)", check.Path, actual, check.Operator, expected)
+	}
+	return nil
+}
+
+// compareFieldValues compares two rendered field values. Values are compared
+// as numbers if both are numeric, and as strings otherwise. Ordering
+// operators are only supported for numbers.
+func compareFieldValues(actual, expected string, operator v1alpha1.FieldCheckOperator) (bool, error) {
+	actualNumber, actualErr := strconv.ParseFloat(actual, 64)
+	expectedNumber, expectedErr := strconv.ParseFloat(expected, 64)
+	numeric := actualErr == nil && expectedErr == nil
+
+	switch operator {
+	case v1alpha1.FieldCheckEqual:
+		if numeric {
+			return actualNumber == expectedNumber, nil
+		}
+		return actual == expected, nil
+	case v1alpha1.FieldCheckNotEqual:
+		if numeric {
+			return actualNumber != expectedNumber, nil
+		}
+		return actual != expected, nil
+	}
+	if !numeric {
+		return false, fmt.Errorf("operator %s needs numeric values, got %q and %q", operator, actual, expected)
+	}
+	switch operator {
+	case v1alpha1.FieldCheckGreaterThan:
+		return actualNumber > expectedNumber, nil
+	case v1alpha1.FieldCheckGreaterThanOrEqual:
+		return actualNumber >= expectedNumber, nil
+	case v1alpha1.FieldCheckLessThan:
+		return actualNumber < expectedNumber, nil
+	case v1alpha1.FieldCheckLessThanOrEqual:
+		return actualNumber <= expectedNumber, nil
+	default:
+		return false, fmt.Errorf("unknown operator %q", operator)
+	}
+}
+
+// presetStatusCheck checks the readiness of a built-in kind, following the
+// same rules as `kubectl rollout status`.
+func presetStatusCheck(preset v1alpha1.ReadinessPreset, child *unstructured.Unstructured) error {
+	switch preset {
+	case v1alpha1.ReadinessPresetDeployment:
+		return deploymentReadiness(child)
+	case v1alpha1.ReadinessPresetStatefulSet:
+		return statefulSetReadiness(child)
+	case v1alpha1.ReadinessPresetDaemonSet:
+		return daemonSetReadiness(child)
+	case v1alpha1.ReadinessPresetJob:
+		return jobReadiness(child)
+	case v1alpha1.ReadinessPresetPersistentVolumeClaim:
+		return persistentVolumeClaimReadiness(child)
+	default:
+		return fmt.Errorf("unknown readiness preset %q", preset)
+	}
+}
+
+func observedGenerationCheck(child *unstructured.Unstructured) error {
+	observedGeneration, _, err := dynamicobject.GetObservedGeneration(child.UnstructuredContent())
+	if err != nil {
+		return err
+	}
+	if observedGeneration < child.GetGeneration() {
+		return fmt.Errorf("observed generation %d is older than generation %d", observedGeneration, child.GetGeneration())
+	}
+	return nil
+}
+
+// nestedInt64 returns the integer at the given path, or the default if the
+// field is missing.
+func nestedInt64(child *unstructured.Unstructured, defaultValue int64, fields ...string) int64 {
+	value, found, err := unstructured.NestedInt64(child.UnstructuredContent(), fields...)
+	if !found || err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func deploymentReadiness(child *unstructured.Unstructured) error {
+	if err := observedGenerationCheck(child); err != nil {
+		return err
+	}
+	if cond, _ := dynamicobject.GetStatusCondition(child.UnstructuredContent(), "Progressing"); cond != nil && cond.Reason == "ProgressDeadlineExceeded" {
+		return fmt.Errorf("deployment exceeded its progress deadline")
+	}
+	replicas := nestedInt64(child, 1, "spec", "replicas")
+	updated := nestedInt64(child, 0, "status", "updatedReplicas")
+	total := nestedInt64(child, 0, "status", "replicas")
+	available := nestedInt64(child, 0, "status", "availableReplicas")
+	switch {
+	case updated < replicas:
+		return fmt.Errorf("%d of %d replicas updated", updated, replicas)
+	case total > updated:
+		return fmt.Errorf("%d old replicas pending termination", total-updated)
+	case available < updated:
+		return fmt.Errorf("%d of %d updated replicas available", available, updated)
+	}
+	return nil
+}
+
+func statefulSetReadiness(child *unstructured.Unstructured) error {
+	if err := observedGenerationCheck(child); err != nil {
+		return err
+	}
+	replicas := nestedInt64(child, 1, "spec", "replicas")
+	ready := nestedInt64(child, 0, "status", "readyReplicas")
+	if ready < replicas {
+		return fmt.Errorf("%d of %d replicas ready", ready, replicas)
+	}
+	strategyType, _, _ := unstructured.NestedString(child.UnstructuredContent(), "spec", "updateStrategy", "type")
+	if strategyType == "OnDelete" {
+		// Pods are only updated when deleted, so there's no rollout to wait for.
+		return nil
+	}
+	if partition := nestedInt64(child, 0, "spec", "updateStrategy", "rollingUpdate", "partition"); partition > 0 {
+		updated := nestedInt64(child, 0, "status", "updatedReplicas")
+		if updated < replicas-partition {
+			return fmt.Errorf("%d of %d replicas above partition updated", updated, replicas-partition)
+		}
+		return nil
+	}
+	currentRevision, _, _ := unstructured.NestedString(child.UnstructuredContent(), "status", "currentRevision")
+	updateRevision, _, _ := unstructured.NestedString(child.UnstructuredContent(), "status", "updateRevision")
+	if currentRevision != updateRevision {
+		return fmt.Errorf("waiting for update to revision %q", updateRevision)
+	}
+	return nil
+}
+
+func daemonSetReadiness(child *unstructured.Unstructured) error {
+	if err := observedGenerationCheck(child); err != nil {
+		return err
+	}
+	desired := nestedInt64(child, 0, "status", "desiredNumberScheduled")
+	updated := nestedInt64(child, 0, "status", "updatedNumberScheduled")
+	available := nestedInt64(child, 0, "status", "numberAvailable")
+	switch {
+	case updated < desired:
+		return fmt.Errorf("%d of %d pods updated", updated, desired)
+	case available < desired:
+		return fmt.Errorf("%d of %d pods available", available, desired)
+	}
+	return nil
+}
+
+func jobReadiness(child *unstructured.Unstructured) error {
+	if cond, _ := dynamicobject.GetStatusCondition(child.UnstructuredContent(), "Failed"); cond != nil && cond.Status == "True" {
+		return fmt.Errorf("job failed: %s", cond.Message)
+	}
+	if cond, _ := dynamicobject.GetStatusCondition(child.UnstructuredContent(), "Complete"); cond != nil && cond.Status == "True" {
+		return nil
+	}
+	return fmt.Errorf("job has not completed")
+}
+
+func persistentVolumeClaimReadiness(child *unstructured.Unstructured) error {
+	phase, _, _ := unstructured.NestedString(child.UnstructuredContent(), "status", "phase")
+	if phase != "Bound" {
+		return fmt.Errorf("phase is %q (want %q)", phase, "Bound")
+	}
+	return nil
+}
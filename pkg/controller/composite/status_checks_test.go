@@ -0,0 +1,211 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+)
+
+func newStatusCheckChild(spec, status map[string]interface{}) *unstructured.Unstructured {
+	child := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   spec,
+		"status": status,
+	}}
+	child.SetGeneration(2)
+	return child
+}
+
+func TestChildStatusCheck_fields(t *testing.T) {
+	child := newStatusCheckChild(
+		map[string]interface{}{"replicas": int64(3)},
+		map[string]interface{}{
+			"readyReplicas": int64(3),
+			"loadBalancer":  map[string]interface{}{"ingress": []interface{}{map[string]interface{}{"ip": "10.0.0.1"}}},
+		},
+	)
+	tests := []struct {
+		name    string
+		check   v1alpha1.StatusFieldCheck
+		wantErr bool
+	}{
+		{
+			name:  "equal to other field",
+			check: v1alpha1.StatusFieldCheck{Path: "{.status.readyReplicas}", Operator: v1alpha1.FieldCheckEqual, ValuePath: "{.spec.replicas}"},
+		},
+		{
+			name:  "greater than or equal to literal",
+			check: v1alpha1.StatusFieldCheck{Path: "{.status.readyReplicas}", Operator: v1alpha1.FieldCheckGreaterThanOrEqual, Value: ptr.To("3.0")},
+		},
+		{
+			name:    "less than literal",
+			check:   v1alpha1.StatusFieldCheck{Path: "{.status.readyReplicas}", Operator: v1alpha1.FieldCheckLessThan, Value: ptr.To("3")},
+			wantErr: true,
+		},
+		{
+			name:  "exists",
+			check: v1alpha1.StatusFieldCheck{Path: "{.status.loadBalancer.ingress[0].ip}", Operator: v1alpha1.FieldCheckExists},
+		},
+		{
+			name:    "missing field does not exist",
+			check:   v1alpha1.StatusFieldCheck{Path: "{.status.loadBalancer.ingress[0].hostname}", Operator: v1alpha1.FieldCheckExists},
+			wantErr: true,
+		},
+		{
+			name:  "string not equal",
+			check: v1alpha1.StatusFieldCheck{Path: "{.status.phase}", Operator: v1alpha1.FieldCheckNotEqual, Value: ptr.To("Failed")},
+		},
+		{
+			name:    "ordering of strings",
+			check:   v1alpha1.StatusFieldCheck{Path: "{.status.phase}", Operator: v1alpha1.FieldCheckGreaterThan, Value: ptr.To("a")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := childStatusCheck(&v1alpha1.ChildUpdateStatusChecks{Fields: []v1alpha1.StatusFieldCheck{tt.check}}, child)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPresetStatusCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		preset  v1alpha1.ReadinessPreset
+		child   *unstructured.Unstructured
+		wantErr bool
+	}{
+		{
+			name:   "deployment rolled out",
+			preset: v1alpha1.ReadinessPresetDeployment,
+			child: newStatusCheckChild(map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2),
+			}),
+		},
+		{
+			name:   "deployment with old replicas",
+			preset: v1alpha1.ReadinessPresetDeployment,
+			child: newStatusCheckChild(map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "replicas": int64(3), "updatedReplicas": int64(2), "availableReplicas": int64(2),
+			}),
+			wantErr: true,
+		},
+		{
+			name:   "deployment with outdated status",
+			preset: v1alpha1.ReadinessPresetDeployment,
+			child: newStatusCheckChild(map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2),
+			}),
+			wantErr: true,
+		},
+		{
+			name:   "statefulset on update revision",
+			preset: v1alpha1.ReadinessPresetStatefulSet,
+			child: newStatusCheckChild(map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "readyReplicas": int64(2), "currentRevision": "b", "updateRevision": "b",
+			}),
+		},
+		{
+			name:   "statefulset waiting for revision",
+			preset: v1alpha1.ReadinessPresetStatefulSet,
+			child: newStatusCheckChild(map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "readyReplicas": int64(2), "currentRevision": "a", "updateRevision": "b",
+			}),
+			wantErr: true,
+		},
+		{
+			name:   "statefulset with partition",
+			preset: v1alpha1.ReadinessPresetStatefulSet,
+			child: newStatusCheckChild(map[string]interface{}{
+				"replicas":       int64(3),
+				"updateStrategy": map[string]interface{}{"type": "RollingUpdate", "rollingUpdate": map[string]interface{}{"partition": int64(2)}},
+			}, map[string]interface{}{
+				"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(1), "currentRevision": "a", "updateRevision": "b",
+			}),
+		},
+		{
+			name:   "daemonset not available",
+			preset: v1alpha1.ReadinessPresetDaemonSet,
+			child: newStatusCheckChild(nil, map[string]interface{}{
+				"observedGeneration": int64(2), "desiredNumberScheduled": int64(3), "updatedNumberScheduled": int64(3), "numberAvailable": int64(2),
+			}),
+			wantErr: true,
+		},
+		{
+			name:   "job complete",
+			preset: v1alpha1.ReadinessPresetJob,
+			child: newStatusCheckChild(nil, map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}},
+			}),
+		},
+		{
+			name:    "job running",
+			preset:  v1alpha1.ReadinessPresetJob,
+			child:   newStatusCheckChild(nil, map[string]interface{}{"active": int64(1)}),
+			wantErr: true,
+		},
+		{
+			name:   "pvc bound",
+			preset: v1alpha1.ReadinessPresetPersistentVolumeClaim,
+			child:  newStatusCheckChild(nil, map[string]interface{}{"phase": "Bound"}),
+		},
+		{
+			name:    "pvc pending",
+			preset:  v1alpha1.ReadinessPresetPersistentVolumeClaim,
+			child:   newStatusCheckChild(nil, map[string]interface{}{"phase": "Pending"}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := childStatusCheck(&v1alpha1.ChildUpdateStatusChecks{Preset: tt.preset}, tt.child)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateStatusChecks(t *testing.T) {
+	deployment := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+
+	assert.NoError(t, validateStatusChecks(&v1alpha1.ChildUpdateStatusChecks{
+		Fields: []v1alpha1.StatusFieldCheck{{Path: "{.status.readyReplicas}", Operator: v1alpha1.FieldCheckEqual, ValuePath: "{.spec.replicas}"}},
+		Preset: v1alpha1.ReadinessPresetDeployment,
+	}, deployment))
+	assert.Error(t, validateStatusChecks(&v1alpha1.ChildUpdateStatusChecks{
+		Fields: []v1alpha1.StatusFieldCheck{{Path: ".status.readyReplicas", Operator: v1alpha1.FieldCheckExists}},
+	}, deployment))
+	assert.Error(t, validateStatusChecks(&v1alpha1.ChildUpdateStatusChecks{
+		Fields: []v1alpha1.StatusFieldCheck{{Path: "{.status.readyReplicas}", Operator: v1alpha1.FieldCheckEqual}},
+	}, deployment))
+	assert.Error(t, validateStatusChecks(&v1alpha1.ChildUpdateStatusChecks{
+		Preset: v1alpha1.ReadinessPresetJob,
+	}, deployment))
+}
@@ -25,9 +25,13 @@ import (
 	"sync"
 	"time"
 
+	"metacontroller/pkg/audit"
 	"metacontroller/pkg/controller/common/api"
 	commonv2 "metacontroller/pkg/controller/common/api/v2"
+	v1 "metacontroller/pkg/controller/decorator/api/v1"
 	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -59,6 +63,7 @@ import (
 	"metacontroller/pkg/controller/common/finalizer"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	"metacontroller/pkg/tracing"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -80,13 +85,16 @@ type decoratorController struct {
 	doneCh   chan struct{}
 	stopOnce sync.Once
 	queue    workqueue.TypedRateLimitingInterface[string]
+	// queueProgress tracks the progress of the queue while the controller runs.
+	queueProgress *common.QueueProgress
+	metrics       *common.ControllerMetrics
 
 	updateStrategy updateStrategyMap
 
 	parentInformers *common.InformerMap
 	childInformers  *common.InformerMap
 
-	ssaOptions *common.ApplyOptions
+	applyOptions *common.ApplyOptionsMap
 
 	numWorkers    int
 	eventRecorder record.EventRecorder
@@ -116,6 +124,14 @@ func newDecoratorController(
 	if dc.Spec.Hooks == nil {
 		return nil, fmt.Errorf("no hooks defined")
 	}
+	logger = logger.WithName(dc.Name)
+	if dc.Spec.LogLevel != "" {
+		level, err := logging.ParseLevel(dc.Spec.LogLevel)
+		if err != nil {
+			return nil, err
+		}
+		logger = logging.WithLevel(logger, level)
+	}
 	syncCfg, err := hooks.ResolveEndpointConfig(ctx, k8sClient, syncWebhook(dc), dc.GetEndpointConfigs())
 	if err != nil {
 		return nil, fmt.Errorf("can't resolve endpoint config for sync hook: %w", err)
@@ -140,23 +156,15 @@ func newDecoratorController(
 		parentKinds:     common.NewGroupKindMap(),
 		parentInformers: common.NewInformerMap(),
 		childInformers:  common.NewInformerMap(),
-		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
-			workqueue.DefaultTypedControllerRateLimiter[string](),
-			workqueue.TypedRateLimitingQueueConfig[string]{
-				Name: common.DecoratorController.String() + "-" + dc.Name,
-			},
-		),
-		numWorkers:    numWorkers,
-		eventRecorder: eventRecorder,
-		finalizer: finalizer.NewManager(
-			"metacontroller.io/decoratorcontroller-"+dc.Name,
-			dc.Spec.Hooks.Finalize != nil,
-		),
-		syncHook:     syncHook,
-		finalizeHook: finalizeHook,
-		logger:       logger.WithName(dc.Name),
-		ssaOptions:   ssaOptions,
-		ctx:          ctx,
+		queue:           common.NewQueue(common.DecoratorController.String() + "-" + dc.Name),
+		metrics:         common.NewControllerMetrics(common.DecoratorController, dc.Name),
+		numWorkers:      numWorkers,
+		eventRecorder:   eventRecorder,
+		finalizer:       newFinalizerManager(dc),
+		syncHook:        syncHook,
+		finalizeHook:    finalizeHook,
+		logger:          logger,
+		ctx:             ctx,
 	}
 
 	customize, err := customize.NewCustomizeManager(
@@ -196,6 +204,11 @@ func newDecoratorController(
 	if err != nil {
 		return nil, err
 	}
+	c.applyOptions, err = makeApplyOptionsMap(resources, dc, ssaOptions, eventRecorder)
+	if err != nil {
+		return nil, err
+	}
+	c.applyOptions.SetMetrics(c.metrics)
 
 	// Create informers for all parent and child resources.
 	defer func() {
@@ -224,7 +237,14 @@ func newDecoratorController(
 	}
 
 	for _, child := range dc.Spec.Attachments {
-		informer, err := dynInformers.Resource(ctx, child.APIVersion, child.Resource)
+		var method v1alpha1.ChildUpdateMethod
+		if child.UpdateStrategy != nil {
+			method = child.UpdateStrategy.Method
+		}
+		if err := common.ValidateChildCacheMode(child.CacheMode, method); err != nil {
+			return nil, fmt.Errorf("invalid cache mode for child resource %q in %v: %w", child.Resource, child.APIVersion, err)
+		}
+		informer, err := common.NewResourceInformer(ctx, dynInformers, child.APIVersion, child.Resource, child.CacheMode)
 		if err != nil {
 			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
 		}
@@ -257,6 +277,7 @@ func finalizeWebhook(dc *v1alpha1.DecoratorController) *v1alpha1.Webhook {
 func (c *decoratorController) Start() {
 	c.ctx, c.cancel = context.WithCancel(c.ctx)
 	c.doneCh = make(chan struct{})
+	c.queueProgress = common.TrackQueue(common.DecoratorController.String()+"-"+c.dc.Name, c.queue)
 
 	c.customize.Start(c.ctx)
 
@@ -337,11 +358,27 @@ func (c *decoratorController) Start() {
 	}()
 }
 
+// status returns the state of the controller, for introspection.
+func (c *decoratorController) status() common.ControllerStatus {
+	status := common.ControllerStatus{
+		Kind:               common.DecoratorController.String(),
+		Name:               c.dc.Name,
+		Generation:         c.dc.Generation,
+		CustomizeCacheSize: c.customize.CacheSize(),
+		ETagCacheSize: hooks.CacheSize(c.syncHook) +
+			hooks.CacheSize(c.finalizeHook) +
+			c.customize.ETagCacheSize(),
+	}
+	status.SetQueue(c.queue)
+	return status
+}
+
 func (c *decoratorController) Stop() {
 	c.stopOnce.Do(func() {
 		c.cancel()
 		c.queue.ShutDown()
 		<-c.doneCh
+		c.queueProgress.Untrack()
 
 		// Remove event handlers and close informers for all child resources.
 		c.childInformers.ForEach(func(_ schema.GroupVersionResource, informer *dynamicinformer.ResourceInformer) {
@@ -354,6 +391,7 @@ func (c *decoratorController) Stop() {
 			informer.Close()
 		})
 		c.customize.Stop()
+		c.metrics.Delete()
 	})
 }
 
@@ -368,8 +406,16 @@ func (c *decoratorController) processNextWorkItem(ctx context.Context) bool {
 		return false
 	}
 	defer c.queue.Done(key)
-
-	if err := c.sync(ctx, key); err != nil {
+	defer c.queueProgress.Progress()
+
+	start := time.Now()
+	// Hooks log with the logger and the redactor of the controller.
+	ctx = redact.NewContext(logging.NewContext(ctx, c.logger), c.applyOptions.Redactor())
+	ctx, span := tracing.StartSync(audit.WithSync(ctx), common.DecoratorController.String(), c.dc.Name, key)
+	err := c.sync(ctx, key)
+	tracing.End(span, err)
+	c.metrics.ObserveSync(start, err)
+	if err != nil {
 		var tooManyRequestError *hooks.TooManyRequestError
 		if errors.As(err, &tooManyRequestError) {
 			c.queue.AddAfter(key, time.Duration(tooManyRequestError.AfterSecond)*time.Second)
@@ -588,6 +634,7 @@ func (c *decoratorController) sync(ctx context.Context, key string) error {
 		if apierrors.IsNotFound(err) {
 			// Swallow the error since there's no point retrying if the parent is gone.
 			c.logger.V(4).Info("Parent object has been deleted", "kind", kind, "object", klog.KRef(namespace, name))
+			c.metrics.ForgetParent(key)
 			return nil
 		} else {
 			return err
@@ -617,48 +664,80 @@ func (c *decoratorController) syncParentObject(ctx context.Context, parent *unst
 		return err
 	}
 
+	parent, ok, err := c.syncParentFinalizer(ctx, parent)
+	if err != nil || !ok {
+		return err
+	}
+	parentKey, _ := parentQueueKey(parent)
+
+	// List all children belonging to this parent, of the kinds we care about.
+	// This only lists the children we created. Existing children are ignored.
+	observedChildren, err := c.getChildren(ctx, parent)
+	if err != nil {
+		return err
+	}
+	c.metrics.ObserveChildren(parentKey, observedChildren)
+
+	start := time.Now()
+	relatedObjects, err := c.customize.GetRelatedObjects(ctx, parent)
+	if err = c.metrics.ObservePhase(common.SyncPhaseHook, start, err); err != nil {
+		return err
+	}
+
+	_, err = c.syncObservedParent(ctx, parent, observedChildren, relatedObjects)
+	return err
+}
+
+// syncParentFinalizer adds or removes our finalizer on the parent as
+// necessary. It returns the parent as it's left, and false if the controller
+// doesn't handle the parent, in which case the sync stops there.
+func (c *decoratorController) syncParentFinalizer(ctx context.Context, parent *unstructured.Unstructured) (*unstructured.Unstructured, bool, error) {
 	// If it doesn't match our selector, and it doesn't have our finalizer, ignore it.
+	parentKey, _ := parentQueueKey(parent)
 	if !c.parentSelector.Matches(parent) && !controllerutil.ContainsFinalizer(parent, c.finalizer.Name) {
-		return nil
+		c.metrics.ForgetParent(parentKey)
+		return parent, false, nil
 	}
 
 	c.logger.V(4).Info("DecoratorController sync", "controller", c.dc, "parent", parent)
 
 	parentClient, err := c.dynClient.Kind(parent.GetAPIVersion(), parent.GetKind())
 	if err != nil {
-		return fmt.Errorf("can't get client for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+		return parent, false, fmt.Errorf("can't get client for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 	}
 
 	// Before taking any other action, add our finalizer (if desired).
 	// This ensures we have a chance to clean up after any action we later take.
-	updatedParent, err := c.finalizer.SyncObject(ctx, parentClient, parent)
+	updatedParent, err := c.syncFinalizer(ctx, parentClient, parent)
 	if err != nil {
 		// If we fail to do this, abort before doing anything else and requeue.
-		return fmt.Errorf("can't sync finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+		return parent, false, fmt.Errorf("can't sync finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 	}
 	parent = updatedParent
 
 	// Check the finalizer again in case we just removed it.
 	if !c.parentSelector.Matches(parent) && !controllerutil.ContainsFinalizer(parent, c.finalizer.Name) {
-		return nil
-	}
-
-	// List all children belonging to this parent, of the kinds we care about.
-	// This only lists the children we created. Existing children are ignored.
-	observedChildren, err := c.getChildren(parent)
-	if err != nil {
-		return err
+		c.metrics.ForgetParent(parentKey)
+		return parent, false, nil
 	}
+	return parent, true, nil
+}
 
-	relatedObjects, err := c.customize.GetRelatedObjects(ctx, parent)
+// syncObservedParent syncs the parent against its attachments and related
+// objects: it calls the hooks, updates the labels, annotations and status of
+// the parent and reconciles the attachments. It returns the desired
+// attachments.
+func (c *decoratorController) syncObservedParent(ctx context.Context, parent *unstructured.Unstructured, observedChildren, relatedObjects api.ObjectMap) ([]*unstructured.Unstructured, error) {
+	parentClient, err := c.dynClient.Kind(parent.GetAPIVersion(), parent.GetKind())
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("can't get client for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 	}
 
 	// Call the sync hook to get the desired annotations and children.
+	start := time.Now()
 	syncResult, err := c.callHook(ctx, parent, observedChildren, relatedObjects)
-	if err != nil {
-		return err
+	if err = c.metrics.ObservePhase(common.SyncPhaseHook, start, err); err != nil {
+		return nil, err
 	}
 	desiredChildren := commonv2.MakeUniformObjectMap(parent, syncResult.Attachments)
 
@@ -670,56 +749,66 @@ func (c *decoratorController) syncParentObject(ctx context.Context, parent *unst
 	// Set desired labels and annotations on parent.
 	// Also remove finalizer if requested.
 	// Make a copy since parent is from the cache.
-	updatedParent = parent.DeepCopy()
-	parentLabels := updatedParent.GetLabels()
-	if parentLabels == nil {
-		parentLabels = make(map[string]string)
-	}
-	parentAnnotations := updatedParent.GetAnnotations()
-	if parentAnnotations == nil {
-		parentAnnotations = make(map[string]string)
-	}
-	parentStatus, _, err := unstructured.NestedMap(updatedParent.Object, "status")
+	updatedParent := parent.DeepCopy()
+	update, err := newParentUpdate(updatedParent, syncResult)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if syncResult.Status == nil {
-		// A null .status in the sync response means leave it unchanged.
-		syncResult.Status = parentStatus
+	finalize := syncResult.Finalized && controllerutil.ContainsFinalizer(parent, c.finalizer.Name)
+	if stripped := c.applyOptions.Stripped(); stripped.Lossy() && (update.changed() || finalize || dependsOnStripped(stripped, syncResult)) {
+		// The comparison depends on data that the cache drops, or the update
+		// below would remove it, so start from the live parent instead.
+		updatedParent, err = common.GetLive(ctx, parentClient, parent)
+		if err != nil {
+			return nil, err
+		}
+		if updatedParent == nil {
+			// The parent is gone or was replaced; wait for the informer.
+			return nil, nil
+		}
+		if update, err = newParentUpdate(updatedParent, syncResult); err != nil {
+			return nil, err
+		}
 	}
 
-	labelsChanged := updateStringMap(parentLabels, syncResult.Labels)
-	annotationsChanged := updateStringMap(parentAnnotations, syncResult.Annotations)
-	statusChanged := !common.DeepEqual(parentStatus, syncResult.Status)
-
 	// Only do the update if something changed.
-	if labelsChanged || annotationsChanged || statusChanged ||
-		(syncResult.Finalized && controllerutil.ContainsFinalizer(parent, c.finalizer.Name)) {
-		updatedParent.SetLabels(parentLabels)
-		updatedParent.SetAnnotations(parentAnnotations)
-		if err := unstructured.SetNestedField(updatedParent.Object, syncResult.Status, "status"); err != nil {
-			return err
+	start = time.Now()
+	if update.changed() || finalize {
+		// The parent before the update, for the audit log.
+		original := updatedParent.DeepCopy()
+		updatedParent.SetLabels(update.labels)
+		updatedParent.SetAnnotations(update.annotations)
+		if err := unstructured.SetNestedField(updatedParent.Object, update.status, "status"); err != nil {
+			return nil, err
 		}
 
-		if statusChanged && parentClient.HasSubresource("status") {
+		if update.statusChanged && parentClient.HasSubresource("status") {
 			// The regular Update below will ignore changes to .status so we do it separately.
-			result, err := parentClient.Namespace(parent.GetNamespace()).UpdateStatus(ctx, updatedParent, metav1.UpdateOptions{})
+			statusCtx, span := tracing.Start(ctx, "update parent status", tracing.Object("parent", parent)...)
+			result, err := parentClient.Namespace(parent.GetNamespace()).UpdateStatus(statusCtx, updatedParent, metav1.UpdateOptions{DryRun: c.applyOptions.DryRun().Options()})
+			tracing.End(span, err)
 			if err != nil {
 				switch {
 				case apierrors.IsNotFound(err):
 					// Swallow the error since there's no point retrying if the child is gone.
 					c.logger.V(4).Info("DecoratorController Failed to sync status, parent object has been deleted", "controller", c.dc, "parent", parent)
-					return nil
+					return syncResult.Attachments, nil
 				case apierrors.IsConflict(err):
 					// it is possible that the object was modified after this sync was started, ignore conflict since we will reconcile again
 					c.logger.V(4).Info("DecoratorController ignoring update status due to outdated resourceVersion", "controller", c.dc, "parent", parent)
-					return nil
+					return syncResult.Attachments, nil
 				default:
-					return fmt.Errorf("can't update status: %w", err)
+					return nil, c.metrics.ObservePhase(common.SyncPhaseStatus, start, fmt.Errorf("can't update status: %w", err))
 				}
 			}
 			// The Update below needs to use the latest ResourceVersion.
 			updatedParent.SetResourceVersion(result.GetResourceVersion())
+			if auditor := c.applyOptions.Audit(); auditor != nil {
+				statusUpdated := original.DeepCopy()
+				statusUpdated.Object["status"] = updatedParent.Object["status"]
+				auditor.Record(ctx, parent, common.AuditUpdateStatus, original, statusUpdated)
+				original = statusUpdated
+			}
 		}
 
 		if syncResult.Finalized {
@@ -727,36 +816,27 @@ func (c *decoratorController) syncParentObject(ctx context.Context, parent *unst
 		}
 
 		c.logger.V(4).Info("DecoratorController updating", "controller", c.dc, "parent", parent)
-		_, err = parentClient.Namespace(parent.GetNamespace()).Update(ctx, updatedParent, metav1.UpdateOptions{})
+		updateCtx, span := tracing.Start(ctx, "update parent", tracing.Object("parent", parent)...)
+		_, err = parentClient.Namespace(parent.GetNamespace()).Update(updateCtx, updatedParent, metav1.UpdateOptions{DryRun: c.applyOptions.DryRun().Options()})
+		tracing.End(span, err)
 		if err != nil {
 			if apierrors.IsNotFound(err) {
 				// Swallow the error since there's no point retrying if the parent is gone.
 				c.logger.V(4).Info("DecoratorController Failed to sync, parent object has been deleted", "controller", c.dc, "parent", parent)
-				return nil
+				return syncResult.Attachments, nil
 			} else if apierrors.IsConflict(err) {
 				// it is possible that the object was modified after this sync was started, ignore conflict since we will reconcile again
 				c.logger.V(4).Info("DecoratorController ignoring update due to outdated resourceVersion", "controller", c.dc, "parent", parent)
-				return nil
+				return syncResult.Attachments, nil
 			}
-			return fmt.Errorf("can't update %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+			return nil, c.metrics.ObservePhase(common.SyncPhaseStatus, start, fmt.Errorf("can't update %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err))
 		}
+		c.applyOptions.DryRun().Report(parent, common.DryRunUpdate, parent, updatedParent)
+		c.applyOptions.Audit().Record(ctx, parent, common.AuditUpdate, original, updatedParent)
+		_ = c.metrics.ObservePhase(common.SyncPhaseStatus, start, nil)
 	}
 
-	// Add an annotation to all desired children to remember that they were
-	// created by this decorator.
-	for _, group := range desiredChildren {
-		for _, child := range group {
-			ann := child.GetAnnotations()
-			if ann[decoratorControllerAnnotation] == c.dc.Name {
-				continue
-			}
-			if ann == nil {
-				ann = make(map[string]string)
-			}
-			ann[decoratorControllerAnnotation] = c.dc.Name
-			child.SetAnnotations(ann)
-		}
-	}
+	c.annotateChildren(desiredChildren)
 
 	// Reconcile child objects belonging to this parent.
 	// Remember manage error, but continue to update status regardless.
@@ -766,15 +846,35 @@ func (c *decoratorController) syncParentObject(ctx context.Context, parent *unst
 	var manageErr error
 	if parent.GetDeletionTimestamp() == nil || c.finalizer.ShouldFinalize(parent) {
 		// Reconcile children.
-		if err := common.ManageChildren(ctx, c.dynClient, c.updateStrategy, parent, observedChildren, desiredChildren, c.ssaOptions); err != nil {
+		start := time.Now()
+		err := common.ManageChildren(ctx, c.dynClient, c.updateStrategy, parent, observedChildren, desiredChildren, c.applyOptions)
+		if err = c.metrics.ObservePhase(common.SyncPhaseApply, start, err); err != nil {
 			manageErr = fmt.Errorf("can't reconcile children for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 		}
 	}
 
-	return manageErr
+	return syncResult.Attachments, manageErr
 }
 
-func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (api.ObjectMap, error) {
+// annotateChildren adds an annotation to all desired children to remember
+// that they were created by this decorator.
+func (c *decoratorController) annotateChildren(desiredChildren commonv2.UniformObjectMap) {
+	for _, group := range desiredChildren {
+		for _, child := range group {
+			ann := child.GetAnnotations()
+			if ann[decoratorControllerAnnotation] == c.dc.Name {
+				continue
+			}
+			if ann == nil {
+				ann = make(map[string]string)
+			}
+			ann[decoratorControllerAnnotation] = c.dc.Name
+			child.SetAnnotations(ann)
+		}
+	}
+}
+
+func (c *decoratorController) getChildren(ctx context.Context, parent *unstructured.Unstructured) (api.ObjectMap, error) {
 	parentUID := parent.GetUID()
 	parentNamespace := parent.GetNamespace()
 	childMap := make(commonv2.UniformObjectMap)
@@ -807,6 +907,7 @@ func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (ap
 
 		// Take only the objects that belong to this parent,
 		// and that were created by this decorator.
+		var children []*unstructured.Unstructured
 		for _, obj := range all {
 			controllerRef := metav1.GetControllerOf(obj)
 			if controllerRef == nil || controllerRef.UID != parentUID {
@@ -815,8 +916,19 @@ func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (ap
 			if obj.GetAnnotations()[decoratorControllerAnnotation] != c.dc.Name {
 				continue
 			}
-			childMap.Insert(parent, obj)
+			children = append(children, obj)
 		}
+		if child.CacheMode == v1alpha1.CacheModeMetadata {
+			childClient, err := c.dynClient.Resource(child.APIVersion, child.Resource)
+			if err != nil {
+				return nil, err
+			}
+			children, err = common.FetchObjects(ctx, childClient, child.CacheMode, children)
+			if err != nil {
+				return nil, err
+			}
+		}
+		childMap.InsertAll(parent, children)
 	}
 	return childMap, nil
 }
@@ -831,6 +943,14 @@ func (m updateStrategyMap) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdate
 	return strategy.Method
 }
 
+func (m updateStrategyMap) GetOnImmutableFieldError(apiGroup, kind string) v1alpha1.ImmutableFieldErrorPolicy {
+	strategy := m.get(apiGroup, kind)
+	if strategy == nil || strategy.OnImmutableFieldError == "" {
+		return v1alpha1.ImmutableFieldErrorFail
+	}
+	return strategy.OnImmutableFieldError
+}
+
 func (m updateStrategyMap) get(apiGroup, kind string) *v1alpha1.DecoratorControllerAttachmentUpdateStrategy {
 	return m[updateStrategyMapKey(apiGroup, kind)]
 }
@@ -857,6 +977,68 @@ func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1
 	return m, nil
 }
 
+// syncFinalizer adds or removes our finalizer on the parent as necessary. In
+// dry-run mode, it only reports the change and returns the parent as it
+// would be.
+func (c *decoratorController) syncFinalizer(ctx context.Context, parentClient *dynamicclientset.ResourceClient, parent *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	dryRun := c.applyOptions.DryRun()
+	if !dryRun.Enabled() {
+		updated, changed, err := c.finalizer.SyncObject(ctx, parentClient, parent)
+		if changed {
+			c.applyOptions.Audit().RecordFinalizers(ctx, parent, updated)
+		}
+		return updated, err
+	}
+	updated, changed := c.finalizer.DryRunSyncObject(parent)
+	if changed {
+		dryRun.Report(parent, common.DryRunUpdate, parent, updated)
+	}
+	return updated, nil
+}
+
+// newFinalizerManager returns the manager of the finalizer of the controller.
+func newFinalizerManager(dc *v1alpha1.DecoratorController) *finalizer.Manager {
+	return finalizer.NewManager(
+		"metacontroller.io/decoratorcontroller-"+dc.Name,
+		dc.Spec.Hooks.Finalize != nil,
+	)
+}
+
+func makeApplyOptionsMap(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController, global *common.ApplyOptions, eventRecorder record.EventRecorder) (*common.ApplyOptionsMap, error) {
+	m, err := common.NewApplyOptionsMap(global, dc.Spec.ApplyStrategy)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range dc.Spec.Attachments {
+		if child.ApplyStrategy == nil {
+			continue
+		}
+		// Map resource name to kind name.
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		// Ignore API version.
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := m.Set(apiGroup, resource.Kind, child.ApplyStrategy); err != nil {
+			return nil, fmt.Errorf("invalid apply strategy for child resource %q in %v: %w", child.Resource, child.APIVersion, err)
+		}
+	}
+	redactor, err := redact.New(dc.Spec.RedactionRules)
+	if err != nil {
+		return nil, err
+	}
+	m.SetRedactor(redactor)
+	m.SetEventRecorder(eventRecorder)
+	m.SetAudit(global.Audit.ForController("DecoratorController", dc.Name, redactor))
+	if dc.Spec.DryRun || global.DryRun.Enabled() {
+		m.SetDryRun(common.NewDryRun("DecoratorController", dc.Name, eventRecorder, redactor))
+		// Nothing is written in dry-run mode, so there's nothing to audit.
+		m.SetAudit(nil)
+	}
+	return m, nil
+}
+
 func parentQueueKey(obj interface{}) (string, error) {
 	switch o := obj.(type) {
 	case cache.DeletedFinalStateUnknown:
@@ -878,6 +1060,57 @@ func splitParentQueueKey(key string) (apiVersion, kind, namespace, name string,
 	return parts[0], parts[1], parts[2], parts[3], nil
 }
 
+// parentUpdate is how a sync result changes the labels, annotations and
+// status of a parent.
+type parentUpdate struct {
+	labels, annotations                              map[string]string
+	status                                           map[string]interface{}
+	labelsChanged, annotationsChanged, statusChanged bool
+}
+
+func newParentUpdate(parent *unstructured.Unstructured, syncResult *v1.DecoratorHookResponse) (*parentUpdate, error) {
+	update := &parentUpdate{
+		labels:      parent.GetLabels(),
+		annotations: parent.GetAnnotations(),
+		status:      syncResult.Status,
+	}
+	if update.labels == nil {
+		update.labels = make(map[string]string)
+	}
+	if update.annotations == nil {
+		update.annotations = make(map[string]string)
+	}
+	parentStatus, _, err := unstructured.NestedMap(parent.Object, "status")
+	if err != nil {
+		return nil, err
+	}
+	if update.status == nil {
+		// A null .status in the sync response means leave it unchanged.
+		update.status = parentStatus
+	}
+
+	update.labelsChanged = updateStringMap(update.labels, syncResult.Labels)
+	update.annotationsChanged = updateStringMap(update.annotations, syncResult.Annotations)
+	update.statusChanged = !common.DeepEqual(parentStatus, update.status)
+	return update, nil
+}
+
+func (u *parentUpdate) changed() bool {
+	return u.labelsChanged || u.annotationsChanged || u.statusChanged
+}
+
+// dependsOnStripped returns true if comparing the sync result with a cached
+// parent depends on data that the cache drops: annotations it sets or
+// removes, or the status, if the cache drops status fields.
+func dependsOnStripped(stripped dynamicinformer.StripOptions, syncResult *v1.DecoratorHookResponse) bool {
+	for key := range syncResult.Annotations {
+		if stripped.StripsAnnotation(key) {
+			return true
+		}
+	}
+	return syncResult.Status != nil && stripped.StripsWithin("status")
+}
+
 func updateStringMap(dest map[string]string, updates map[string]*string) (changed bool) {
 	for k, v := range updates {
 		if v == nil {
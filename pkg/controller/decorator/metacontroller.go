@@ -18,6 +18,7 @@ package decorator
 
 import (
 	"context"
+	"sort"
 
 	"metacontroller/pkg/logging"
 
@@ -35,12 +36,14 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Metacontroller struct {
@@ -53,6 +56,7 @@ type Metacontroller struct {
 	eventRecorder record.EventRecorder
 
 	decoratorControllers common.SyncMap[string, *decoratorController]
+	retrier              *common.DiscoveryRetrier
 
 	numWorkers int
 
@@ -76,11 +80,45 @@ func NewMetacontroller(ctx context.Context, controllerContext common.ControllerC
 		logger: logging.Logger.WithName("decorator"),
 		ctx:    ctx,
 	}
+	mc.retrier = common.NewDiscoveryRetrier(ctx, controllerContext.Resources, func(name string) client.Object {
+		return &v1alpha1.DecoratorController{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	})
 
 	return mc
 }
 
+// RetrySource returns the source of the events that retry DecoratorControllers
+// that failed to sync, as soon as API discovery changes.
+func (mc *Metacontroller) RetrySource() source.Source {
+	return mc.retrier.Source()
+}
+
+// FailedControllers returns the names of the DecoratorControllers that failed to sync
+// last time, sorted.
+func (mc *Metacontroller) FailedControllers() []string {
+	return mc.retrier.Failed()
+}
+
+// ControllerStatuses returns the state of the running DecoratorControllers, sorted by
+// name.
+func (mc *Metacontroller) ControllerStatuses() []common.ControllerStatus {
+	var statuses []common.ControllerStatus
+	mc.decoratorControllers.ForEach(func(_ string, c *decoratorController) {
+		statuses = append(statuses, c.status())
+	})
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
 func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	result, err := mc.reconcile(ctx, request)
+	mc.retrier.Observe(request.Name, err)
+	return result, err
+}
+
+func (mc *Metacontroller) reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	decoratorControllerName := request.Name
 	mc.logger.V(4).Info("Sync DecoratorController", "name", decoratorControllerName)
 
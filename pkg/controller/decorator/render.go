@@ -0,0 +1,94 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decorator
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/api"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/redact"
+)
+
+// Render syncs the parent like the controller would, against the given
+// observed attachments and related objects, and returns the desired
+// attachments. The controller runs in dry-run mode and passes its writes to
+// collect instead of reporting them. Its dry-run writes go to the given
+// client, which should hold the parent and its observed attachments, and the
+// resources map the resources of the controller to kinds, like discovery
+// does.
+func Render(
+	ctx context.Context,
+	resources *dynamicdiscovery.ResourceMap,
+	dynClient *dynamicclientset.Clientset,
+	dc *v1alpha1.DecoratorController,
+	syncHook, finalizeHook hooks.Hook,
+	collect func(write common.DryRunWrite),
+	parent *unstructured.Unstructured,
+	observedChildren, relatedObjects api.ObjectMap,
+) ([]*unstructured.Unstructured, error) {
+	if dc.Spec.Hooks == nil {
+		return nil, fmt.Errorf("no hooks defined")
+	}
+	parentSelector, err := newDecoratorSelector(resources, dc)
+	if err != nil {
+		return nil, err
+	}
+	updateStrategy, err := makeUpdateStrategyMap(resources, dc)
+	if err != nil {
+		return nil, err
+	}
+	applyOptions, err := makeApplyOptionsMap(resources, dc, &common.ApplyOptions{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyOptions.SetDryRun(common.NewCollectingDryRun("DecoratorController", dc.Name, collect))
+	queue := common.NewQueue("")
+	defer queue.ShutDown()
+
+	c := &decoratorController{
+		dc:             dc,
+		resources:      resources,
+		parentSelector: parentSelector,
+		dynClient:      dynClient,
+		queue:          queue,
+		updateStrategy: updateStrategy,
+		applyOptions:   applyOptions,
+		eventRecorder:  &record.FakeRecorder{},
+		finalizer:      newFinalizerManager(dc),
+		syncHook:       syncHook,
+		finalizeHook:   finalizeHook,
+		logger:         logging.Logger.WithName(dc.Name),
+		ctx:            ctx,
+	}
+	ctx = redact.NewContext(ctx, applyOptions.Redactor())
+
+	parent, ok, err := c.syncParentFinalizer(ctx, parent)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return c.syncObservedParent(ctx, parent, observedChildren, relatedObjects)
+}
@@ -15,7 +15,8 @@ limitations under the License.
 */
 
 // Package apply is a dynamic, client-side substitute for `kubectl apply` that
-// tries to guess the right thing to do without any type-specific knowledge.
+// follows the list semantics published in the OpenAPI schema of a resource,
+// and tries to guess the right thing to do where no schema is available.
 // Instead of generating a PATCH request, it does the patching locally and
 // returns a full object with the ResourceVersion intact.
 //
@@ -25,7 +26,12 @@ limitations under the License.
 package apply
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -36,41 +42,136 @@ const (
 	LastAppliedAnnotation = "metacontroller.k8s.io/last-applied-configuration"
 )
 
+// LastAppliedEncoding is how the last-applied configuration is stored in the
+// LastAppliedAnnotation.
+type LastAppliedEncoding string
+
+const (
+	// LastAppliedEncodingJSON stores plain JSON, which is the only encoding
+	// older versions of Metacontroller can read.
+	LastAppliedEncodingJSON LastAppliedEncoding = "JSON"
+	// LastAppliedEncodingGzip stores gzip-compressed, base64-encoded JSON,
+	// so that large objects fit in the size limit of annotations.
+	LastAppliedEncodingGzip LastAppliedEncoding = "Gzip"
+	// LastAppliedEncodingAuto stores plain JSON, unless it is larger than
+	// AutoCompressThreshold.
+	LastAppliedEncodingAuto LastAppliedEncoding = "Auto"
+)
+
+// AutoCompressThreshold is the size in bytes of the JSON last-applied
+// configuration above which LastAppliedEncodingAuto compresses it.
+const AutoCompressThreshold = 64 * 1024
+
+// gzipPrefix marks a compressed last-applied configuration. Plain JSON values
+// always start with "{", so they can't be mistaken for compressed ones.
+const gzipPrefix = "gzip+base64:"
+
+// ValidateLastAppliedEncoding returns an error if the encoding is unknown.
+// The empty encoding is the same as LastAppliedEncodingJSON.
+func ValidateLastAppliedEncoding(encoding LastAppliedEncoding) error {
+	switch encoding {
+	case "", LastAppliedEncodingJSON, LastAppliedEncodingGzip, LastAppliedEncodingAuto:
+		return nil
+	default:
+		return fmt.Errorf("unknown last-applied encoding %q", encoding)
+	}
+}
+
 func SetLastApplied(obj *unstructured.Unstructured, lastApplied map[string]interface{}) error {
+	return SetLastAppliedWithEncoding(obj, lastApplied, LastAppliedEncodingJSON)
+}
+
+// SetLastAppliedWithEncoding is like SetLastApplied, but stores the
+// last-applied configuration with the given encoding.
+func SetLastAppliedWithEncoding(obj *unstructured.Unstructured, lastApplied map[string]interface{}, encoding LastAppliedEncoding) error {
 	lastAppliedJSON, err := json.Marshal(lastApplied)
 	if err != nil {
 		return fmt.Errorf("can't marshal last applied config: %w", err)
 	}
 
+	value := string(lastAppliedJSON)
+	if encoding == LastAppliedEncodingGzip || (encoding == LastAppliedEncodingAuto && len(lastAppliedJSON) > AutoCompressThreshold) {
+		if value, err = compressLastApplied(lastAppliedJSON); err != nil {
+			return fmt.Errorf("can't compress last applied config: %w", err)
+		}
+	}
+
 	ann := obj.GetAnnotations()
 	if ann == nil {
 		ann = make(map[string]string, 1)
 	}
-	ann[LastAppliedAnnotation] = string(lastAppliedJSON)
+	ann[LastAppliedAnnotation] = value
 	obj.SetAnnotations(ann)
 	return nil
 }
 
+// GetLastApplied returns the last-applied configuration of the object, in any
+// of the supported encodings.
 func GetLastApplied(obj *unstructured.Unstructured) (map[string]interface{}, error) {
-	lastAppliedJSON := obj.GetAnnotations()[LastAppliedAnnotation]
-	if lastAppliedJSON == "" {
+	value := obj.GetAnnotations()[LastAppliedAnnotation]
+	if value == "" {
 		return nil, nil
 	}
+	lastAppliedJSON := []byte(value)
+	if compressed, ok := strings.CutPrefix(value, gzipPrefix); ok {
+		var err error
+		if lastAppliedJSON, err = decompressLastApplied(compressed); err != nil {
+			return nil, fmt.Errorf("can't decompress %q annotation: %w", LastAppliedAnnotation, err)
+		}
+	}
 	lastApplied := make(map[string]interface{})
-	err := json.Unmarshal([]byte(lastAppliedJSON), &lastApplied)
+	err := json.Unmarshal(lastAppliedJSON, &lastApplied)
 	if err != nil {
 		return nil, fmt.Errorf("can't unmarshal %q annotation: %w", LastAppliedAnnotation, err)
 	}
 	return lastApplied, nil
 }
 
+// compressLastApplied returns the gzip-compressed, base64-encoded data with
+// the gzipPrefix. The result only depends on the data, so that unchanged
+// configurations don't cause updates.
+func compressLastApplied(data []byte) (string, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return gzipPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decompressLastApplied(value string) ([]byte, error) {
+	compressed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
 // Merge updates the given observed object to apply the desired changes.
 // It returns an updated copy of the observed object if no error occurs.
 func Merge(observed, lastApplied, desired map[string]interface{}) (map[string]interface{}, error) {
+	return MergeWithSchema(observed, lastApplied, desired, nil)
+}
+
+// MergeWithSchema is like Merge, but merges lists as described by the given
+// schema of the object. Merge keys are only guessed for fields the schema
+// doesn't describe, or if the schema is nil.
+func MergeWithSchema(observed, lastApplied, desired map[string]interface{}, schema *Schema) (map[string]interface{}, error) {
 	// Make a copy of observed since merge() mutates the destination.
 	destination := runtime.DeepCopyJSON(observed)
 
-	if _, err := merge("", destination, lastApplied, desired); err != nil {
+	if _, err := merge("", destination, lastApplied, desired, schema); err != nil {
 		return nil, fmt.Errorf("can't merge desired changes: %w", err)
 	}
 	return destination, nil
@@ -78,7 +179,7 @@ func Merge(observed, lastApplied, desired map[string]interface{}) (map[string]in
 
 // merge finds the diff from lastApplied to desired,
 // and applies it to destination, returning the replacement destination value.
-func merge(fieldPath string, destination, lastApplied, desired interface{}) (interface{}, error) {
+func merge(fieldPath string, destination, lastApplied, desired interface{}, schema *Schema) (interface{}, error) {
 	switch destVal := destination.(type) {
 	case map[string]interface{}:
 		// destination is an object.
@@ -91,7 +192,7 @@ func merge(fieldPath string, destination, lastApplied, desired interface{}) (int
 		if !ok && desVal != nil {
 			return nil, fmt.Errorf("desired%s: expecting map[string]interface, got %T", fieldPath, desired)
 		}
-		return mergeObject(fieldPath, destVal, lastVal, desVal)
+		return mergeObject(fieldPath, destVal, lastVal, desVal, schema)
 	case []interface{}:
 		// destination is an array.
 		// Make sure the others are arrays too (or null).
@@ -103,7 +204,7 @@ func merge(fieldPath string, destination, lastApplied, desired interface{}) (int
 		if !ok && desVal != nil {
 			return nil, fmt.Errorf("desired%s: expecting []interface, got %T", fieldPath, desired)
 		}
-		return mergeArray(fieldPath, destVal, lastVal, desVal)
+		return mergeArray(fieldPath, destVal, lastVal, desVal, schema)
 	default:
 		// destination is a scalar or null.
 		// Just take the desired value. We won't be called if there's none.
@@ -111,7 +212,7 @@ func merge(fieldPath string, destination, lastApplied, desired interface{}) (int
 	}
 }
 
-func mergeObject(fieldPath string, destination, lastApplied, desired map[string]interface{}) (interface{}, error) {
+func mergeObject(fieldPath string, destination, lastApplied, desired map[string]interface{}, schema *Schema) (interface{}, error) {
 	// Remove fields that were present in lastApplied, but no longer in desired.
 	for key := range lastApplied {
 		if _, present := desired[key]; !present {
@@ -122,7 +223,7 @@ func mergeObject(fieldPath string, destination, lastApplied, desired map[string]
 	// Add/Update all fields present in desired.
 	var err error
 	for key, desVal := range desired {
-		destination[key], err = merge(fmt.Sprintf("%s[%s]", fieldPath, key), destination[key], lastApplied[key], desVal)
+		destination[key], err = merge(fmt.Sprintf("%s[%s]", fieldPath, key), destination[key], lastApplied[key], desVal, schema.field(key))
 		if err != nil {
 			return nil, err
 		}
@@ -131,10 +232,24 @@ func mergeObject(fieldPath string, destination, lastApplied, desired map[string]
 	return destination, nil
 }
 
-func mergeArray(fieldPath string, destination, lastApplied, desired []interface{}) (interface{}, error) {
+func mergeArray(fieldPath string, destination, lastApplied, desired []interface{}, schema *Schema) (interface{}, error) {
+	if schema != nil {
+		// The schema tells us exactly how to merge this list.
+		switch schema.ListType {
+		case ListTypeMap:
+			if keyDefaults, ok := listMapKeyDefaults(schema.ListMapKeys, schema.Items, destination, lastApplied, desired); ok {
+				return mergeListMap(fieldPath, schema.ListMapKeys, keyDefaults, destination, lastApplied, desired, schema.Items)
+			}
+		case ListTypeSet:
+			return mergeListSet(destination, lastApplied, desired), nil
+		}
+		// Anything else is an atomic list, which is replaced as a whole.
+		return desired, nil
+	}
+
 	// If it looks like a list map, use the special merge.
 	if mergeKey := detectListMapKey(destination, lastApplied, desired); mergeKey != "" {
-		return mergeListMap(fieldPath, mergeKey, destination, lastApplied, desired)
+		return mergeListMap(fieldPath, []string{mergeKey}, nil, destination, lastApplied, desired, nil)
 	}
 
 	// It's a normal array. Just replace for now.
@@ -142,13 +257,20 @@ func mergeArray(fieldPath string, destination, lastApplied, desired []interface{
 	return desired, nil
 }
 
-func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired []interface{}) (interface{}, error) {
-	// Treat each list of objects as if it were a map, keyed by the mergeKey field.
-	destMap := makeListMap(mergeKey, destination)
-	lastMap := makeListMap(mergeKey, lastApplied)
-	desMap := makeListMap(mergeKey, desired)
+// mergeListMap merges lists of objects identified by the values of the
+// mergeKeys fields. Key fields that an item leaves out take the value of
+// keyDefaults, like the API server would default them.
+func mergeListMap(fieldPath string, mergeKeys []string, keyDefaults map[string]interface{}, destination, lastApplied, desired []interface{}, itemSchema *Schema) (interface{}, error) {
+	// Treat each list of objects as if it were a map, keyed by the mergeKeys fields.
+	destMap := makeListMap(mergeKeys, keyDefaults, destination)
+	lastMap := makeListMap(mergeKeys, keyDefaults, lastApplied)
+	desMap := makeListMap(mergeKeys, keyDefaults, desired)
 
-	_, err := mergeObject(fieldPath, destMap, lastMap, desMap)
+	var mapSchema *Schema
+	if itemSchema != nil {
+		mapSchema = &Schema{AdditionalProperties: itemSchema}
+	}
+	_, err := mergeObject(fieldPath, destMap, lastMap, desMap, mapSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -158,7 +280,7 @@ func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired
 	added := make(map[string]bool, len(destMap))
 	// First take items that were already in destination.
 	for _, item := range destination {
-		key := stringMergeKey(item.(map[string]interface{})[mergeKey])
+		key := listMapKey(mergeKeys, keyDefaults, item.(map[string]interface{}))
 		if newItem, ok := destMap[key]; ok {
 			destList = append(destList, newItem)
 			// Remember which items we've already added to the final list.
@@ -167,7 +289,7 @@ func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired
 	}
 	// Then take items in desired that haven't been added yet.
 	for _, item := range desired {
-		key := stringMergeKey(item.(map[string]interface{})[mergeKey])
+		key := listMapKey(mergeKeys, keyDefaults, item.(map[string]interface{}))
 		if !added[key] {
 			destList = append(destList, destMap[key])
 			added[key] = true
@@ -177,17 +299,65 @@ func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired
 	return destList, nil
 }
 
-func makeListMap(mergeKey string, list []interface{}) map[string]interface{} {
+// mergeListSet merges lists of unique values. Values that were removed from
+// lastApplied are removed from destination, and new values from desired are
+// added after the ones that are already there.
+func mergeListSet(destination, lastApplied, desired []interface{}) []interface{} {
+	desiredValues := make(map[string]bool, len(desired))
+	for _, item := range desired {
+		desiredValues[stringMergeKey(item)] = true
+	}
+	removed := make(map[string]bool, len(lastApplied))
+	for _, item := range lastApplied {
+		if key := stringMergeKey(item); !desiredValues[key] {
+			removed[key] = true
+		}
+	}
+
+	destList := make([]interface{}, 0, len(destination)+len(desired))
+	added := make(map[string]bool, len(destination)+len(desired))
+	for _, item := range destination {
+		key := stringMergeKey(item)
+		if !removed[key] && !added[key] {
+			destList = append(destList, item)
+			added[key] = true
+		}
+	}
+	for _, item := range desired {
+		key := stringMergeKey(item)
+		if !added[key] {
+			destList = append(destList, item)
+			added[key] = true
+		}
+	}
+	return destList
+}
+
+func makeListMap(mergeKeys []string, keyDefaults map[string]interface{}, list []interface{}) map[string]interface{} {
 	res := make(map[string]interface{}, len(list))
 	for _, item := range list {
-		// We only end up here if detectListMapKey() already verified that
-		// all items are objects.
+		// We only end up here if detectListMapKey() or listMapKeyDefaults()
+		// already verified that all items are objects.
 		itemMap := item.(map[string]interface{})
-		res[stringMergeKey(itemMap[mergeKey])] = item
+		res[listMapKey(mergeKeys, keyDefaults, itemMap)] = item
 	}
 	return res
 }
 
+// listMapKey returns the key of a list map item, made of the values of all
+// its merge key fields, or of their defaults if the item leaves them out.
+func listMapKey(mergeKeys []string, keyDefaults map[string]interface{}, item map[string]interface{}) string {
+	values := make([]string, 0, len(mergeKeys))
+	for _, key := range mergeKeys {
+		value, ok := item[key]
+		if !ok {
+			value = keyDefaults[key]
+		}
+		values = append(values, stringMergeKey(value))
+	}
+	return strings.Join(values, "\x00")
+}
+
 // stringMergeKey converts merge key values that aren't strings to strings.
 func stringMergeKey(val interface{}) string {
 	switch tval := val.(type) {
@@ -259,3 +429,34 @@ func detectListMapKey(lists ...[]interface{}) string {
 	}
 	return ""
 }
+
+// listMapKeyDefaults returns the defaults of the merge keys from the schema,
+// and whether all items in the given lists are objects that have each key
+// set or defaulted by the schema. Otherwise, items can't be told apart
+// reliably, like ports that only differ in a protocol without a default, so
+// the list isn't merged as a map.
+func listMapKeyDefaults(mergeKeys []string, itemSchema *Schema, lists ...[]interface{}) (map[string]interface{}, bool) {
+	if len(mergeKeys) == 0 {
+		return nil, false
+	}
+	keyDefaults := make(map[string]interface{}, len(mergeKeys))
+	for _, key := range mergeKeys {
+		if field := itemSchema.field(key); field != nil && field.Default != nil {
+			keyDefaults[key] = field.Default
+		}
+	}
+	for _, list := range lists {
+		for _, item := range list {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			for _, key := range mergeKeys {
+				if _, ok := obj[key]; !ok && keyDefaults[key] == nil {
+					return nil, false
+				}
+			}
+		}
+	}
+	return keyDefaults, true
+}
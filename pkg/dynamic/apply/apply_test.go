@@ -18,6 +18,7 @@ package apply
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -208,3 +209,171 @@ func TestLastAppliedAnnotation(t *testing.T) {
 		t.Errorf("got %#v, want %#v", out, in)
 	}
 }
+
+func TestLastAppliedEncoding(t *testing.T) {
+	small := map[string]interface{}{"data": map[string]interface{}{"key": "value"}}
+	large := map[string]interface{}{"data": map[string]interface{}{"key": strings.Repeat("value", AutoCompressThreshold)}}
+
+	tests := []struct {
+		name           string
+		lastApplied    map[string]interface{}
+		encoding       LastAppliedEncoding
+		wantCompressed bool
+	}{
+		{name: "json", lastApplied: large, encoding: LastAppliedEncodingJSON},
+		{name: "empty encoding is json", lastApplied: small},
+		{name: "gzip", lastApplied: small, encoding: LastAppliedEncodingGzip, wantCompressed: true},
+		{name: "auto keeps small configurations readable", lastApplied: small, encoding: LastAppliedEncodingAuto},
+		{name: "auto compresses large configurations", lastApplied: large, encoding: LastAppliedEncodingAuto, wantCompressed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{}
+			if err := SetLastAppliedWithEncoding(obj, tt.lastApplied, tt.encoding); err != nil {
+				t.Fatalf("SetLastAppliedWithEncoding error: %v", err)
+			}
+			value := obj.GetAnnotations()[LastAppliedAnnotation]
+			if got := strings.HasPrefix(value, gzipPrefix); got != tt.wantCompressed {
+				t.Errorf("compressed = %v, want %v", got, tt.wantCompressed)
+			}
+			if tt.wantCompressed && len(value) > AutoCompressThreshold {
+				t.Errorf("compressed annotation is %d bytes, want at most %d", len(value), AutoCompressThreshold)
+			}
+			out, err := GetLastApplied(obj)
+			if err != nil {
+				t.Fatalf("GetLastApplied error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.lastApplied, out) {
+				t.Errorf("GetLastApplied() = %.100v, want %.100v", out, tt.lastApplied)
+			}
+
+			// Storing the same configuration again must not change the annotation.
+			again := &unstructured.Unstructured{}
+			if err := SetLastAppliedWithEncoding(again, tt.lastApplied, tt.encoding); err != nil {
+				t.Fatalf("SetLastAppliedWithEncoding error: %v", err)
+			}
+			if again.GetAnnotations()[LastAppliedAnnotation] != value {
+				t.Errorf("annotation isn't deterministic")
+			}
+		})
+	}
+}
+
+func TestGetLastApplied_invalidCompressed(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAnnotations(map[string]string{LastAppliedAnnotation: gzipPrefix + "not gzip"})
+	if _, err := GetLastApplied(obj); err == nil {
+		t.Errorf("GetLastApplied() error = nil, want error")
+	}
+}
+
+func TestMergeWithSchema(t *testing.T) {
+	schema := &Schema{
+		Fields: map[string]*Schema{
+			"ports": {
+				ListType:    ListTypeMap,
+				ListMapKeys: []string{"containerPort", "protocol"},
+				Items: &Schema{Fields: map[string]*Schema{
+					"protocol": {Default: "TCP"},
+				}},
+			},
+			"endpoints": {
+				ListType:    ListTypeMap,
+				ListMapKeys: []string{"port", "protocol"},
+				Items:       &Schema{},
+			},
+			"tolerations": {ListType: ListTypeAtomic},
+			"finalizers":  {ListType: ListTypeSet},
+			"rules": {
+				ListType:    ListTypeMap,
+				ListMapKeys: []string{"host"},
+				Items: &Schema{Fields: map[string]*Schema{
+					"paths": {ListType: ListTypeAtomic},
+				}},
+			},
+		},
+	}
+	table := []struct {
+		name, observed, lastApplied, desired, want string
+	}{
+		{
+			name:        "list map with multiple keys",
+			observed:    `{"ports": [{"containerPort": 53, "protocol": "UDP", "keep": "other"}, {"containerPort": 53, "protocol": "TCP"}]}`,
+			lastApplied: `{"ports": [{"containerPort": 53, "protocol": "UDP"}, {"containerPort": 53, "protocol": "TCP"}]}`,
+			desired:     `{"ports": [{"containerPort": 53, "protocol": "UDP", "name": "dns"}]}`,
+			want:        `{"ports": [{"containerPort": 53, "protocol": "UDP", "name": "dns", "keep": "other"}]}`,
+		},
+		{
+			name:        "list map keys defaulted by the server",
+			observed:    `{"ports": [{"containerPort": 80, "protocol": "TCP"}]}`,
+			lastApplied: `{"ports": [{"containerPort": 80}]}`,
+			desired:     `{"ports": [{"containerPort": 80, "name": "http"}]}`,
+			want:        `{"ports": [{"containerPort": 80, "protocol": "TCP", "name": "http"}]}`,
+		},
+		{
+			name:        "list map items that only differ in a defaulted key",
+			observed:    `{"ports": [{"containerPort": 53, "protocol": "TCP", "keep": "tcp"}, {"containerPort": 53, "protocol": "UDP", "keep": "udp"}]}`,
+			lastApplied: `{"ports": [{"containerPort": 53}, {"containerPort": 53, "protocol": "UDP"}]}`,
+			desired:     `{"ports": [{"containerPort": 53, "name": "dns-tcp"}, {"containerPort": 53, "protocol": "UDP", "name": "dns-udp"}]}`,
+			want:        `{"ports": [{"containerPort": 53, "protocol": "TCP", "keep": "tcp", "name": "dns-tcp"}, {"containerPort": 53, "protocol": "UDP", "keep": "udp", "name": "dns-udp"}]}`,
+		},
+		{
+			name:        "list map with a missing key without default is atomic",
+			observed:    `{"endpoints": [{"port": 53, "protocol": "TCP"}, {"port": 53, "protocol": "UDP"}]}`,
+			lastApplied: `{"endpoints": [{"port": 53}]}`,
+			desired:     `{"endpoints": [{"port": 53, "name": "dns"}]}`,
+			want:        `{"endpoints": [{"port": 53, "name": "dns"}]}`,
+		},
+		{
+			name:        "atomic list with guessable keys",
+			observed:    `{"tolerations": [{"name": "keep", "value": "other"}]}`,
+			lastApplied: `{"tolerations": []}`,
+			desired:     `{"tolerations": [{"name": "add"}]}`,
+			want:        `{"tolerations": [{"name": "add"}]}`,
+		},
+		{
+			name:        "set",
+			observed:    `{"finalizers": ["other", "remove", "keep"]}`,
+			lastApplied: `{"finalizers": ["remove", "keep"]}`,
+			desired:     `{"finalizers": ["keep", "add"]}`,
+			want:        `{"finalizers": ["other", "keep", "add"]}`,
+		},
+		{
+			name:        "nested atomic list inside list map",
+			observed:    `{"rules": [{"host": "a", "paths": [{"path": "/other"}]}]}`,
+			lastApplied: `{"rules": [{"host": "a", "paths": []}]}`,
+			desired:     `{"rules": [{"host": "a", "paths": [{"path": "/new"}]}]}`,
+			want:        `{"rules": [{"host": "a", "paths": [{"path": "/new"}]}]}`,
+		},
+		{
+			name:        "unknown field falls back to guessing",
+			observed:    `{"unknown": [{"name": "keep"}]}`,
+			lastApplied: `{"unknown": []}`,
+			desired:     `{"unknown": [{"name": "add"}]}`,
+			want:        `{"unknown": [{"name": "keep"}, {"name": "add"}]}`,
+		},
+	}
+
+	for _, tc := range table {
+		var observed, lastApplied, desired, want map[string]interface{}
+		for _, in := range []struct {
+			json string
+			out  *map[string]interface{}
+		}{{tc.observed, &observed}, {tc.lastApplied, &lastApplied}, {tc.desired, &desired}, {tc.want, &want}} {
+			if err := json.Unmarshal([]byte(in.json), in.out); err != nil {
+				t.Fatalf("%v: can't unmarshal %q: %v", tc.name, in.json, err)
+			}
+		}
+
+		got, err := MergeWithSchema(observed, lastApplied, desired, schema)
+		if err != nil {
+			t.Errorf("%v: MergeWithSchema error: %v", tc.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("reflect diff: a=got, b=want:\n%s", cmp.Diff(got, want))
+			t.Errorf("%v: MergeWithSchema() = %#v, want %#v", tc.name, got, want)
+		}
+	}
+}
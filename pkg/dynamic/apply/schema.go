@@ -0,0 +1,57 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+// List types, as defined by the x-kubernetes-list-type OpenAPI extension.
+const (
+	ListTypeAtomic = "atomic"
+	ListTypeSet    = "set"
+	ListTypeMap    = "map"
+)
+
+// Schema describes the parts of the OpenAPI schema of a field that matter for
+// merging. A nil Schema means nothing is known about the field, in which case
+// list merge keys are guessed.
+type Schema struct {
+	// Fields holds the schemas of the known properties of an object.
+	Fields map[string]*Schema
+	// AdditionalProperties is the schema of all values of a map-like object.
+	AdditionalProperties *Schema
+	// Items is the schema of the items of a list.
+	Items *Schema
+	// ListType is how a list is merged. Lists with an empty or unknown list
+	// type are atomic.
+	ListType string
+	// ListMapKeys are the fields that identify the items of a list with
+	// ListTypeMap.
+	ListMapKeys []string
+	// Default is the value the API server sets the field to if it's left out,
+	// or nil if it has none.
+	Default interface{}
+}
+
+// field returns the schema of the given property of an object, or nil if the
+// schema doesn't describe it.
+func (s *Schema) field(name string) *Schema {
+	if s == nil {
+		return nil
+	}
+	if field, ok := s.Fields[name]; ok {
+		return field
+	}
+	return s.AdditionalProperties
+}
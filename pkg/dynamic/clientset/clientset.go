@@ -26,9 +26,11 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/retry"
 
+	dynamicapply "metacontroller/pkg/dynamic/apply"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 )
 
@@ -36,13 +38,21 @@ type Clientset struct {
 	config    rest.Config
 	resources *dynamicdiscovery.ResourceMap
 	dc        dynamic.Interface
+	mc        metadata.Interface
 }
 
 func NewClientset(config *rest.Config, resources *dynamicdiscovery.ResourceMap, dc dynamic.Interface) *Clientset {
+	return NewClientsetWithMetadata(config, resources, dc, nil)
+}
+
+// NewClientsetWithMetadata returns a Clientset that can also read only the
+// metadata of objects, through the given metadata client.
+func NewClientsetWithMetadata(config *rest.Config, resources *dynamicdiscovery.ResourceMap, dc dynamic.Interface, mc metadata.Interface) *Clientset {
 	return &Clientset{
 		config:    *config,
 		resources: resources,
 		dc:        dc,
+		mc:        mc,
 	}
 }
 
@@ -51,7 +61,11 @@ func New(config *rest.Config, resources *dynamicdiscovery.ResourceMap) (*Clients
 	if err != nil {
 		return nil, fmt.Errorf("can't create dynamic client when creating clientset: %w", err)
 	}
-	return NewClientset(config, resources, dc), nil
+	mc, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("can't create metadata client when creating clientset: %w", err)
+	}
+	return NewClientsetWithMetadata(config, resources, dc, mc), nil
 }
 
 func (cs *Clientset) HasSynced() bool {
@@ -83,6 +97,8 @@ func (cs *Clientset) resource(apiResource *dynamicdiscovery.APIResource) *Resour
 		APIResource:       apiResource,
 		rootClient:        client,
 		dc:                cs.dc,
+		mc:                cs.mc,
+		resources:         cs.resources,
 	}
 }
 
@@ -100,6 +116,8 @@ type ResourceClient struct {
 
 	rootClient dynamic.NamespaceableResourceInterface
 	dc         dynamic.Interface
+	mc         metadata.Interface
+	resources  *dynamicdiscovery.ResourceMap
 }
 
 // IsWatchListSemanticsUnSupported returns true if the underlying client explicitly does not support WatchList.
@@ -113,6 +131,12 @@ func (rc *ResourceClient) IsWatchListSemanticsUnSupported() bool {
 	return false
 }
 
+// Schema returns the merge schema of the resource published by the API server,
+// or nil if it's unknown.
+func (rc *ResourceClient) Schema() *dynamicapply.Schema {
+	return rc.resources.GetSchema(rc.APIVersion, rc.Kind)
+}
+
 // Namespace returns a copy of the ResourceClient with the client namespace set.
 //
 // This can be chained to set the namespace to something else.
@@ -133,7 +157,18 @@ func (rc *ResourceClient) Namespace(namespace string) *ResourceClient {
 		APIResource:       rc.APIResource,
 		rootClient:        rc.rootClient,
 		dc:                rc.dc,
+		mc:                rc.mc,
+		resources:         rc.resources,
+	}
+}
+
+// Metadata returns a client that reads only the metadata of objects of this
+// resource, in all namespaces.
+func (rc *ResourceClient) Metadata() (metadata.ResourceInterface, error) {
+	if rc.mc == nil {
+		return nil, fmt.Errorf("no metadata client for %v", rc.GroupResource())
 	}
+	return rc.mc.Resource(rc.GroupVersionResource()), nil
 }
 
 // AtomicUpdate performs an atomic read-modify-write loop, retrying on
@@ -24,9 +24,13 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/sync/singleflight"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/openapi"
+
+	dynamicapply "metacontroller/pkg/dynamic/apply"
 )
 
 type APIResource struct {
@@ -67,9 +71,35 @@ type groupVersionEntry struct {
 type ResourceMap struct {
 	mutex         sync.RWMutex
 	groupVersions map[string]groupVersionEntry
+	// generation is incremented whenever resources are installed or removed.
+	generation int64
 
 	discoveryClient discovery.DiscoveryInterface
 	doneCh          chan struct{}
+	// refreshMutex serializes full and targeted refreshes, so that an older
+	// snapshot never replaces a newer one.
+	refreshMutex sync.Mutex
+
+	handlerMutex   sync.Mutex
+	changeHandlers []func()
+
+	// openAPIClient is nil if the discovery client doesn't serve OpenAPI v3
+	// documents, in which case no schemas are known.
+	openAPIClient openapi.Client
+	// schemaFetches deduplicates concurrent fetches of the OpenAPI v3 paths
+	// and documents, which are made without holding schemaMutex.
+	schemaFetches singleflight.Group
+	// schemaMutex guards schemaPaths, schemas and schemaGeneration.
+	schemaMutex sync.Mutex
+	// schemaPaths is nil until the OpenAPI v3 paths are fetched.
+	schemaPaths map[string]openapi.GroupVersion
+	schemas     map[string]*openAPIDocument
+	// schemaGeneration is incremented by resetSchemas, so that fetches that
+	// started before don't cache their outcome.
+	schemaGeneration int64
+	// staticSchemas holds the schemas set by SetSchema by apiVersion and kind,
+	// which take precedence over the OpenAPI v3 documents.
+	staticSchemas map[string]map[string]*dynamicapply.Schema
 }
 
 func (rm *ResourceMap) Get(apiVersion, resource string) (result *APIResource) {
@@ -95,8 +125,13 @@ func (rm *ResourceMap) GetKind(apiVersion, kind string) (result *APIResource) {
 }
 
 func (rm *ResourceMap) refresh() {
+	rm.refreshMutex.Lock()
+	defer rm.refreshMutex.Unlock()
+
 	// Fetch all API Group-Versions and their resources from the server.
 	// We do this before acquiring the lock so we don't block readers.
+	// The discovery client uses aggregated discovery if the server supports
+	// it, which fetches everything in a single request.
 	logging.Logger.V(7).Info("Refreshing API discovery info")
 	_, groups, err := rm.discoveryClient.ServerGroupsAndResources()
 	if err != nil {
@@ -107,66 +142,100 @@ func (rm *ResourceMap) refresh() {
 		logging.Logger.V(4).Info("Failed to fetch all resources, continuing with partial discovery info", "failures", err)
 	}
 
-	// Denormalize resource lists into maps for convenient lookup
-	// by either Group-Version-Kind or Group-Version-Resource.
 	groupVersions := make(map[string]groupVersionEntry, len(groups))
 	for _, group := range groups {
-		gv, err := schema.ParseGroupVersion(group.GroupVersion)
-		if err != nil {
-			// This shouldn't happen because we get these values from the server.
-			panic(fmt.Errorf("received invalid GroupVersion from server: %w", err))
+		groupVersions[group.GroupVersion] = newGroupVersionEntry(group)
+	}
+
+	// Replace the local cache.
+	rm.mutex.Lock()
+	synced := rm.groupVersions != nil
+	changed := !synced || !sameResources(rm.groupVersions, groupVersions)
+	if changed {
+		rm.generation++
+	}
+	rm.groupVersions = groupVersions
+	rm.mutex.Unlock()
+
+	rm.resetSchemas()
+	if synced && changed {
+		rm.notifyChange()
+	}
+}
+
+// newGroupVersionEntry denormalizes a resource list into maps for convenient
+// lookup by either Group-Version-Kind or Group-Version-Resource.
+func newGroupVersionEntry(group *metav1.APIResourceList) groupVersionEntry {
+	gv, err := schema.ParseGroupVersion(group.GroupVersion)
+	if err != nil {
+		// This shouldn't happen because we get these values from the server.
+		panic(fmt.Errorf("received invalid GroupVersion from server: %w", err))
+	}
+	gve := groupVersionEntry{
+		resources:    make(map[string]*APIResource, len(group.APIResources)),
+		kinds:        make(map[string]*APIResource, len(group.APIResources)),
+		subresources: make(map[string]*APIResource, len(group.APIResources)),
+	}
+
+	for i := range group.APIResources {
+		apiResource := &APIResource{
+			APIResource: group.APIResources[i],
+			APIVersion:  group.GroupVersion,
+		}
+		// Materialize default values from the list into each entry.
+		if apiResource.Group == "" {
+			apiResource.Group = gv.Group
 		}
-		gve := groupVersionEntry{
-			resources:    make(map[string]*APIResource, len(group.APIResources)),
-			kinds:        make(map[string]*APIResource, len(group.APIResources)),
-			subresources: make(map[string]*APIResource, len(group.APIResources)),
+		if apiResource.Version == "" {
+			apiResource.Version = gv.Version
 		}
+		gve.resources[apiResource.Name] = apiResource
+		// Remember which resources are subresources, and map the kind to the main resource.
+		// This is different from what RESTMapper provides because we already know
+		// the full GroupVersionKind and just need the resource name.
+		if strings.ContainsRune(apiResource.Name, '/') {
+			gve.subresources[apiResource.Name] = apiResource
+		} else {
+			gve.kinds[apiResource.Kind] = apiResource
+		}
+	}
 
-		for i := range group.APIResources {
-			apiResource := &APIResource{
-				APIResource: group.APIResources[i],
-				APIVersion:  group.GroupVersion,
-			}
-			// Materialize default values from the list into each entry.
-			if apiResource.Group == "" {
-				apiResource.Group = gv.Group
-			}
-			if apiResource.Version == "" {
-				apiResource.Version = gv.Version
-			}
-			gve.resources[apiResource.Name] = apiResource
-			// Remember which resources are subresources, and map the kind to the main resource.
-			// This is different from what RESTMapper provides because we already know
-			// the full GroupVersionKind and just need the resource name.
-			if strings.ContainsRune(apiResource.Name, '/') {
-				gve.subresources[apiResource.Name] = apiResource
-			} else {
-				gve.kinds[apiResource.Kind] = apiResource
-			}
+	// Group all subresources for a resource.
+	for apiSubresourceName := range gve.subresources {
+		arr := strings.Split(apiSubresourceName, "/")
+		apiResourceName := arr[0]
+		subresourceKey := arr[1]
+		apiResource := gve.resources[apiResourceName]
+		if apiResource == nil {
+			continue
+		}
+		if apiResource.subresourceMap == nil {
+			apiResource.subresourceMap = make(map[string]bool)
 		}
+		apiResource.subresourceMap[subresourceKey] = true
+	}
+	return gve
+}
 
-		// Group all subresources for a resource.
-		for apiSubresourceName := range gve.subresources {
-			arr := strings.Split(apiSubresourceName, "/")
-			apiResourceName := arr[0]
-			subresourceKey := arr[1]
-			apiResource := gve.resources[apiResourceName]
-			if apiResource == nil {
-				continue
-			}
-			if apiResource.subresourceMap == nil {
-				apiResource.subresourceMap = make(map[string]bool)
+// sameResources returns true if two discovery snapshots have the same
+// resources, including subresources.
+func sameResources(a, b map[string]groupVersionEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for groupVersion, gveA := range a {
+		gveB, ok := b[groupVersion]
+		if !ok || len(gveA.resources) != len(gveB.resources) {
+			return false
+		}
+		for name, resourceA := range gveA.resources {
+			resourceB, ok := gveB.resources[name]
+			if !ok || resourceA.Kind != resourceB.Kind {
+				return false
 			}
-			apiResource.subresourceMap[subresourceKey] = true
 		}
-
-		groupVersions[group.GroupVersion] = gve
 	}
-
-	// Replace the local cache.
-	rm.mutex.Lock()
-	rm.groupVersions = groupVersions
-	rm.mutex.Unlock()
+	return true
 }
 
 func (rm *ResourceMap) Start(ctx context.Context, refreshInterval time.Duration) {
@@ -190,6 +259,14 @@ func (rm *ResourceMap) Start(ctx context.Context, refreshInterval time.Duration)
 	}()
 }
 
+// Generation returns a number that is incremented whenever a refresh finds
+// that resources were installed or removed. It is 0 until the first refresh.
+func (rm *ResourceMap) Generation() int64 {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	return rm.generation
+}
+
 func (rm *ResourceMap) HasSynced() bool {
 	rm.mutex.RLock()
 	defer rm.mutex.RUnlock()
@@ -197,7 +274,25 @@ func (rm *ResourceMap) HasSynced() bool {
 }
 
 func NewResourceMap(discoveryClient discovery.DiscoveryInterface) *ResourceMap {
-	return &ResourceMap{
+	rm := &ResourceMap{
 		discoveryClient: discoveryClient,
+		schemas:         make(map[string]*openAPIDocument),
+	}
+	// Fake discovery clients don't serve OpenAPI v3 documents.
+	if dc, ok := discoveryClient.(*discovery.DiscoveryClient); ok {
+		rm.openAPIClient = dc.OpenAPIV3()
+	}
+	return rm
+}
+
+// NewStaticResourceMap returns a ResourceMap that serves the given resources,
+// like discovery would, for use without an API server. It never refreshes and
+// knows only the schemas set by SetSchema.
+func NewStaticResourceMap(groups []*metav1.APIResourceList) *ResourceMap {
+	rm := NewResourceMap(nil)
+	rm.groupVersions = make(map[string]groupVersionEntry, len(groups))
+	for _, group := range groups {
+		rm.groupVersions[group.GroupVersion] = newGroupVersionEntry(group)
 	}
+	return rm
 }
@@ -0,0 +1,318 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi"
+
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+	"metacontroller/pkg/logging"
+)
+
+const componentSchemaRefPrefix = "#/components/schemas/"
+
+// openAPIDocument holds the merge schemas of all kinds in one group-version,
+// parsed from its OpenAPI v3 document.
+type openAPIDocument struct {
+	// url is the server-relative URL the document was fetched from. It
+	// contains a hash of the document, so it changes whenever the schema does.
+	url string
+	// kinds is nil if the document couldn't be fetched or parsed.
+	kinds map[string]*dynamicapply.Schema
+}
+
+// GetSchema returns the merge schema of the given kind, as published by the
+// API server in its OpenAPI v3 document. It returns nil if the schema is
+// unknown, in which case callers should fall back to guessing.
+//
+// Documents are fetched on first use and cached until the server publishes
+// a new version of them. Concurrent requests for the same document share one
+// fetch. Schemas set by SetSchema are returned without fetching anything.
+func (rm *ResourceMap) GetSchema(apiVersion, kind string) *dynamicapply.Schema {
+	if rm == nil {
+		return nil
+	}
+	rm.schemaMutex.Lock()
+	static, ok := rm.staticSchemas[apiVersion][kind]
+	rm.schemaMutex.Unlock()
+	if ok {
+		return static
+	}
+	if rm.openAPIClient == nil {
+		return nil
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil
+	}
+	path := openAPIPath(gv)
+
+	gvClient, ok := rm.openAPIPaths()[path]
+	if !ok {
+		return nil
+	}
+	url := gvClient.ServerRelativeURL()
+	rm.schemaMutex.Lock()
+	doc, ok := rm.schemas[path]
+	rm.schemaMutex.Unlock()
+	if ok && doc.url == url {
+		return doc.kinds[kind]
+	}
+
+	// The URL is part of the key, so that a fetch of an older version of the
+	// document isn't shared.
+	result, _, _ := rm.schemaFetches.Do("document "+url, func() (interface{}, error) {
+		generation := rm.currentSchemaGeneration()
+		doc := &openAPIDocument{url: url}
+		data, err := gvClient.Schema("application/json")
+		if err == nil {
+			doc.kinds, err = parseOpenAPIDocument(data, gv)
+		}
+		if err != nil {
+			// Remember the failure until the next discovery refresh.
+			logging.Logger.Error(err, "Failed to fetch OpenAPI v3 schema", "groupVersion", apiVersion)
+		}
+		rm.schemaMutex.Lock()
+		defer rm.schemaMutex.Unlock()
+		if rm.schemaGeneration == generation {
+			rm.schemas[path] = doc
+		}
+		return doc, nil
+	})
+	return result.(*openAPIDocument).kinds[kind]
+}
+
+// SetSchema makes GetSchema return the merge schema of the given OpenAPI v3
+// schema for a kind, like the openAPIV3Schema of a version of a
+// CustomResourceDefinition, instead of the one the API server publishes.
+// References to other schemas aren't resolved.
+func (rm *ResourceMap) SetSchema(apiVersion, kind string, openAPISchema map[string]interface{}) {
+	p := &schemaParser{refs: make(map[string]*dynamicapply.Schema)}
+	s := p.parse(openAPISchema)
+
+	rm.schemaMutex.Lock()
+	defer rm.schemaMutex.Unlock()
+	if rm.staticSchemas == nil {
+		rm.staticSchemas = make(map[string]map[string]*dynamicapply.Schema)
+	}
+	if rm.staticSchemas[apiVersion] == nil {
+		rm.staticSchemas[apiVersion] = make(map[string]*dynamicapply.Schema)
+	}
+	rm.staticSchemas[apiVersion][kind] = s
+}
+
+// openAPIPaths returns the OpenAPI v3 documents of the API server by path,
+// fetching them if they aren't known yet.
+func (rm *ResourceMap) openAPIPaths() map[string]openapi.GroupVersion {
+	rm.schemaMutex.Lock()
+	paths := rm.schemaPaths
+	rm.schemaMutex.Unlock()
+	if paths != nil {
+		return paths
+	}
+
+	result, _, _ := rm.schemaFetches.Do("paths", func() (interface{}, error) {
+		generation := rm.currentSchemaGeneration()
+		paths, err := rm.openAPIClient.Paths()
+		if err != nil {
+			// Don't retry until the next discovery refresh.
+			logging.Logger.Error(err, "Failed to fetch OpenAPI v3 paths")
+		}
+		if paths == nil {
+			paths = make(map[string]openapi.GroupVersion)
+		}
+		rm.schemaMutex.Lock()
+		defer rm.schemaMutex.Unlock()
+		if rm.schemaGeneration == generation {
+			rm.schemaPaths = paths
+		}
+		return paths, nil
+	})
+	return result.(map[string]openapi.GroupVersion)
+}
+
+// currentSchemaGeneration returns the number of times the schemas were reset.
+func (rm *ResourceMap) currentSchemaGeneration() int64 {
+	rm.schemaMutex.Lock()
+	defer rm.schemaMutex.Unlock()
+	return rm.schemaGeneration
+}
+
+// resetSchemas makes sure the OpenAPI paths are fetched again, and documents
+// that failed to load are retried, the next time a schema is requested.
+func (rm *ResourceMap) resetSchemas() {
+	rm.schemaMutex.Lock()
+	defer rm.schemaMutex.Unlock()
+
+	rm.schemaGeneration++
+	rm.schemaPaths = nil
+	for path, doc := range rm.schemas {
+		if doc.kinds == nil {
+			delete(rm.schemas, path)
+		}
+	}
+}
+
+// openAPIPath returns the path of the OpenAPI v3 document of a group-version,
+// relative to /openapi/v3.
+func openAPIPath(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return "api/" + gv.Version
+	}
+	return "apis/" + gv.Group + "/" + gv.Version
+}
+
+// parseOpenAPIDocument returns the merge schemas of all kinds in the given
+// group-version that the document defines.
+func parseOpenAPIDocument(data []byte, gv schema.GroupVersion) (map[string]*dynamicapply.Schema, error) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("can't unmarshal OpenAPI document: %w", err)
+	}
+
+	p := &schemaParser{
+		components: doc.Components.Schemas,
+		refs:       make(map[string]*dynamicapply.Schema),
+	}
+	kinds := make(map[string]*dynamicapply.Schema)
+	for name, component := range doc.Components.Schemas {
+		gvks, _ := component["x-kubernetes-group-version-kind"].([]interface{})
+		for _, item := range gvks {
+			gvk, _ := item.(map[string]interface{})
+			if gvk["group"] != gv.Group || gvk["version"] != gv.Version {
+				continue
+			}
+			if kind, ok := gvk["kind"].(string); ok {
+				kinds[kind] = p.parseRef(componentSchemaRefPrefix + name)
+			}
+		}
+	}
+	return kinds, nil
+}
+
+// schemaParser turns OpenAPI schemas into merge schemas, resolving references
+// to other components of the same document.
+type schemaParser struct {
+	components map[string]map[string]interface{}
+	// refs holds the schemas of all components parsed so far, including the
+	// ones still being parsed, so that recursive types terminate.
+	refs map[string]*dynamicapply.Schema
+}
+
+func (p *schemaParser) parseRef(ref string) *dynamicapply.Schema {
+	name := strings.TrimPrefix(ref, componentSchemaRefPrefix)
+	if s, ok := p.refs[name]; ok {
+		return s
+	}
+	component, ok := p.components[name]
+	if !ok {
+		return nil
+	}
+	s := &dynamicapply.Schema{}
+	p.refs[name] = s
+	parsed := p.parse(component)
+	if parsed == nil {
+		p.refs[name] = nil
+		return nil
+	}
+	*s = *parsed
+	return s
+}
+
+// parse returns the merge schema of an OpenAPI schema, or nil if the schema
+// doesn't say anything about the structure of the value.
+func (p *schemaParser) parse(node map[string]interface{}) *dynamicapply.Schema {
+	if ref, ok := node["$ref"].(string); ok {
+		return p.parseRef(ref)
+	}
+	properties, _ := node["properties"].(map[string]interface{})
+	items, _ := node["items"].(map[string]interface{})
+	nodeType, _ := node["type"].(string)
+	defaultValue := node["default"]
+	if allOf, ok := node["allOf"].([]interface{}); ok && len(allOf) == 1 && properties == nil && items == nil {
+		// Properties that reference another component wrap the reference,
+		// so they can add a description or default value.
+		if sub, ok := allOf[0].(map[string]interface{}); ok {
+			s := p.parse(sub)
+			if s == nil || defaultValue == nil {
+				return s
+			}
+			// Components are shared, so don't set the default on them.
+			withDefault := *s
+			withDefault.Default = defaultValue
+			return &withDefault
+		}
+	}
+	if nodeType == "" && properties == nil && items == nil {
+		return nil
+	}
+
+	s := &dynamicapply.Schema{Default: defaultValue}
+	if properties != nil {
+		s.Fields = make(map[string]*dynamicapply.Schema, len(properties))
+		for name, property := range properties {
+			if property, ok := property.(map[string]interface{}); ok {
+				s.Fields[name] = p.parse(property)
+			}
+		}
+	}
+	if additionalProperties, ok := node["additionalProperties"].(map[string]interface{}); ok {
+		s.AdditionalProperties = p.parse(additionalProperties)
+	}
+	if nodeType == "array" || items != nil {
+		if items != nil {
+			s.Items = p.parse(items)
+		}
+		s.ListType, s.ListMapKeys = listType(node)
+	}
+	return s
+}
+
+// listType returns how a list is merged. The x-kubernetes-list-type extension
+// takes precedence over the strategic merge patch extensions, which older
+// built-in types only have.
+func listType(node map[string]interface{}) (string, []string) {
+	if listType, ok := node["x-kubernetes-list-type"].(string); ok {
+		if listType != dynamicapply.ListTypeMap {
+			return listType, nil
+		}
+		keys, _ := node["x-kubernetes-list-map-keys"].([]interface{})
+		mapKeys := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if key, ok := key.(string); ok {
+				mapKeys = append(mapKeys, key)
+			}
+		}
+		return listType, mapKeys
+	}
+	if mergeKey, ok := node["x-kubernetes-patch-merge-key"].(string); ok {
+		return dynamicapply.ListTypeMap, []string{mergeKey}
+	}
+	if strategy, _ := node["x-kubernetes-patch-strategy"].(string); strings.Contains(strategy, "merge") {
+		return dynamicapply.ListTypeSet, nil
+	}
+	return dynamicapply.ListTypeAtomic, nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/openapi/openapitest"
+
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+func newSchemaResourceMap() *ResourceMap {
+	return &ResourceMap{
+		openAPIClient: openapitest.NewEmbeddedFileClient(),
+		schemas:       make(map[string]*openAPIDocument),
+	}
+}
+
+func TestGetSchema(t *testing.T) {
+	rm := newSchemaResourceMap()
+
+	deployment := rm.GetSchema("apps/v1", "Deployment")
+	if !assert.NotNil(t, deployment) {
+		return
+	}
+	podSpec := deployment.Fields["spec"].Fields["template"].Fields["spec"]
+	containers := podSpec.Fields["containers"]
+	assert.Equal(t, dynamicapply.ListTypeMap, containers.ListType)
+	assert.Equal(t, []string{"name"}, containers.ListMapKeys)
+	ports := containers.Items.Fields["ports"]
+	assert.Equal(t, dynamicapply.ListTypeMap, ports.ListType)
+	assert.Equal(t, []string{"containerPort", "protocol"}, ports.ListMapKeys)
+	assert.Equal(t, "TCP", ports.Items.Fields["protocol"].Default)
+	assert.Equal(t, dynamicapply.ListTypeAtomic, podSpec.Fields["tolerations"].ListType)
+	assert.NotNil(t, deployment.Fields["metadata"].Fields["labels"])
+
+	// Schemas are shared within a document.
+	assert.Same(t, deployment, rm.GetSchema("apps/v1", "Deployment"))
+	assert.Nil(t, rm.GetSchema("apps/v1", "Unknown"))
+	assert.Nil(t, rm.GetSchema("unknown.k8s.io/v1", "Deployment"))
+	assert.NotNil(t, rm.GetSchema("v1", "Pod"))
+}
+
+// blockingClient is an OpenAPI client whose fetches of the paths wait until
+// release is closed.
+type blockingClient struct {
+	openapi.Client
+	fetching chan struct{}
+	release  chan struct{}
+	fetches  int
+}
+
+func (c *blockingClient) Paths() (map[string]openapi.GroupVersion, error) {
+	c.fetches++
+	c.fetching <- struct{}{}
+	<-c.release
+	return c.Client.Paths()
+}
+
+func TestGetSchema_fetchesWithoutLock(t *testing.T) {
+	client := &blockingClient{
+		Client:   openapitest.NewEmbeddedFileClient(),
+		fetching: make(chan struct{}, 1),
+		release:  make(chan struct{}),
+	}
+	rm := newSchemaResourceMap()
+	rm.openAPIClient = client
+	done := make(chan *dynamicapply.Schema)
+	go func() { done <- rm.GetSchema("v1", "Pod") }()
+
+	<-client.fetching
+	// Resetting takes the lock, and makes the fetch in progress stale.
+	rm.resetSchemas()
+	close(client.release)
+
+	assert.NotNil(t, <-done)
+	assert.Nil(t, rm.schemaPaths)
+	assert.NotNil(t, rm.GetSchema("v1", "Pod"))
+	assert.Equal(t, 2, client.fetches)
+}
+
+func TestGetSchema_withoutOpenAPI(t *testing.T) {
+	var rm *ResourceMap
+	assert.Nil(t, rm.GetSchema("v1", "Pod"))
+	assert.Nil(t, (&ResourceMap{}).GetSchema("v1", "Pod"))
+}
+
+func TestListType(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     map[string]interface{}
+		wantType string
+		wantKeys []string
+	}{
+		{
+			name:     "list map",
+			node:     map[string]interface{}{"x-kubernetes-list-type": "map", "x-kubernetes-list-map-keys": []interface{}{"name", "namespace"}},
+			wantType: dynamicapply.ListTypeMap,
+			wantKeys: []string{"name", "namespace"},
+		},
+		{
+			name:     "list type takes precedence over patch merge key",
+			node:     map[string]interface{}{"x-kubernetes-list-type": "atomic", "x-kubernetes-patch-merge-key": "name"},
+			wantType: dynamicapply.ListTypeAtomic,
+		},
+		{
+			name:     "patch merge key",
+			node:     map[string]interface{}{"x-kubernetes-patch-merge-key": "name", "x-kubernetes-patch-strategy": "merge"},
+			wantType: dynamicapply.ListTypeMap,
+			wantKeys: []string{"name"},
+		},
+		{
+			name:     "patch strategy without key",
+			node:     map[string]interface{}{"x-kubernetes-patch-strategy": "merge"},
+			wantType: dynamicapply.ListTypeSet,
+		},
+		{
+			name:     "no extensions",
+			node:     map[string]interface{}{},
+			wantType: dynamicapply.ListTypeAtomic,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotKeys := listType(tt.node)
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Equal(t, tt.wantKeys, gotKeys)
+		})
+	}
+}
+
+func TestSetSchema(t *testing.T) {
+	rm := NewStaticResourceMap(nil)
+	rm.SetSchema("example.com/v1", "Thing", map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"ports": map[string]interface{}{
+						"type":                       "array",
+						"x-kubernetes-list-type":     "map",
+						"x-kubernetes-list-map-keys": []interface{}{"port"},
+						"items":                      map[string]interface{}{"type": "object"},
+					},
+				},
+			},
+		},
+	})
+
+	thing := rm.GetSchema("example.com/v1", "Thing")
+	if !assert.NotNil(t, thing) {
+		return
+	}
+	ports := thing.Fields["spec"].Fields["ports"]
+	assert.Equal(t, dynamicapply.ListTypeMap, ports.ListType)
+	assert.Equal(t, []string{"port"}, ports.ListMapKeys)
+	assert.Nil(t, rm.GetSchema("example.com/v1", "Other"))
+}
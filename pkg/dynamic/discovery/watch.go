@@ -0,0 +1,244 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"metacontroller/pkg/logging"
+)
+
+var (
+	crdResource        = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	apiServiceResource = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+)
+
+// discoveryLag is how long after a CRD change the affected group is refreshed
+// a second time, since the API server may update its discovery documents
+// shortly after the CRD status.
+const discoveryLag = 2 * time.Second
+
+// refreshKey identifies what to refresh: a group-version, or all known
+// versions of a group if the version is empty.
+type refreshKey struct {
+	group, version string
+}
+
+// AddChangeHandler registers a function that is called whenever a refresh
+// finds that resources were installed or removed.
+func (rm *ResourceMap) AddChangeHandler(handler func()) {
+	rm.handlerMutex.Lock()
+	defer rm.handlerMutex.Unlock()
+	rm.changeHandlers = append(rm.changeHandlers, handler)
+}
+
+func (rm *ResourceMap) notifyChange() {
+	rm.handlerMutex.Lock()
+	handlers := append([]func(){}, rm.changeHandlers...)
+	rm.handlerMutex.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+// Watch refreshes the affected group-versions as soon as CRDs or APIServices
+// change, instead of waiting for the next full refresh. It only watches the
+// metadata of CRDs and APIServices, since their names tell which
+// group-versions they serve.
+func (rm *ResourceMap) Watch(ctx context.Context, client metadata.Interface) {
+	queue := workqueue.NewTypedRateLimitingQueueWithConfig(
+		workqueue.DefaultTypedControllerRateLimiter[refreshKey](),
+		workqueue.TypedRateLimitingQueueConfig[refreshKey]{
+			Name: "discovery",
+		},
+	)
+	informers := metadatainformer.NewSharedInformerFactory(client, 0)
+
+	crdHandler := func(obj interface{}) {
+		// CRDs are named <plural>.<group>, and a change may affect any of the
+		// versions of the group.
+		_, group, ok := splitName(obj)
+		if !ok {
+			return
+		}
+		key := refreshKey{group: group}
+		queue.Add(key)
+		queue.AddAfter(key, discoveryLag)
+	}
+	apiServiceHandler := func(obj interface{}) {
+		// APIServices are named <version>.<group>, and the core group-version
+		// is named "v1.".
+		version, group, ok := splitName(obj)
+		if !ok {
+			return
+		}
+		queue.Add(refreshKey{group: group, version: version})
+	}
+	for gvr, handler := range map[schema.GroupVersionResource]func(obj interface{}){
+		crdResource:        crdHandler,
+		apiServiceResource: apiServiceHandler,
+	} {
+		_, err := informers.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				// The initial list is covered by the full refresh.
+				if !isInInitialList {
+					handler(obj)
+				}
+			},
+			UpdateFunc: func(old, cur interface{}) { handler(cur) },
+			DeleteFunc: handler,
+		})
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("can't watch %v for discovery changes: %w", gvr.Resource, err))
+		}
+	}
+	informers.Start(ctx.Done())
+
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+		informers.Shutdown()
+	}()
+	go func() {
+		for {
+			key, quit := queue.Get()
+			if quit {
+				return
+			}
+			if err := rm.refreshKey(key); err != nil {
+				utilruntime.HandleError(fmt.Errorf("failed to refresh discovery for %v: %w", key, err))
+				queue.AddRateLimited(key)
+			} else {
+				queue.Forget(key)
+			}
+			queue.Done(key)
+		}
+	}()
+}
+
+// splitName splits the name of a CRD or APIService at the first dot.
+func splitName(obj interface{}) (prefix, group string, ok bool) {
+	if tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown); isTombstone {
+		obj = tombstone.Obj
+	}
+	meta, isMeta := obj.(metav1.Object)
+	if !isMeta {
+		return "", "", false
+	}
+	return strings.Cut(meta.GetName(), ".")
+}
+
+func (rm *ResourceMap) refreshKey(key refreshKey) error {
+	if key.version != "" {
+		return rm.refreshGroupVersions(schema.GroupVersion{Group: key.group, Version: key.version}.String())
+	}
+
+	var groupVersions []string
+	rm.mutex.RLock()
+	for groupVersion := range rm.groupVersions {
+		if gv, err := schema.ParseGroupVersion(groupVersion); err == nil && gv.Group == key.group {
+			groupVersions = append(groupVersions, groupVersion)
+		}
+	}
+	rm.mutex.RUnlock()
+
+	if len(groupVersions) == 0 {
+		// The group is new, so ask which versions it serves.
+		groups, err := rm.discoveryClient.ServerGroups()
+		if err != nil {
+			return err
+		}
+		for _, group := range groups.Groups {
+			if group.Name != key.group {
+				continue
+			}
+			for _, version := range group.Versions {
+				groupVersions = append(groupVersions, version.GroupVersion)
+			}
+		}
+	}
+	return rm.refreshGroupVersions(groupVersions...)
+}
+
+// refreshGroupVersions fetches the resources of the given group-versions
+// from the server, and replaces them in the local cache. Group-versions that
+// the server doesn't serve anymore are removed.
+func (rm *ResourceMap) refreshGroupVersions(groupVersions ...string) error {
+	rm.refreshMutex.Lock()
+	defer rm.refreshMutex.Unlock()
+
+	if !rm.HasSynced() {
+		// The first full refresh will pick up everything.
+		return nil
+	}
+
+	var errs []error
+	updates := make(map[string]*groupVersionEntry, len(groupVersions))
+	for _, groupVersion := range groupVersions {
+		logging.Logger.V(4).Info("Refreshing API discovery info", "groupVersion", groupVersion)
+		list, err := rm.discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+		switch {
+		case apierrors.IsNotFound(err):
+			updates[groupVersion] = nil
+		case err != nil:
+			errs = append(errs, err)
+		default:
+			gve := newGroupVersionEntry(list)
+			updates[groupVersion] = &gve
+		}
+	}
+
+	rm.mutex.Lock()
+	result := make(map[string]groupVersionEntry, len(rm.groupVersions))
+	for groupVersion, gve := range rm.groupVersions {
+		result[groupVersion] = gve
+	}
+	for groupVersion, gve := range updates {
+		if gve == nil {
+			delete(result, groupVersion)
+		} else {
+			result[groupVersion] = *gve
+		}
+	}
+	changed := !sameResources(rm.groupVersions, result)
+	if changed {
+		rm.generation++
+	}
+	rm.groupVersions = result
+	rm.mutex.Unlock()
+
+	if changed {
+		logging.Logger.Info("API discovery info changed", "groupVersions", groupVersions)
+		rm.resetSchemas()
+		rm.notifyChange()
+	}
+	return utilerrors.NewAggregate(errs)
+}
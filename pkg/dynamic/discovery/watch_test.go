@@ -0,0 +1,106 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+var widgets = &metav1.APIResourceList{
+	GroupVersion: "example.com/v1",
+	APIResources: []metav1.APIResource{
+		{Name: "widgets", Kind: "Widget", Namespaced: true},
+		{Name: "widgets/status", Kind: "Widget", Namespaced: true},
+	},
+}
+
+func newFakeResourceMap(t *testing.T) (*ResourceMap, *fakediscovery.FakeDiscovery, *atomic.Int32) {
+	fake := fakeclientset.NewClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	fake.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods", Kind: "Pod", Namespaced: true}},
+	}}
+	rm := NewResourceMap(fake)
+	rm.refresh()
+	if !rm.HasSynced() {
+		t.Fatal("discovery didn't sync")
+	}
+	changes := &atomic.Int32{}
+	rm.AddChangeHandler(func() { changes.Add(1) })
+	return rm, fake, changes
+}
+
+func TestRefreshGroupVersions(t *testing.T) {
+	rm, fake, changes := newFakeResourceMap(t)
+
+	fake.Resources = append(fake.Resources, widgets)
+	assert.NoError(t, rm.refreshKey(refreshKey{group: "example.com", version: "v1"}))
+	widget := rm.Get("example.com/v1", "widgets")
+	if assert.NotNil(t, widget) {
+		assert.True(t, widget.HasSubresource("status"))
+	}
+	assert.NotNil(t, rm.GetKind("example.com/v1", "Widget"))
+	assert.NotNil(t, rm.Get("v1", "pods"), "other group-versions are kept")
+	assert.Equal(t, int32(1), changes.Load())
+
+	// Refreshing the known versions of the group finds no change.
+	assert.NoError(t, rm.refreshKey(refreshKey{group: "example.com"}))
+	assert.Equal(t, int32(1), changes.Load())
+
+	fake.Resources = fake.Resources[:1]
+	assert.NoError(t, rm.refreshKey(refreshKey{group: "example.com"}))
+	assert.Nil(t, rm.Get("example.com/v1", "widgets"))
+	assert.Equal(t, int32(2), changes.Load())
+}
+
+func TestWatch(t *testing.T) {
+	rm, fake, changes := newFakeResourceMap(t)
+	scheme := metadatafake.NewTestScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	client := metadatafake.NewSimpleMetadataClient(scheme)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rm.Watch(ctx, client)
+
+	fake.Resources = append(fake.Resources, widgets)
+	apiService := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apiregistration.k8s.io/v1", Kind: "APIService"},
+		ObjectMeta: metav1.ObjectMeta{Name: "v1.example.com"},
+	}
+	// The informer may still be listing, so keep creating until it sees an
+	// event.
+	assert.Eventually(t, func() bool {
+		resource := client.Resource(apiServiceResource).(metadatafake.MetadataClient)
+		if _, err := resource.CreateFake(apiService, metav1.CreateOptions{}); err != nil {
+			_, _ = resource.UpdateFake(apiService, metav1.UpdateOptions{})
+		}
+		return rm.Get("example.com/v1", "widgets") != nil
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.Equal(t, int32(1), changes.Load())
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"metacontroller/pkg/logging"
+	"sort"
 	"sync"
 	"time"
 
@@ -32,6 +33,7 @@ import (
 type SharedInformerFactory struct {
 	clientset     *dynamicclientset.Clientset
 	defaultResync time.Duration
+	stripOptions  StripOptions
 
 	mutex           sync.Mutex
 	refCount        map[string]int
@@ -49,6 +51,16 @@ func NewSharedInformerFactory(clientset *dynamicclientset.Clientset, defaultResy
 	}
 }
 
+// SetStripOptions sets what informers drop from cached objects. It only
+// applies to informers that are started afterwards, so call it before
+// requesting any informers.
+func (f *SharedInformerFactory) SetStripOptions(options StripOptions) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.stripOptions = options
+}
+
 // Resource returns a dynamic informer and lister for the given resource.
 // These are shared with any other controllers in the same process that request
 // the same resource.
@@ -58,15 +70,29 @@ func NewSharedInformerFactory(clientset *dynamicclientset.Clientset, defaultResy
 // Shared informers that become unused will be stopped to minimize our load on
 // the API server.
 func (f *SharedInformerFactory) Resource(ctx context.Context, apiVersion, resource string) (*ResourceInformer, error) {
+	return f.resource(ctx, apiVersion, resource, false)
+}
+
+// MetadataResource returns a dynamic informer and lister for the given
+// resource, which only cache the metadata of objects. It's otherwise like
+// Resource, but doesn't share informers with it.
+func (f *SharedInformerFactory) MetadataResource(ctx context.Context, apiVersion, resource string) (*ResourceInformer, error) {
+	return f.resource(ctx, apiVersion, resource, true)
+}
+
+func (f *SharedInformerFactory) resource(ctx context.Context, apiVersion, resource string, metadataOnly bool) (*ResourceInformer, error) {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
 	// Return existing informer if there is one.
 	key := resourceKey(apiVersion, resource)
+	if metadataOnly {
+		key += "/metadata"
+	}
 	if sharedInformer, ok := f.sharedInformers[key]; ok {
 		count := f.refCount[key] + 1
 		f.refCount[key] = count
-		logging.Logger.V(4).Info("Subscribed to shared informer", "resource", resource, "api_version", apiVersion, "total_subscribers", count)
+		logging.Logger.V(4).Info("Subscribed to shared informer", "resource", resource, "api_version", apiVersion, "metadata_only", metadataOnly, "total_subscribers", count)
 		return newResourceInformer(sharedInformer), nil
 	}
 
@@ -97,12 +123,13 @@ func (f *SharedInformerFactory) Resource(ctx context.Context, apiVersion, resour
 		// We're the last ones using it.
 		logging.Logger.V(4).Info("Stopping shared informer (no more subscribers)", "resource", resource, "api_version", apiVersion, "total_subscribers", count)
 		close(stopCh)
+		f.sharedInformers[key].stripper.reset()
 		delete(f.refCount, key)
 		delete(f.sharedInformers, key)
 	}
 
-	logging.Logger.V(4).Info("Starting shared informer", "resource", resource, "api_version", apiVersion)
-	sharedInformer, err := newSharedResourceInformer(ctx, client, f.defaultResync, closeFn)
+	logging.Logger.V(4).Info("Starting shared informer", "resource", resource, "api_version", apiVersion, "metadata_only", metadataOnly)
+	sharedInformer, err := newSharedResourceInformer(ctx, client, metadataOnly, f.stripOptions, f.defaultResync, closeFn)
 	if err != nil {
 		return nil, fmt.Errorf("can't create client for %v shared informer: %w", key, err)
 	}
@@ -116,6 +143,52 @@ func (f *SharedInformerFactory) Resource(ctx context.Context, apiVersion, resour
 	return newResourceInformer(sharedInformer), nil
 }
 
+// Unsynced returns the keys of the shared informers that haven't synced yet,
+// sorted.
+func (f *SharedInformerFactory) Unsynced() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var keys []string
+	for key, sharedInformer := range f.sharedInformers {
+		if !sharedInformer.informer.HasSynced() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// InformerStats describes a shared informer.
+type InformerStats struct {
+	Resource     string `json:"resource"`
+	Subscribers  int    `json:"subscribers"`
+	MetadataOnly bool   `json:"metadataOnly"`
+	Synced       bool   `json:"synced"`
+	Objects      int    `json:"objects"`
+}
+
+// Stats describes the running shared informers, sorted by resource.
+func (f *SharedInformerFactory) Stats() []InformerStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	stats := make([]InformerStats, 0, len(f.sharedInformers))
+	for key, sharedInformer := range f.sharedInformers {
+		stats = append(stats, InformerStats{
+			Resource:     key,
+			Subscribers:  f.refCount[key],
+			MetadataOnly: sharedInformer.metadataOnly,
+			Synced:       sharedInformer.informer.HasSynced(),
+			Objects:      len(sharedInformer.informer.GetStore().ListKeys()),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Resource < stats[j].Resource
+	})
+	return stats
+}
+
 func (f *SharedInformerFactory) IsInitialized() bool {
 	return f != nil && f.clientset != nil
 }
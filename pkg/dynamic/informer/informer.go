@@ -182,6 +182,12 @@ func (ri *ResourceInformer) byIndexAndNamespace(indexName, key string, namespace
 	return result, nil
 }
 
+// IsMetadataOnly returns true if the cached objects only have metadata, in
+// which case the full objects have to be fetched from the API server.
+func (ri *ResourceInformer) IsMetadataOnly() bool {
+	return ri.sharedResourceInformer.metadataOnly
+}
+
 // Close marks this ResourceInformer as unused, allowing the underlying shared
 // informer to be stopped when no users are left.
 // You should call this when you no longer need the informer, so the watches
@@ -197,6 +203,11 @@ type sharedResourceInformer struct {
 	informer cache.SharedIndexInformer
 	lister   dynamiclister.Lister
 
+	// metadataOnly is true if the informer caches only the metadata of
+	// objects.
+	metadataOnly bool
+	stripper     *stripper
+
 	defaultResyncPeriod time.Duration
 
 	eventHandlers *sharedEventHandler
@@ -205,19 +216,24 @@ type sharedResourceInformer struct {
 	close func()
 }
 
-func newSharedResourceInformer(ctx context.Context, client *dynamicclientset.ResourceClient, defaultResyncPeriod time.Duration, close func()) (*sharedResourceInformer, error) {
+func newSharedResourceInformer(ctx context.Context, client *dynamicclientset.ResourceClient, metadataOnly bool, stripOptions StripOptions, defaultResyncPeriod time.Duration, close func()) (*sharedResourceInformer, error) {
+	listWatch := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return client.List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return client.Watch(ctx, opts)
+		},
+	}
+	if metadataOnly {
+		var err error
+		listWatch, err = newMetadataListWatch(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+	}
 	informer := cache.NewSharedIndexInformer(
-		cache.ToListWatcherWithWatchListSemantics(
-			&cache.ListWatch{
-				ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
-					return client.List(ctx, opts)
-				},
-				WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
-					return client.Watch(ctx, opts)
-				},
-			},
-			client,
-		),
+		cache.ToListWatcherWithWatchListSemantics(listWatch, client),
 		&unstructured.Unstructured{},
 		defaultResyncPeriod,
 		cache.Indexers{
@@ -226,9 +242,18 @@ func newSharedResourceInformer(ctx context.Context, client *dynamicclientset.Res
 			OrphanIndex:          orphanIndexFunc,
 		},
 	)
+	stripper := newStripper(stripOptions, client, metadataOnly)
+	if err := informer.SetTransform(stripper.transform); err != nil {
+		return nil, err
+	}
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{DeleteFunc: stripper.forget}); err != nil {
+		return nil, err
+	}
 	sri := &sharedResourceInformer{
 		close:               close,
 		informer:            informer,
+		stripper:            stripper,
+		metadataOnly:        metadataOnly,
 		defaultResyncPeriod: defaultResyncPeriod,
 
 		//lister: dynamiclister.New(client.GroupResource(), informer.GetIndexer()),
@@ -0,0 +1,93 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// newMetadataListWatch returns a ListWatch that reads only the metadata of
+// objects (as PartialObjectMetadata), and returns them as Unstructured objects
+// of the resource's kind, so they can be cached and listed like full objects.
+func newMetadataListWatch(ctx context.Context, client *dynamicclientset.ResourceClient) (*cache.ListWatch, error) {
+	metadataClient, err := client.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	gvk := client.GroupVersionKind()
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			list, err := metadataClient.List(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			result := &unstructured.UnstructuredList{}
+			result.SetResourceVersion(list.ResourceVersion)
+			result.SetContinue(list.Continue)
+			result.SetRemainingItemCount(list.RemainingItemCount)
+			result.Items = make([]unstructured.Unstructured, 0, len(list.Items))
+			for i := range list.Items {
+				obj, err := metadataToUnstructured(&list.Items[i], gvk)
+				if err != nil {
+					return nil, err
+				}
+				result.Items = append(result.Items, *obj)
+			}
+			return result, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			w, err := metadataClient.Watch(ctx, opts)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				if object, ok := event.Object.(*metav1.PartialObjectMetadata); ok {
+					obj, err := metadataToUnstructured(object, gvk)
+					if err != nil {
+						return watch.Event{Type: watch.Error, Object: &metav1.Status{
+							Status:  metav1.StatusFailure,
+							Message: err.Error(),
+						}}, true
+					}
+					event.Object = obj
+				}
+				return event, true
+			}), nil
+		},
+	}, nil
+}
+
+// metadataToUnstructured converts the metadata of an object to an
+// Unstructured object of the given kind, without spec or status.
+func metadataToUnstructured(object *metav1.PartialObjectMetadata, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&object.ObjectMeta)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"metadata": content}}
+	obj.SetGroupVersionKind(gvk)
+	return obj, nil
+}
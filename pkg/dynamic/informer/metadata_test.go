@@ -0,0 +1,83 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/cache"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	. "metacontroller/pkg/internal/testutils/common"
+	. "metacontroller/pkg/internal/testutils/dynamic/clientset"
+	. "metacontroller/pkg/internal/testutils/dynamic/discovery"
+)
+
+func TestSharedInformerFactory_MetadataResource(t *testing.T) {
+	scheme := metadatafake.NewTestScheme()
+	if err := metav1.AddMetaToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	object := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: TestAPIVersion, Kind: TestKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: TestNamespace,
+			Name:      TestName,
+			Labels:    map[string]string{"app": "test"},
+		},
+	}
+	resourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+	clientset := dynamicclientset.NewClientsetWithMetadata(NewDefaultRestConfig(), resourceMap,
+		dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		metadatafake.NewSimpleMetadataClient(scheme, object))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	informer, err := NewSharedInformerFactory(clientset, time.Minute).MetadataResource(ctx, TestAPIVersion, TestResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer informer.Close()
+	if !informer.IsMetadataOnly() {
+		t.Error("IsMetadataOnly() = false, want true")
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), informer.Informer().HasSynced) {
+		t.Fatal("timed out waiting for informer sync")
+	}
+
+	objects, err := informer.Lister().List(labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("List() returned %d objects, want 1", len(objects))
+	}
+	obj := objects[0]
+	if obj.GetAPIVersion() != TestAPIVersion || obj.GetKind() != TestKind {
+		t.Errorf("cached object is a %s %s, want a %s %s", obj.GetAPIVersion(), obj.GetKind(), TestAPIVersion, TestKind)
+	}
+	if obj.GetName() != TestName || obj.GetLabels()["app"] != "test" {
+		t.Errorf("cached object has metadata %v", obj.Object["metadata"])
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+var strippedBytes = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "metacontroller",
+		Subsystem: "informer",
+		Name:      "stripped_bytes",
+		Help:      "The approximate size, in bytes of JSON, of the data stripped from the objects cached by an informer.",
+	},
+	[]string{"group", "resource", "cache"},
+)
+
+func init() {
+	controllerruntimemetrics.Registry.MustRegister(strippedBytes)
+}
+
+// StripOptions selects what informers drop from cached objects, in addition
+// to metadata.managedFields, which is always dropped.
+type StripOptions struct {
+	// Annotations are the keys of the annotations to drop.
+	Annotations []string
+	// Fields are the dot-separated paths of the fields to drop, like
+	// "status.conditions".
+	Fields []string
+}
+
+// Lossy returns true if the cached objects lack data that updates of the
+// objects should preserve, so that the live objects have to be read before
+// updating them. Updates that leave out metadata.managedFields preserve them.
+func (o StripOptions) Lossy() bool {
+	return len(o.Annotations) != 0 || len(o.Fields) != 0
+}
+
+// StripsAnnotation returns true if the annotation with the given key is
+// dropped from cached objects.
+func (o StripOptions) StripsAnnotation(key string) bool {
+	for _, annotation := range o.Annotations {
+		if annotation == key {
+			return true
+		}
+	}
+	return false
+}
+
+// StripsWithin returns true if the field, given as a dot-separated path, or
+// any field within it is dropped from cached objects.
+func (o StripOptions) StripsWithin(field string) bool {
+	for _, stripped := range o.Fields {
+		if stripped == field || strings.HasPrefix(stripped, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets returns true if the object has any of the annotations or fields that
+// are dropped from cached objects, so that comparing it with a cached object
+// finds differences that may not exist.
+func (o StripOptions) Sets(obj map[string]interface{}) bool {
+	annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+	for _, key := range o.Annotations {
+		if _, ok := annotations[key]; ok {
+			return true
+		}
+	}
+	for _, field := range o.Fields {
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj, strings.Split(field, ".")...); found {
+			return true
+		}
+	}
+	return false
+}
+
+// Strip drops the annotations and fields from the object, like informers do.
+func (o StripOptions) Strip(obj map[string]interface{}) {
+	for _, key := range o.Annotations {
+		removeField(obj, "metadata", "annotations", key)
+	}
+	for _, field := range o.Fields {
+		removeField(obj, strings.Split(field, ".")...)
+	}
+}
+
+// stripper is the transform of an informer, which strips objects before they
+// are cached. It keeps track of how much it stripped from each cached object.
+type stripper struct {
+	annotations []string
+	fields      [][]string
+	labels      []string
+
+	mutex sync.Mutex
+	sizes map[string]int
+}
+
+func newStripper(options StripOptions, client *dynamicclientset.ResourceClient, metadataOnly bool) *stripper {
+	fields := make([][]string, 0, len(options.Fields))
+	for _, field := range options.Fields {
+		fields = append(fields, strings.Split(field, "."))
+	}
+	return &stripper{
+		annotations: options.Annotations,
+		fields:      fields,
+		labels:      []string{client.Group, client.Name, cacheLabel(metadataOnly)},
+		sizes:       make(map[string]int),
+	}
+}
+
+func cacheLabel(metadataOnly bool) string {
+	if metadataOnly {
+		return "metadata"
+	}
+	return "full"
+}
+
+// transform is a cache.TransformFunc, which strips the object in place.
+func (s *stripper) transform(obj interface{}) (interface{}, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return obj, nil
+	}
+	size := removeField(u.Object, "metadata", "managedFields")
+	for _, key := range s.annotations {
+		size += removeField(u.Object, "metadata", "annotations", key)
+	}
+	for _, field := range s.fields {
+		size += removeField(u.Object, field...)
+	}
+	if key, err := cache.MetaNamespaceKeyFunc(u); err == nil {
+		s.record(key, size)
+	}
+	return u, nil
+}
+
+// removeField removes a field, and returns its size.
+func removeField(obj map[string]interface{}, fields ...string) int {
+	value, found, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if err != nil || !found {
+		return 0
+	}
+	unstructured.RemoveNestedField(obj, fields...)
+	return len(fields[len(fields)-1]) + jsonSize(value)
+}
+
+func jsonSize(value interface{}) int {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+func (s *stripper) record(key string, size int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	strippedBytes.WithLabelValues(s.labels...).Add(float64(size - s.sizes[key]))
+	if size == 0 {
+		delete(s.sizes, key)
+	} else {
+		s.sizes[key] = size
+	}
+}
+
+// forget stops accounting for a deleted object.
+func (s *stripper) forget(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	s.record(key, 0)
+}
+
+// reset stops accounting for all objects, when the informer stops.
+func (s *stripper) reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	strippedBytes.DeleteLabelValues(s.labels...)
+	s.sizes = make(map[string]int)
+}
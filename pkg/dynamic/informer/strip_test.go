@@ -0,0 +1,123 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	. "metacontroller/pkg/internal/testutils/common"
+	. "metacontroller/pkg/internal/testutils/dynamic/clientset"
+	. "metacontroller/pkg/internal/testutils/dynamic/discovery"
+)
+
+func TestStripper(t *testing.T) {
+	resourceMap := NewFakeResourceMap(NewFakeClientsetWithResources(NewDefaultAPIResourceList()))
+	clientset := dynamicclientset.NewClientset(NewDefaultRestConfig(), resourceMap, fake.NewSimpleDynamicClient(runtime.NewScheme()))
+	client, err := clientset.Resource(TestAPIVersion, TestResource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newStripper(StripOptions{
+		Annotations: []string{"example.com/big"},
+		Fields:      []string{"status.conditions"},
+	}, client, false)
+	defer s.reset()
+
+	obj := NewDefaultUnstructured()
+	obj.SetAnnotations(map[string]string{"example.com/big": "data", "example.com/kept": "true"})
+	obj.Object["metadata"].(map[string]interface{})["managedFields"] = []interface{}{
+		map[string]interface{}{"manager": "test"},
+	}
+	obj.Object["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready"}},
+		"phase":      "Running",
+	}
+
+	result, err := s.transform(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stripped := result.(*unstructured.Unstructured)
+	if stripped.GetManagedFields() != nil {
+		t.Errorf("managedFields = %v, want none", stripped.GetManagedFields())
+	}
+	if want := map[string]string{"example.com/kept": "true"}; !maps.Equal(stripped.GetAnnotations(), want) {
+		t.Errorf("annotations = %v, want %v", stripped.GetAnnotations(), want)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(stripped.Object, "status", "conditions"); found {
+		t.Error("status.conditions wasn't stripped")
+	}
+	if phase, _, _ := unstructured.NestedString(stripped.Object, "status", "phase"); phase != "Running" {
+		t.Errorf("status.phase = %q, want %q", phase, "Running")
+	}
+
+	gauge := strippedBytes.WithLabelValues(client.Group, client.Name, "full")
+	size := testutil.ToFloat64(gauge)
+	if size <= 0 {
+		t.Fatalf("stripped bytes = %v, want more than 0", size)
+	}
+
+	// Stripping the object again replaces its size instead of adding to it.
+	again := NewDefaultUnstructured()
+	again.SetAnnotations(map[string]string{"example.com/big": "data"})
+	if _, err := s.transform(again); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := testutil.ToFloat64(gauge), float64(len("example.com/big")+len(`"data"`)); got != want {
+		t.Errorf("stripped bytes = %v, want %v", got, want)
+	}
+
+	s.forget(again)
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Errorf("stripped bytes after delete = %v, want 0", got)
+	}
+}
+
+func TestStripOptions(t *testing.T) {
+	options := StripOptions{
+		Annotations: []string{"example.com/big"},
+		Fields:      []string{"status.conditions"},
+	}
+	if !options.StripsWithin("status") || options.StripsWithin("spec") || options.StripsWithin("stat") {
+		t.Errorf("StripsWithin() is wrong for %v", options.Fields)
+	}
+
+	obj := NewDefaultUnstructured()
+	if options.Sets(obj.Object) {
+		t.Errorf("Sets(%v) = true, want false", obj.Object)
+	}
+	obj.SetAnnotations(map[string]string{"example.com/big": "data"})
+	if !options.Sets(obj.Object) {
+		t.Errorf("Sets(%v) = false, want true", obj.Object)
+	}
+	options.Strip(obj.Object)
+	obj.Object["status"] = map[string]interface{}{"conditions": []interface{}{}}
+	if !options.Sets(obj.Object) {
+		t.Errorf("Sets(%v) = false, want true", obj.Object)
+	}
+	options.Strip(obj.Object)
+	if options.Sets(obj.Object) {
+		t.Errorf("Sets() = true after Strip(), want false: %v", obj.Object)
+	}
+}
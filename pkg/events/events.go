@@ -15,6 +15,17 @@ const (
 	ReasonStopping    string = "Stopping"
 	ReasonSyncError   string = "SyncError"
 	ReasonCreateError string = "CreateError"
+
+	ReasonRollbackStarted  string = "RollbackStarted"
+	ReasonRollbackSkipped  string = "RollbackSkipped"
+	ReasonRollbackComplete string = "RollbackComplete"
+	ReasonRollbackFailed   string = "RollbackFailed"
+
+	ReasonProgressDeadlineExceeded string = "ProgressDeadlineExceeded"
+
+	ReasonDryRun string = "DryRun"
+
+	ReasonRecreatingChild string = "RecreatingChild"
 )
 
 func NewBroadcaster(config *rest.Config, options record.CorrelatorOptions) (record.EventBroadcaster, error) {
@@ -11,3 +11,8 @@ type TooManyRequestError struct {
 func (e *TooManyRequestError) Error() string {
 	return fmt.Sprintf("Too many request, it will be resync after: %d", e.AfterSecond)
 }
+
+// SyncErrorReason implements common.SyncErrorReasoner.
+func (e *TooManyRequestError) SyncErrorReason() string {
+	return "HookTooManyRequests"
+}
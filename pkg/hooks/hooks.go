@@ -51,6 +51,24 @@ func NewHook(
 	}, nil
 }
 
+// CacheSize returns the number of responses that a hook caches for ETags, or 0
+// if it doesn't cache any.
+func CacheSize(hook Hook) int {
+	impl, ok := hook.(*hookExecutorImpl)
+	if !ok {
+		return 0
+	}
+	executor, ok := impl.webhookExecutor.(*webhookExecutor)
+	if !ok {
+		return 0
+	}
+	etag, ok := executor.webhookAbstract.(*webhookExecutorEtag)
+	if !ok {
+		return 0
+	}
+	return etag.etagCache.Len()
+}
+
 // hookExecutorImpl is default implementation of Hook
 type hookExecutorImpl struct {
 	webhookExecutor WebhookExecutor
@@ -0,0 +1,65 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// inProcessURL is the URL that in-process hooks are called at.
+const inProcessURL = "http://in-process/"
+
+// NewInProcessHook returns a Hook that calls the given handler in-process,
+// the same way as the webhook of the given hook would be called, without a
+// network. The Hook is disabled if the given hook is nil.
+func NewInProcessHook(hook *v1alpha1.Hook, hookType common.HookType, handler http.Handler) Hook {
+	if hook == nil {
+		return &hookExecutorImpl{}
+	}
+	var unmarshallMode *v1alpha1.ResponseUnmarshallMode
+	if hook.Webhook != nil {
+		unmarshallMode = hook.Webhook.ResponseUnmarshallMode
+	}
+	return &hookExecutorImpl{
+		webhookExecutor: newWebhookExecutor(
+			handlerClient{handler: handler},
+			inProcessURL,
+			hookType,
+			hook.Version,
+			unmarshallMode,
+			&webhookExecutorPlain{},
+			"",
+			time.Now,
+		),
+	}
+}
+
+// handlerClient serves requests with a handler, in-process.
+type handlerClient struct {
+	handler http.Handler
+}
+
+func (c handlerClient) Do(request *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, request)
+	return recorder.Result(), nil
+}
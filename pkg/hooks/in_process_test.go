@@ -0,0 +1,74 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	v1 "metacontroller/pkg/controller/common/customize/api/v1"
+	"metacontroller/pkg/logging"
+)
+
+func TestNewInProcessHook_whenNilHook_returnDisabledHook(t *testing.T) {
+	hook := NewInProcessHook(nil, common.SyncHook, http.NotFoundHandler())
+
+	assert.False(t, hook.IsEnabled())
+}
+
+func TestNewInProcessHook_callsHandler(t *testing.T) {
+	logging.Logger = testr.New(t)
+	var method string
+	var body []byte
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"relatedResources":[]}`))
+	})
+	version := v1alpha1.HookVersionV2
+	hook := NewInProcessHook(&v1alpha1.Hook{Version: &version}, common.CustomizeHook, handler)
+
+	var response v1.CustomizeHookResponse
+	err := hook.Call(context.TODO(), nil, &response)
+
+	require.NoError(t, err)
+	assert.True(t, hook.IsEnabled())
+	assert.Equal(t, v1alpha1.HookVersionV2, hook.GetVersion())
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "null", string(body))
+}
+
+func TestNewInProcessHook_whenHandlerFails_returnError(t *testing.T) {
+	logging.Logger = testr.New(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	hook := NewInProcessHook(&v1alpha1.Hook{}, common.SyncHook, handler)
+
+	var response v1.CustomizeHookResponse
+	err := hook.Call(context.TODO(), nil, &response)
+
+	assert.Error(t, err)
+}
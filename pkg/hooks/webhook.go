@@ -25,15 +25,19 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"metacontroller/pkg/audit"
 	"metacontroller/pkg/cache"
 	"metacontroller/pkg/controller/common"
 	"metacontroller/pkg/controller/common/api"
 	"metacontroller/pkg/logging"
 	"metacontroller/pkg/metrics"
+	"metacontroller/pkg/redact"
+	"metacontroller/pkg/tracing"
 	"net/http"
 	"strconv"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
@@ -188,16 +192,24 @@ func (w *webhookExecutor) GetVersion() v1alpha1.HookVersion {
 	return w.effectiveHookVersion()
 }
 
-func (w *webhookExecutor) Call(ctx context.Context, webhookRequest api.WebhookRequest, webhookResponse interface{}) error {
+func (w *webhookExecutor) Call(ctx context.Context, webhookRequest api.WebhookRequest, webhookResponse interface{}) (err error) {
+	ctx, span := tracing.Start(ctx, "hook "+w.hookType,
+		attribute.String("metacontroller.hook.type", w.hookType),
+		attribute.String("metacontroller.hook.version", string(w.effectiveHookVersion())),
+		attribute.String("url.full", w.url),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	// Encode webhookRequest.
 	requestBody, err := k8sjson.Marshal(webhookRequest)
 	if err != nil {
 		return fmt.Errorf("can't marshal request: %w", err)
 	}
 	requestAPIVersion := w.effectiveHookVersion()
-	if logging.Logger.V(6).Enabled() {
-		rawRequest := json.RawMessage(requestBody)
-		logging.Logger.V(6).Info("Webhook request", "version", requestAPIVersion, "type", w.hookType, "url", w.url, "body", rawRequest)
+	logger := logging.FromContext(ctx)
+	if logger.V(6).Enabled() {
+		rawRequest := json.RawMessage(redact.FromContext(ctx).JSON(requestBody))
+		logger.V(6).Info("Webhook request", "version", requestAPIVersion, "type", w.hookType, "url", w.url, "body", rawRequest)
 	}
 	request, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(requestBody))
 	if err != nil {
@@ -208,12 +220,14 @@ func (w *webhookExecutor) Call(ctx context.Context, webhookRequest api.WebhookRe
 		request.Header.Set("Authorization", w.authHeader)
 	}
 	w.webhookAbstract.enrichHeaders(request, webhookRequest)
+	tracing.Inject(ctx, request.Header)
 
 	response, err := w.client.Do(request)
 	if err != nil {
 		return fmt.Errorf("http error: %w", err)
 	}
 	defer response.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
 
 	if response.StatusCode == 429 {
 		var afterSecond int
@@ -232,13 +246,18 @@ func (w *webhookExecutor) Call(ctx context.Context, webhookRequest api.WebhookRe
 	if err != nil {
 		return fmt.Errorf("can't read response body: %w", err)
 	}
-	if logging.Logger.V(6).Enabled() {
-		rawResponse := json.RawMessage(responseBody)
-		logging.Logger.V(6).Info("Webhook response", "version", requestAPIVersion, "type", w.hookType, "url", w.url, "body", rawResponse)
+	if logger.V(6).Enabled() {
+		rawResponse := json.RawMessage(redact.FromContext(ctx).JSON(responseBody))
+		logger.V(6).Info("Webhook response", "version", requestAPIVersion, "type", w.hookType, "url", w.url, "body", rawResponse)
 	}
 
 	if !w.webhookAbstract.isStatusSupported(request, response) {
-		return fmt.Errorf("unsupported status code: %d body: %s", response.StatusCode, responseBody)
+		// The error ends up in Events and logs, so mask sensitive fields.
+		return fmt.Errorf("unsupported status code: %d body: %s", response.StatusCode, redact.FromContext(ctx).JSON(responseBody))
+	}
+	if w.hookType != common.CustomizeHook.String() {
+		// The writes of the sync are audited with the response they follow.
+		audit.SetHookResponseETag(ctx, response.Header.Get(headerETag))
 	}
 
 	responseBody, err = w.webhookAbstract.adjustResponse(request, webhookRequest, responseBody, response)
@@ -260,7 +279,7 @@ func (w *webhookExecutor) Call(ctx context.Context, webhookRequest api.WebhookRe
 		if w.shouldReportStrictErrors() {
 			return fmt.Errorf("strict validation failed for %s webhookResponse (type: %s, mode: %s): %w", requestAPIVersion, w.hookType, w.responseUnmarshallMode, strictAggregateErr)
 		}
-		logging.Logger.V(4).Info("Webhook response had non-fatal strict validation issues (due to loose mode)", "version", requestAPIVersion, "type", w.hookType, "url", w.url, "issues", strictAggregateErr.Error())
+		logger.V(4).Info("Webhook response had non-fatal strict validation issues (due to loose mode)", "version", requestAPIVersion, "type", w.hookType, "url", w.url, "issues", strictAggregateErr.Error())
 	}
 	return nil
 }
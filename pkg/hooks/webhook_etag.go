@@ -52,8 +52,8 @@ func (w *webhookExecutorEtag) enrichHeaders(request *http.Request, webhookReques
 	cacheEntry, cacheEntryExists := w.etagCache.Get(cacheKey)
 	if cacheEntryExists {
 		request.Header.Set(headerIfNoneMatch, cacheEntry.Etag)
-		if logging.Logger.V(6).Enabled() {
-			logging.Logger.V(6).Info("enriching headers with 'If-None-Match'", "cacheKey", cacheKey, "eTag", cacheEntry.Etag)
+		if logger := logging.FromContext(request.Context()); logger.V(6).Enabled() {
+			logger.V(6).Info("enriching headers with 'If-None-Match'", "cacheKey", cacheKey, "eTag", cacheEntry.Etag)
 		}
 	}
 }
@@ -65,7 +65,7 @@ func (w *webhookExecutorEtag) adjustResponse(
 	response *http.Response) ([]byte, error) {
 	cacheKey := w.getKeyFromObject(webhookRequest.GetRootObject())
 	if request.Header.Get(headerIfNoneMatch) != "" && (response.StatusCode == http.StatusNotModified || response.StatusCode == http.StatusPreconditionFailed) {
-		logging.Logger.Info("retrieving body from cache", "cacheKey", cacheKey)
+		logging.FromContext(request.Context()).Info("retrieving body from cache", "cacheKey", cacheKey)
 		cacheEntry, cacheEntryExists := w.etagCache.Get(cacheKey)
 		if !cacheEntryExists {
 			return nil, fmt.Errorf("cannot find cached response for cache key: %s", cacheKey)
@@ -75,7 +75,7 @@ func (w *webhookExecutorEtag) adjustResponse(
 	eTag := response.Header.Get(headerETag)
 	if eTag != "" {
 		w.etagCache.Set(cacheKey, &eTagEntry{Response: responseBody, Etag: eTag})
-		logging.Logger.Info("updating cache entry", "cacheKey", cacheKey)
+		logging.FromContext(request.Context()).Info("updating cache entry", "cacheKey", cacheKey)
 	}
 	return responseBody, nil
 }
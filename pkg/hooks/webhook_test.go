@@ -11,6 +11,7 @@ import (
 	"metacontroller/pkg/controller/common"
 	v1 "metacontroller/pkg/controller/common/customize/api/v1"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/tracing"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -22,7 +23,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/go-logr/logr/testr"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 
@@ -392,6 +398,86 @@ func TestNewWebhookExecutor_AuthHeader_sentToServer(t *testing.T) {
 	assert.Equal(t, "Bearer secret-token", receivedAuth)
 }
 
+func TestNewWebhookExecutor_Traceparent_sentToServer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	var receivedTraceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedTraceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/sync"
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{URL: &url}, nil, "test-controller", common.CompositeController, common.SyncHook, nil)
+	require.NoError(t, err)
+
+	ctx, span := tracing.StartSync(context.TODO(), common.CompositeController.String(), "test-controller", "default/test")
+	var response struct{}
+	require.NoError(t, executor.Call(ctx, nil, &response))
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	hookSpan := spans[0]
+	assert.Equal(t, "hook sync", hookSpan.Name())
+	assert.Equal(t, span.SpanContext().SpanID(), hookSpan.Parent().SpanID())
+	assert.Equal(t,
+		fmt.Sprintf("00-%s-%s-01", hookSpan.SpanContext().TraceID(), hookSpan.SpanContext().SpanID()),
+		receivedTraceparent)
+}
+
+func TestWebhookExecutor_Call_logsRedactedBodiesWithLoggerOfContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"children":[{"apiVersion":"v1","kind":"Secret","data":{"password":"c2VjcmV0"}}]}`))
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/sync"
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{URL: &url}, nil, "test-controller", common.CompositeController, common.SyncHook, nil)
+	require.NoError(t, err)
+
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 6})
+	ctx := logging.NewContext(context.TODO(), logger)
+	var response struct {
+		Children []interface{} `json:"children"`
+	}
+	require.NoError(t, executor.Call(ctx, nil, &response))
+
+	require.Len(t, logs, 2)
+	assert.Contains(t, logs[1], `"msg"="Webhook response"`)
+	assert.Contains(t, logs[1], "REDACTED")
+	assert.NotContains(t, logs[1], "c2VjcmV0")
+	// The response itself isn't redacted.
+	assert.Equal(t, "c2VjcmV0", response.Children[0].(map[string]interface{})["data"].(map[string]interface{})["password"])
+}
+
+func TestWebhookExecutor_Call_redactsBodyOfUnsupportedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"object":{"apiVersion":"v1","kind":"Secret","data":{"password":"c2VjcmV0"}}}`))
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/sync"
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{URL: &url}, nil, "test-controller", common.CompositeController, common.SyncHook, nil)
+	require.NoError(t, err)
+
+	var response struct{}
+	err = executor.Call(context.TODO(), nil, &response)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported status code: 500")
+	assert.Contains(t, err.Error(), "REDACTED")
+	assert.NotContains(t, err.Error(), "c2VjcmV0")
+}
+
 func TestNewWebhookExecutor_ClientTLS_presentedDuringHandshake(t *testing.T) {
 	certPEM, keyPEM := generateClientCertPEMs(t)
 
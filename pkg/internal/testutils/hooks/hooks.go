@@ -96,6 +96,10 @@ func (cc *NilCustomizableController) GetEndpointConfigs() []v1alpha1.EndpointCon
 	return nil
 }
 
+func (cc *NilCustomizableController) GetRelatedResourceRules() []v1alpha1.RelatedResourceRuleTemplate {
+	return nil
+}
+
 type FakeCustomizableController struct {
 }
 
@@ -112,6 +116,10 @@ func (cc *FakeCustomizableController) GetEndpointConfigs() []v1alpha1.EndpointCo
 	return nil
 }
 
+func (cc *FakeCustomizableController) GetRelatedResourceRules() []v1alpha1.RelatedResourceRuleTemplate {
+	return nil
+}
+
 func NewSerializingExecutorStub(responseJson string) hooks.Hook {
 	return &serializingHookExecutorStub{response: responseJson}
 }
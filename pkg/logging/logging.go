@@ -17,17 +17,28 @@ limitations under the License.
 package logging
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	controllerruntimelog "sigs.k8s.io/controller-runtime/pkg/log"
 	controllerruntimezap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
 	"k8s.io/klog/v2"
 )
 
 var (
 	// Logger is global json log format logr
 	Logger logr.Logger
+
+	// level is the level of Logger, which can be changed at runtime.
+	level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
 )
 
 func InitLogging(opts *controllerruntimezap.Options) {
@@ -36,7 +47,156 @@ func InitLogging(opts *controllerruntimezap.Options) {
 			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		})
 	}
-	Logger = controllerruntimezap.New(controllerruntimezap.UseFlagOptions(opts))
+	switch {
+	case opts.Level != nil:
+		level.SetLevel(lowestEnabledLevel(opts.Level))
+	case opts.Development:
+		level.SetLevel(zapcore.DebugLevel)
+	}
+
+	// The core logs at every level, and the loggers filter the entries by
+	// their own level, so that a controller can log more than Logger does.
+	rawOpts := *opts
+	rawOpts.Level = zapcore.Level(-128)
+	if !opts.Development {
+		// Sample like controller-runtime does, which it doesn't do for
+		// verbose cores.
+		rawOpts.ZapOpts = append(rawOpts.ZapOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &samplingCore{Core: core, sampled: zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)}
+		}))
+	}
+	raw := controllerruntimezap.NewRaw(controllerruntimezap.UseFlagOptions(&rawOpts))
+	Logger = zapr.NewLogger(raw.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &levelCore{Core: core, enabler: level}
+	})))
 	klog.SetLogger(Logger)
 	controllerruntimelog.SetLogger(Logger)
 }
+
+// Level returns the level of Logger, in the format of ParseLevel.
+func Level() string {
+	return FormatLevel(level.Level())
+}
+
+// SetLevel sets the level of Logger, and of all loggers derived from it.
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// ParseLevel parses a level like the --zap-log-level flag: one of debug,
+// info, error and panic, or an integer verbosity greater than 0, which
+// enables the logs of logr's V() up to that verbosity.
+func ParseLevel(s string) (zapcore.Level, error) {
+	var l zapcore.Level
+	switch name := strings.ToLower(s); name {
+	case "debug", "info", "error", "panic":
+		if err := l.UnmarshalText([]byte(name)); err != nil {
+			return l, err
+		}
+		return l, nil
+	}
+	verbosity, err := strconv.Atoi(s)
+	if err != nil || verbosity <= 0 || verbosity > 128 {
+		return l, fmt.Errorf("invalid log level %q", s)
+	}
+	return zapcore.Level(-verbosity), nil
+}
+
+// FormatLevel formats a level in the format of ParseLevel.
+func FormatLevel(l zapcore.Level) string {
+	if l < zapcore.DebugLevel {
+		return strconv.Itoa(-int(l))
+	}
+	return l.String()
+}
+
+// WithLevel returns a logger like the given one, that also logs entries at or
+// above the given level, even if the level of Logger is higher. The logger is
+// returned as is if it isn't a zap logger.
+func WithLevel(logger logr.Logger, l zapcore.Level) logr.Logger {
+	underlier, ok := logger.GetSink().(zapr.Underlier)
+	if !ok {
+		return logger
+	}
+	enabler := zap.LevelEnablerFunc(func(entryLevel zapcore.Level) bool {
+		return entryLevel >= l || level.Enabled(entryLevel)
+	})
+	raw := underlier.GetUnderlying().WithOptions(
+		// zapr skips its own frame again.
+		zap.AddCallerSkip(-1),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			if c, ok := core.(*levelCore); ok {
+				core = c.Core
+			}
+			return &levelCore{Core: core, enabler: enabler}
+		}),
+	)
+	return zapr.NewLogger(raw)
+}
+
+type loggerKey struct{}
+
+// NewContext returns a context that carries the logger, for FromContext.
+func NewContext(ctx context.Context, logger logr.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, e.g. the one of the
+// controller that syncs, or Logger if there is none.
+func FromContext(ctx context.Context) logr.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(logr.Logger); ok {
+		return logger
+	}
+	return Logger
+}
+
+// lowestEnabledLevel returns the lowest level that the enabler enables.
+func lowestEnabledLevel(enabler zapcore.LevelEnabler) zapcore.Level {
+	for l := zapcore.Level(-128); l < zapcore.FatalLevel; l++ {
+		if enabler.Enabled(l) {
+			return l
+		}
+	}
+	return zapcore.FatalLevel
+}
+
+// levelCore filters the entries of a core by a level enabler of its own.
+type levelCore struct {
+	zapcore.Core
+	enabler zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return c.enabler.Enabled(l)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enabler: c.enabler}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// samplingCore samples the entries of a core at info level and above, while
+// the level of Logger isn't verbose. Entries below info are only logged if
+// verbose logs were asked for, globally or for a controller, so they're never
+// sampled.
+type samplingCore struct {
+	zapcore.Core
+	sampled zapcore.Core
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), sampled: c.sampled.With(fields)}
+}
+
+func (c *samplingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level >= zapcore.InfoLevel && !level.Enabled(zapcore.Level(-2)) {
+		return c.sampled.Check(entry, checked)
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	controllerruntimezap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestLevels(t *testing.T) {
+	var out bytes.Buffer
+	InitLogging(&controllerruntimezap.Options{DestWriter: &out})
+	defer SetLevel(zapcore.InfoLevel)
+	controllerLogger := WithLevel(Logger.WithName("test"), zapcore.Level(-6))
+
+	logged := func(message string) bool {
+		return strings.Contains(out.String(), `"msg":"`+message+`"`)
+	}
+
+	Logger.V(6).Info("global before")
+	controllerLogger.V(6).Info("controller before")
+	controllerLogger.V(7).Info("controller too verbose")
+	if logged("global before") || !logged("controller before") || logged("controller too verbose") {
+		t.Errorf("at level info, got logs:\n%s", out.String())
+	}
+
+	SetLevel(zapcore.Level(-7))
+	if got := Level(); got != "7" {
+		t.Errorf("Level() = %q, want %q", got, "7")
+	}
+	Logger.V(7).Info("global after")
+	controllerLogger.V(7).Info("controller after")
+	if !logged("global after") || !logged("controller after") {
+		t.Errorf("at level 7, got logs:\n%s", out.String())
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var out bytes.Buffer
+	InitLogging(&controllerruntimezap.Options{DestWriter: &out})
+	defer SetLevel(zapcore.InfoLevel)
+	controllerLogger := WithLevel(Logger.WithName("test"), zapcore.Level(-6))
+	count := func(message string) int {
+		return strings.Count(out.String(), `"msg":"`+message+`"`)
+	}
+
+	for range 1000 {
+		Logger.Info("info")
+		controllerLogger.V(6).Info("controller verbose")
+	}
+	if got := count("info"); got >= 1000 {
+		t.Errorf("at level info, logged %d of 1000 info entries, want them sampled", got)
+	}
+	if got := count("controller verbose"); got != 1000 {
+		t.Errorf("logged %d of 1000 verbose controller entries, want all", got)
+	}
+
+	SetLevel(zapcore.Level(-2))
+	for range 200 {
+		Logger.Info("verbose info")
+	}
+	if got := count("verbose info"); got != 200 {
+		t.Errorf("at level 2, logged %d of 200 info entries, want all", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{in: "info", want: zapcore.InfoLevel},
+		{in: "Debug", want: zapcore.DebugLevel},
+		{in: "6", want: zapcore.Level(-6)},
+		{in: "0", wantErr: true},
+		{in: "warn", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	Logger = Logger.WithName("global")
+	if got := FromContext(context.Background()); got != Logger {
+		t.Errorf("FromContext() without a logger = %v, want Logger", got)
+	}
+	logger := Logger.WithName("controller")
+	if got := FromContext(NewContext(context.Background(), logger)); got != logger {
+		t.Errorf("FromContext() = %v, want the logger of the context", got)
+	}
+}
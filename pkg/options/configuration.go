@@ -19,6 +19,8 @@ package options
 import (
 	"time"
 
+	"metacontroller/pkg/audit"
+
 	"sigs.k8s.io/controller-runtime/pkg/leaderelection"
 
 	"k8s.io/client-go/rest"
@@ -26,15 +28,32 @@ import (
 )
 
 type Configuration struct {
-	RestConfig             *rest.Config
-	DiscoveryInterval      time.Duration
-	InformerRelist         time.Duration
+	RestConfig        *rest.Config
+	DiscoveryInterval time.Duration
+	InformerRelist    time.Duration
+	// CacheStripAnnotations and CacheStripFields are dropped from cached
+	// objects, in addition to metadata.managedFields.
+	CacheStripAnnotations  []string
+	CacheStripFields       []string
 	Workers                int
 	CorrelatorOptions      record.CorrelatorOptions
 	MetricsEndpoint        string
 	LeaderElectionOptions  leaderelection.Options
 	HealthProbeBindAddress string
-	TargetLabelSelector    string
-	ApplyStrategy          string
-	SsaFieldManager        string
+	// StuckQueueTimeout is how long the workqueue of a controller may make
+	// no progress before the liveness check fails. Zero disables the check.
+	StuckQueueTimeout   time.Duration
+	TargetLabelSelector string
+	ApplyStrategy       string
+	SsaFieldManager     string
+	// SsaMigrateFieldManagers are the client-side field managers whose
+	// fields are moved to SsaFieldManager when migrating to server-side apply.
+	SsaMigrateFieldManagers []string
+	LastAppliedEncoding     string
+	// DryRun makes all controllers compute their writes without persisting
+	// them.
+	DryRun bool
+	// AuditSink receives a record of every write of the controllers to
+	// parents and children. It is nil if writes aren't audited.
+	AuditSink *audit.Sink
 }
@@ -0,0 +1,455 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redact masks sensitive fields of objects in the payloads that are
+// logged, recorded in the audit log or attached to Events.
+package redact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+// Mask replaces the values of redacted fields.
+const Mask = "REDACTED"
+
+// secretRule masks the values of Secrets, keeping their keys.
+var secretRule = mustParseRule(v1alpha1.RedactionRule{
+	APIVersion: "v1",
+	Kind:       "Secret",
+	Paths:      []string{"{.data.*}", "{.stringData.*}"},
+})
+
+// lastAppliedPaths select the last applied configurations of dynamic apply
+// and kubectl apply.
+var lastAppliedPaths = [][]jsonpath.Node{
+	annotationPath(dynamicapply.LastAppliedAnnotation),
+	annotationPath(corev1.LastAppliedConfigAnnotation),
+}
+
+// annotationPath returns the steps of the path of an annotation.
+func annotationPath(key string) []jsonpath.Node {
+	return []jsonpath.Node{
+		&jsonpath.FieldNode{NodeType: jsonpath.NodeField, Value: "metadata"},
+		&jsonpath.FieldNode{NodeType: jsonpath.NodeField, Value: "annotations"},
+		&jsonpath.FieldNode{NodeType: jsonpath.NodeField, Value: key},
+	}
+}
+
+// Redactor masks the fields of objects selected by the redaction rules of a
+// controller, and the data of Secrets. A nil *Redactor only masks the data of
+// Secrets.
+type Redactor struct {
+	rules []rule
+}
+
+type rule struct {
+	apiVersion, kind string
+	// paths are the steps of the paths to mask.
+	paths [][]jsonpath.Node
+}
+
+// New returns a redactor that applies the given rules in addition to masking
+// the data of Secrets.
+func New(rules []v1alpha1.RedactionRule) (*Redactor, error) {
+	r := &Redactor{rules: []rule{secretRule}}
+	for _, redactionRule := range rules {
+		parsed, err := parseRule(redactionRule)
+		if err != nil {
+			return nil, err
+		}
+		r.rules = append(r.rules, parsed)
+	}
+	return r, nil
+}
+
+// Object returns a copy of the object with its sensitive fields masked.
+func (r *Redactor) Object(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+	c := obj.DeepCopy()
+	r.redactObject(c.Object, c.GetAPIVersion(), c.GetKind())
+	return c
+}
+
+// Patch returns the JSON merge patch of the object with the sensitive fields
+// of the object masked.
+func (r *Redactor) Patch(patch []byte, obj *unstructured.Unstructured) []byte {
+	if len(patch) == 0 || obj == nil {
+		return patch
+	}
+	return r.redactJSON(patch, func(value interface{}) bool {
+		fields, ok := value.(map[string]interface{})
+		return ok && r.redactObject(fields, obj.GetAPIVersion(), obj.GetKind())
+	})
+}
+
+// JSON returns the JSON document, like the request or response of a hook,
+// with the sensitive fields of all the objects it holds masked. Anything that
+// has an apiVersion and a kind is an object.
+func (r *Redactor) JSON(document []byte) []byte {
+	return r.redactJSON(document, r.redactEmbedded)
+}
+
+// redactJSON decodes the document, redacts it and encodes it again, if
+// anything was masked. A document that isn't JSON is masked as a whole, since
+// it can't be told whether it holds sensitive fields.
+func (r *Redactor) redactJSON(document []byte, redact func(value interface{}) bool) []byte {
+	if len(bytes.TrimSpace(document)) == 0 {
+		return document
+	}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	// Keep large integers as they are.
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []byte(`"` + Mask + `"`)
+	}
+	if !redact(value) {
+		return document
+	}
+	redacted, err := json.Marshal(value)
+	if err != nil {
+		// Better nothing than the sensitive fields.
+		return []byte(`"` + Mask + `"`)
+	}
+	return redacted
+}
+
+// redactEmbedded redacts the objects held by the value, and returns true if
+// it masked anything.
+func (r *Redactor) redactEmbedded(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		apiVersion, _ := v["apiVersion"].(string)
+		kind, _ := v["kind"].(string)
+		if apiVersion != "" && kind != "" {
+			changed = r.redactObject(v, apiVersion, kind)
+		}
+		for _, field := range v {
+			changed = r.redactEmbedded(field) || changed
+		}
+	case []interface{}:
+		for _, item := range v {
+			changed = r.redactEmbedded(item) || changed
+		}
+	}
+	return changed
+}
+
+// redactObject masks the fields of an object of the given API version and
+// kind in place, and returns true if it masked anything.
+func (r *Redactor) redactObject(obj map[string]interface{}, apiVersion, kind string) bool {
+	rules := []rule{secretRule}
+	if r != nil {
+		rules = r.rules
+	}
+	matched, changed := false, false
+	for _, redactionRule := range rules {
+		if (redactionRule.apiVersion != "" && redactionRule.apiVersion != apiVersion) ||
+			(redactionRule.kind != "" && redactionRule.kind != kind) {
+			continue
+		}
+		matched = true
+		for _, path := range redactionRule.paths {
+			if _, masked := mask(obj, path); masked {
+				changed = true
+			}
+		}
+	}
+	if matched {
+		// The last applied configurations hold the fields of the object as
+		// well.
+		for _, path := range lastAppliedPaths {
+			if _, masked := mask(obj, path); masked {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// mask masks the fields selected by the steps of a path in the value, and
+// returns the value with the fields masked and whether it masked any. Maps and
+// slices are changed in place.
+func mask(value interface{}, steps []jsonpath.Node) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if len(steps) == 0 {
+		return Mask, true
+	}
+	changed := false
+	// maskItem masks the rest of the path in an item of value.
+	maskItem := func(item interface{}, set func(interface{})) {
+		if masked, ok := mask(item, steps[1:]); ok {
+			set(masked)
+			changed = true
+		}
+	}
+	switch step := steps[0].(type) {
+	case *jsonpath.FieldNode:
+		if fields, ok := value.(map[string]interface{}); ok {
+			if item, ok := fields[step.Value]; ok {
+				maskItem(item, func(masked interface{}) { fields[step.Value] = masked })
+			}
+		}
+	case *jsonpath.WildcardNode:
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, item := range v {
+				maskItem(item, func(masked interface{}) { v[key] = masked })
+			}
+		case []interface{}:
+			for i, item := range v {
+				maskItem(item, func(masked interface{}) { v[i] = masked })
+			}
+		}
+	case *jsonpath.ArrayNode:
+		if items, ok := value.([]interface{}); ok {
+			for _, i := range arrayIndexes(step, len(items)) {
+				maskItem(items[i], func(masked interface{}) { items[i] = masked })
+			}
+		}
+	case *jsonpath.FilterNode:
+		if items, ok := value.([]interface{}); ok {
+			for i, item := range items {
+				if matches(step, item) {
+					maskItem(item, func(masked interface{}) { items[i] = masked })
+				}
+			}
+		}
+	case *jsonpath.RecursiveNode:
+		// The rest of the path may start here, or at any depth below.
+		if masked, ok := mask(value, steps[1:]); ok {
+			value, changed = masked, true
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, item := range v {
+				if masked, ok := mask(item, steps); ok {
+					v[key], changed = masked, true
+				}
+			}
+		case []interface{}:
+			for i, item := range v {
+				if masked, ok := mask(item, steps); ok {
+					v[i], changed = masked, true
+				}
+			}
+		}
+	}
+	return value, changed
+}
+
+// arrayIndexes returns the indexes of a slice of the given length that an
+// array step like [0], [-1] or [1:3] selects, like JSONPath does, but
+// ignoring indexes out of bounds.
+func arrayIndexes(step *jsonpath.ArrayNode, length int) []int {
+	params := step.Params
+	start, end, stride := 0, length, 1
+	if params[0].Known {
+		start = params[0].Value
+		if start < 0 {
+			start += length
+		}
+	}
+	if params[1].Known {
+		end = params[1].Value
+		if end < 0 || (end == 0 && params[1].Derived) {
+			end += length
+		}
+	}
+	if params[2].Known && params[2].Value > 0 {
+		stride = params[2].Value
+	}
+	var indexes []int
+	for i := max(start, 0); i < min(end, length); i += stride {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// matches returns true if the item passes a filter step like
+// [?(@.name=="TOKEN")].
+func matches(step *jsonpath.FilterNode, item interface{}) bool {
+	left, found := fieldValue(step.Left, item)
+	if step.Operator == "exists" {
+		return found
+	}
+	equal := found && equals(left, step.Right.Nodes[0])
+	if step.Operator == "==" {
+		return equal
+	}
+	return found && !equal
+}
+
+// fieldValue returns the value of the fields of the item that the steps
+// select.
+func fieldValue(steps *jsonpath.ListNode, item interface{}) (interface{}, bool) {
+	value := item
+	for _, step := range steps.Nodes {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[step.(*jsonpath.FieldNode).Value]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// equals compares a value with a literal of a filter.
+func equals(value interface{}, literal jsonpath.Node) bool {
+	switch l := literal.(type) {
+	case *jsonpath.TextNode:
+		s, ok := value.(string)
+		return ok && s == l.Text
+	case *jsonpath.BoolNode:
+		b, ok := value.(bool)
+		return ok && b == l.Value
+	case *jsonpath.IntNode:
+		f, ok := number(value)
+		return ok && f == float64(l.Value)
+	case *jsonpath.FloatNode:
+		f, ok := number(value)
+		return ok && f == l.Value
+	}
+	return false
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func mustParseRule(redactionRule v1alpha1.RedactionRule) rule {
+	parsed, err := parseRule(redactionRule)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func parseRule(redactionRule v1alpha1.RedactionRule) (rule, error) {
+	parsed := rule{apiVersion: redactionRule.APIVersion, kind: redactionRule.Kind}
+	if len(redactionRule.Paths) == 0 {
+		return parsed, fmt.Errorf("redaction rule for %q %q has no paths", redactionRule.APIVersion, redactionRule.Kind)
+	}
+	for _, path := range redactionRule.Paths {
+		steps, err := parsePath(path)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid redaction path %q: %w", path, err)
+		}
+		parsed.paths = append(parsed.paths, steps)
+	}
+	return parsed, nil
+}
+
+// parsePath parses a JSONPath template like {.spec.password} into its steps.
+// Fields, wildcards, array indexes and slices, recursive descent and filters
+// that compare a field with a literal with == or != are supported.
+func parsePath(path string) ([]jsonpath.Node, error) {
+	parser, err := jsonpath.Parse("redact", path)
+	if err != nil {
+		return nil, err
+	}
+	if len(parser.Root.Nodes) != 1 {
+		return nil, fmt.Errorf("must be a single JSONPath template like {.spec.password}")
+	}
+	list, ok := parser.Root.Nodes[0].(*jsonpath.ListNode)
+	if !ok || len(list.Nodes) == 0 {
+		return nil, fmt.Errorf("must be a single JSONPath template like {.spec.password}")
+	}
+	for i, step := range list.Nodes {
+		switch s := step.(type) {
+		case *jsonpath.FieldNode, *jsonpath.WildcardNode, *jsonpath.ArrayNode:
+		case *jsonpath.RecursiveNode:
+			if i == len(list.Nodes)-1 {
+				return nil, fmt.Errorf("recursive descent must be followed by a field")
+			}
+		case *jsonpath.FilterNode:
+			if err := validateFilter(s); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported step %v", step)
+		}
+	}
+	return list.Nodes, nil
+}
+
+func validateFilter(filter *jsonpath.FilterNode) error {
+	if len(filter.Left.Nodes) == 0 {
+		return fmt.Errorf("filter must select a field like @.name")
+	}
+	for _, step := range filter.Left.Nodes {
+		if _, ok := step.(*jsonpath.FieldNode); !ok {
+			return fmt.Errorf("filter must select a field like @.name, not %v", step)
+		}
+	}
+	switch filter.Operator {
+	case "exists":
+		return nil
+	case "==", "!=":
+	default:
+		return fmt.Errorf("unsupported filter operator %s", filter.Operator)
+	}
+	if len(filter.Right.Nodes) != 1 {
+		return fmt.Errorf("filter must compare with a literal")
+	}
+	switch filter.Right.Nodes[0].(type) {
+	case *jsonpath.TextNode, *jsonpath.BoolNode, *jsonpath.IntNode, *jsonpath.FloatNode:
+		return nil
+	}
+	return fmt.Errorf("filter must compare with a literal, not %v", filter.Right.Nodes[0])
+}
+
+type redactorKey struct{}
+
+// NewContext returns a context that carries the redactor, for FromContext.
+func NewContext(ctx context.Context, r *Redactor) context.Context {
+	return context.WithValue(ctx, redactorKey{}, r)
+}
+
+// FromContext returns the redactor carried by ctx, e.g. the one of the
+// controller that syncs, or nil, which only masks the data of Secrets.
+func FromContext(ctx context.Context) *Redactor {
+	r, _ := ctx.Value(redactorKey{}).(*Redactor)
+	return r
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestRedactor_JSON(t *testing.T) {
+	redactor, err := New([]v1alpha1.RedactionRule{{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Paths: []string{
+			`{.spec.template.spec.containers[*].env[?(@.name=="TOKEN")].value}`,
+			`{.spec.template.spec.containers[-1].args[1:]}`,
+		},
+	}, {
+		Kind:  "Database",
+		Paths: []string{"{..password}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		redactor *Redactor
+		in       string
+		want     string
+	}{
+		{
+			name: "secret child",
+			in:   `{"children":{"Secret.v1":{"a":{"apiVersion":"v1","kind":"Secret","data":{"key":"c2VjcmV0"},"stringData":{"other":"secret"},"type":"Opaque"}}}}`,
+			want: `{"children":{"Secret.v1":{"a":{"apiVersion":"v1","data":{"key":"REDACTED"},"kind":"Secret","stringData":{"other":"REDACTED"},"type":"Opaque"}}}}`,
+		},
+		{
+			name: "last applied configuration",
+			in:   `{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"metacontroller.k8s.io/last-applied-configuration":"{\"data\":{\"key\":\"c2VjcmV0\"}}"}}}`,
+			want: `{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"metacontroller.k8s.io/last-applied-configuration":"REDACTED"}}}`,
+		},
+		{
+			name: "kubectl last applied configuration",
+			in:   `{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{\"data\":{\"key\":\"c2VjcmV0\"}}"}}}`,
+			want: `{"apiVersion":"v1","kind":"Secret","metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"REDACTED"}}}`,
+		},
+		{
+			name: "secret without rules",
+			in:   `[{"apiVersion":"v1","kind":"Secret","data":{"key":"c2VjcmV0"}}]`,
+			want: `[{"apiVersion":"v1","data":{"key":"REDACTED"},"kind":"Secret"}]`,
+		},
+		{
+			name:     "filter and slice",
+			redactor: redactor,
+			in:       `{"apiVersion":"apps/v1","kind":"Deployment","spec":{"template":{"spec":{"containers":[{"env":[{"name":"TOKEN","value":"secret"},{"name":"MODE","value":"fast"}],"args":["--token","secret"]}]}}}}`,
+			want:     `{"apiVersion":"apps/v1","kind":"Deployment","spec":{"template":{"spec":{"containers":[{"args":["--token","REDACTED"],"env":[{"name":"TOKEN","value":"REDACTED"},{"name":"MODE","value":"fast"}]}]}}}}`,
+		},
+		{
+			name:     "recursive descent",
+			redactor: redactor,
+			in:       `{"object":{"apiVersion":"example.com/v1","kind":"Database","spec":{"password":"secret","users":[{"name":"a","password":"secret"}]}}}`,
+			want:     `{"object":{"apiVersion":"example.com/v1","kind":"Database","spec":{"password":"REDACTED","users":[{"name":"a","password":"REDACTED"}]}}}`,
+		},
+		{
+			name:     "nothing to redact",
+			redactor: redactor,
+			in:       `{"big": 12345678901234567890, "apiVersion":"v1","kind":"ConfigMap","data":{"key":"value"}}`,
+			want:     `{"big": 12345678901234567890, "apiVersion":"v1","kind":"ConfigMap","data":{"key":"value"}}`,
+		},
+		{
+			name: "not JSON",
+			in:   `not JSON`,
+			want: `"REDACTED"`,
+		},
+		{
+			name: "empty",
+			in:   ``,
+			want: ``,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.redactor.JSON([]byte(tt.in))); got != tt.want {
+				t.Errorf("JSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactor_Patch(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Secret"}}
+	var redactor *Redactor
+	got := string(redactor.Patch([]byte(`{"data":{"changed":"c2VjcmV0","removed":null}}`), secret))
+	if want := `{"data":{"changed":"REDACTED","removed":null}}`; got != want {
+		t.Errorf("Patch() = %s, want %s", got, want)
+	}
+}
+
+func TestRedactor_Object(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"data":       map[string]interface{}{"key": "c2VjcmV0"},
+	}}
+	redacted := (*Redactor)(nil).Object(secret)
+	if got, _, _ := unstructured.NestedString(redacted.Object, "data", "key"); got != Mask {
+		t.Errorf("redacted data = %q, want %q", got, Mask)
+	}
+	if got, _, _ := unstructured.NestedString(secret.Object, "data", "key"); got != "c2VjcmV0" {
+		t.Errorf("original data = %q, want it unchanged", got)
+	}
+}
+
+func TestNew_invalid(t *testing.T) {
+	for _, path := range []string{"spec.password", "{.a}{.b}", "{..}", `{.a[?(@.b<3)]}`, `{.a[?(@.b==@.c)]}`} {
+		if _, err := New([]v1alpha1.RedactionRule{{Paths: []string{path}}}); err == nil {
+			t.Errorf("New() with path %q succeeded, want an error", path)
+		}
+	}
+	if _, err := New([]v1alpha1.RedactionRule{{Kind: "Secret"}}); err == nil {
+		t.Errorf("New() without paths succeeded, want an error")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != nil {
+		t.Errorf("FromContext() without a redactor = %v, want nil", got)
+	}
+	redactor, _ := New(nil)
+	if got := FromContext(NewContext(context.Background(), redactor)); got != redactor {
+		t.Errorf("FromContext() = %v, want the redactor of the context", got)
+	}
+}
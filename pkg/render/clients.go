@@ -0,0 +1,124 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+	restfake "k8s.io/client-go/rest/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	mcclientset "metacontroller/pkg/client/generated/clientset/internalclientset"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+// newDynamicClient returns a client of the resources of the given rules that
+// serves the given objects from memory. Writes change the objects in memory,
+// whether they're dry runs or not.
+func newDynamicClient(resources *dynamicdiscovery.ResourceMap, rules []v1alpha1.ResourceRule, objects []*unstructured.Unstructured) *dynamicclientset.Clientset {
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for _, rule := range rules {
+		resource := resources.Get(rule.APIVersion, rule.Resource)
+		listKinds[resource.GroupVersionResource()] = resource.Kind + "List"
+	}
+	initial := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		initial = append(initial, obj.DeepCopy())
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, initial...)
+	client.PrependReactor("patch", "*", applyReactor(client.Tracker(), resources))
+	return dynamicclientset.NewClientset(&rest.Config{}, resources, client)
+}
+
+// applyReactor serves server-side apply patches, which the object tracker
+// can't apply to unstructured objects, by merging the applied object into the
+// stored one with the schema of its kind. Like the API server, it creates the
+// object if it doesn't exist. Since the tracker doesn't know which fields the
+// field manager owns, fields that the patch leaves out are kept. The result
+// isn't stored.
+func applyReactor(tracker clientgotesting.ObjectTracker, resources *dynamicdiscovery.ResourceMap) clientgotesting.ReactionFunc {
+	return func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(clientgotesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		applied := &unstructured.Unstructured{}
+		if err := applied.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, apierrors.NewBadRequest(err.Error())
+		}
+		stored, err := tracker.Get(action.GetResource(), action.GetNamespace(), patch.GetName())
+		if apierrors.IsNotFound(err) {
+			return true, applied, nil
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(stored)
+		if err != nil {
+			return true, nil, err
+		}
+		merged, err := dynamicapply.MergeWithSchema(content, nil, applied.UnstructuredContent(), resources.GetSchema(applied.GetAPIVersion(), applied.GetKind()))
+		if err != nil {
+			return true, nil, apierrors.NewBadRequest(err.Error())
+		}
+		return true, &unstructured.Unstructured{Object: merged}, nil
+	}
+}
+
+// newRevisionClient returns a client whose writes of ControllerRevisions
+// succeed without storing anything: creates and updates return the written
+// revision.
+func newRevisionClient() (mcclientset.Interface, error) {
+	httpClient := restfake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		status := http.StatusOK
+		switch req.Method {
+		case http.MethodPost, http.MethodPut:
+			var err error
+			if body, err = io.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+			if req.Method == http.MethodPost {
+				status = http.StatusCreated
+			}
+		case http.MethodDelete:
+			body = []byte(`{"kind":"Status","apiVersion":"v1","status":"Success"}`)
+		default:
+			return nil, fmt.Errorf("can't %v %v while rendering", req.Method, req.URL.Path)
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{runtime.ContentTypeJSON}},
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	return mcclientset.NewForConfigAndClient(&rest.Config{Host: "http://render.invalid"}, httpClient)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package render runs a controller against local objects, without an API
+// server, and reports what it would do.
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/api"
+	commonv2 "metacontroller/pkg/controller/common/api/v2"
+	"metacontroller/pkg/controller/composite"
+	"metacontroller/pkg/controller/decorator"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	"metacontroller/pkg/hooks"
+)
+
+// Input is what a controller is rendered against.
+type Input struct {
+	// Controller is a CompositeController or a DecoratorController.
+	Controller *unstructured.Unstructured
+	// Parent is the parent to sync.
+	Parent *unstructured.Unstructured
+	// Children are the observed children of the parent, or its attachments.
+	Children []*unstructured.Unstructured
+	// Related are the related objects, as the customize hook would select
+	// them. The customize hook isn't called.
+	Related []*unstructured.Unstructured
+	// CRDs are the CustomResourceDefinitions of the custom resources of the
+	// controller. Other resources are resolved from the objects and the
+	// built-in kinds.
+	CRDs []*unstructured.Unstructured
+	// Handler serves the hooks in-process, if it's set.
+	Handler http.Handler
+	// HookURL replaces the URLs of the webhooks of the hooks, if it's set.
+	HookURL string
+}
+
+// Output is what a controller would do to the parent and its children.
+type Output struct {
+	// Request is the request sent to the sync or finalize hook.
+	Request api.WebhookRequest `json:"request,omitempty"`
+	// Children are the desired children, or attachments.
+	Children []*unstructured.Unstructured `json:"children"`
+	// Status is the status of the parent after the sync.
+	Status map[string]interface{} `json:"status,omitempty"`
+	// Diff are the writes to the parent and its children, in order.
+	Diff []Change `json:"diff"`
+}
+
+// Change is a write to an object.
+type Change struct {
+	Action     common.DryRunAction `json:"action"`
+	APIVersion string              `json:"apiVersion"`
+	Kind       string              `json:"kind"`
+	Namespace  string              `json:"namespace,omitempty"`
+	Name       string              `json:"name"`
+	// Patch is the JSON merge patch from the observed object to the written
+	// one, or the whole object for creates. Deletes have none.
+	Patch json.RawMessage `json:"patch,omitempty"`
+}
+
+// Render syncs the parent of the input like its controller would, calling
+// its hooks, and returns the desired children and the writes the sync would
+// make. The controller runs the code it runs against an API server, in
+// dry-run mode, against an in-memory client that holds the parent and its
+// children.
+func Render(ctx context.Context, in Input) (*Output, error) {
+	if in.Controller == nil || in.Parent == nil {
+		return nil, fmt.Errorf("a controller and a parent are required")
+	}
+	objects := append(append([]*unstructured.Unstructured{in.Parent}, in.Children...), in.Related...)
+
+	var request api.WebhookRequest
+	var writes []common.DryRunWrite
+	collect := func(write common.DryRunWrite) {
+		writes = append(writes, write)
+	}
+	var children []*unstructured.Unstructured
+	switch gvk := in.Controller.GroupVersionKind(); gvk {
+	case v1alpha1.SchemeGroupVersion.WithKind("CompositeController"):
+		cc := &v1alpha1.CompositeController{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(in.Controller.Object, cc); err != nil {
+			return nil, fmt.Errorf("can't convert CompositeController: %w", err)
+		}
+		if cc.Spec.Hooks == nil {
+			return nil, fmt.Errorf("no hooks defined")
+		}
+		rules := []v1alpha1.ResourceRule{cc.Spec.ParentResource.ResourceRule}
+		for _, child := range cc.Spec.ChildResources {
+			rules = append(rules, child.ResourceRule)
+		}
+		resources, err := newResourceMap(rules, in.CRDs, objects)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkParent(resources, rules[:1], in.Parent); err != nil {
+			return nil, err
+		}
+		observedChildren, err := newObjectMap(resources, rules[1:], in.Parent, in.Children)
+		if err != nil {
+			return nil, err
+		}
+		syncHook, finalizeHook, err := in.newHooks(cc.Name, common.CompositeController, cc.Spec.Hooks.Sync, cc.Spec.Hooks.Finalize, &request)
+		if err != nil {
+			return nil, err
+		}
+		mcClient, err := newRevisionClient()
+		if err != nil {
+			return nil, err
+		}
+		dynClient := newDynamicClient(resources, rules, append([]*unstructured.Unstructured{in.Parent}, in.Children...))
+		children, err = composite.Render(ctx, resources, dynClient, mcClient, cc, syncHook, finalizeHook, collect, in.Parent, observedChildren, commonv2.MakeUniformObjectMap(in.Parent, in.Related))
+		if err != nil {
+			return nil, err
+		}
+	case v1alpha1.SchemeGroupVersion.WithKind("DecoratorController"):
+		dc := &v1alpha1.DecoratorController{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(in.Controller.Object, dc); err != nil {
+			return nil, fmt.Errorf("can't convert DecoratorController: %w", err)
+		}
+		if dc.Spec.Hooks == nil {
+			return nil, fmt.Errorf("no hooks defined")
+		}
+		var parentRules, attachmentRules []v1alpha1.ResourceRule
+		for _, parent := range dc.Spec.Resources {
+			parentRules = append(parentRules, parent.ResourceRule)
+		}
+		for _, attachment := range dc.Spec.Attachments {
+			attachmentRules = append(attachmentRules, attachment.ResourceRule)
+		}
+		rules := append(append([]v1alpha1.ResourceRule{}, parentRules...), attachmentRules...)
+		resources, err := newResourceMap(rules, in.CRDs, objects)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkParent(resources, parentRules, in.Parent); err != nil {
+			return nil, err
+		}
+		observedChildren, err := newObjectMap(resources, attachmentRules, in.Parent, in.Children)
+		if err != nil {
+			return nil, err
+		}
+		syncHook, finalizeHook, err := in.newHooks(dc.Name, common.DecoratorController, dc.Spec.Hooks.Sync, dc.Spec.Hooks.Finalize, &request)
+		if err != nil {
+			return nil, err
+		}
+		dynClient := newDynamicClient(resources, rules, append([]*unstructured.Unstructured{in.Parent}, in.Children...))
+		children, err = decorator.Render(ctx, resources, dynClient, dc, syncHook, finalizeHook, collect, in.Parent, observedChildren, commonv2.MakeUniformObjectMap(in.Parent, in.Related))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported controller %v, want a CompositeController or a DecoratorController", gvk)
+	}
+	return newOutput(request, in.Parent, children, writes)
+}
+
+// newHooks returns the sync and finalize hooks of a controller, which call
+// the handler of the input if it has one, and keep the last request they
+// send in request.
+func (in Input) newHooks(controllerName string, controllerType common.ControllerType, sync, finalize *v1alpha1.Hook, request *api.WebhookRequest) (hooks.Hook, hooks.Hook, error) {
+	syncHook, err := in.newHook(controllerName, controllerType, common.SyncHook, sync)
+	if err != nil {
+		return nil, nil, err
+	}
+	finalizeHook, err := in.newHook(controllerName, controllerType, common.FinalizeHook, finalize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &recordingHook{Hook: syncHook, request: request}, &recordingHook{Hook: finalizeHook, request: request}, nil
+}
+
+// recordingHook is a hook that keeps the last request it sends.
+type recordingHook struct {
+	hooks.Hook
+	request *api.WebhookRequest
+}
+
+func (h *recordingHook) Call(ctx context.Context, request api.WebhookRequest, response interface{}) error {
+	*h.request = request
+	return h.Hook.Call(ctx, request, response)
+}
+
+func (in Input) newHook(controllerName string, controllerType common.ControllerType, hookType common.HookType, hook *v1alpha1.Hook) (hooks.Hook, error) {
+	if in.Handler != nil {
+		return hooks.NewInProcessHook(hook, hookType, in.Handler), nil
+	}
+	if hook != nil && in.HookURL != "" {
+		hook = hook.DeepCopy()
+		if hook.Webhook == nil {
+			hook.Webhook = &v1alpha1.Webhook{}
+		}
+		hook.Webhook.URL = &in.HookURL
+		hook.Webhook.Service = nil
+		hook.Webhook.Path = nil
+	}
+	return hooks.NewHook(hook, controllerName, controllerType, hookType, nil)
+}
+
+// checkParent returns an error if the parent isn't of one of the resources of
+// the given rules.
+func checkParent(resources *dynamicdiscovery.ResourceMap, rules []v1alpha1.ResourceRule, parent *unstructured.Unstructured) error {
+	for _, rule := range rules {
+		resource := resources.Get(rule.APIVersion, rule.Resource)
+		if resource.APIVersion == parent.GetAPIVersion() && resource.Kind == parent.GetKind() {
+			return nil
+		}
+	}
+	return fmt.Errorf("parent %v %v/%v is not of a parent resource of the controller", parent.GetKind(), parent.GetNamespace(), parent.GetName())
+}
+
+// newObjectMap returns the map of the observed children, with a group for
+// each of the resources of the given rules, like the controllers make.
+func newObjectMap(resources *dynamicdiscovery.ResourceMap, rules []v1alpha1.ResourceRule, parent *unstructured.Unstructured, children []*unstructured.Unstructured) (commonv2.UniformObjectMap, error) {
+	objectMap := make(commonv2.UniformObjectMap)
+	for _, rule := range rules {
+		objectMap.InitGroup(resources.Get(rule.APIVersion, rule.Resource).GroupVersionKind())
+	}
+	for _, child := range children {
+		if objectMap.GetObjectsByGVK(child.GroupVersionKind()) == nil {
+			return nil, fmt.Errorf("child %v %v/%v is not of a child resource of the controller", child.GetKind(), child.GetNamespace(), child.GetName())
+		}
+		objectMap.Insert(parent, child)
+	}
+	return objectMap, nil
+}
+
+// newOutput returns the output of a sync of the parent that returned the
+// given children and made the given writes. The status is the one of the
+// last write of the parent.
+func newOutput(request api.WebhookRequest, parent *unstructured.Unstructured, children []*unstructured.Unstructured, writes []common.DryRunWrite) (*Output, error) {
+	out := &Output{
+		Request:  request,
+		Children: children,
+		Diff:     []Change{},
+	}
+	if out.Children == nil {
+		out.Children = []*unstructured.Unstructured{}
+	}
+	status, _ := parent.Object["status"].(map[string]interface{})
+	for _, write := range writes {
+		obj := write.Updated
+		if obj == nil {
+			obj = write.Original
+		}
+		change := Change{
+			Action:     write.Action,
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		}
+		if write.Updated != nil {
+			original := write.Original
+			if original == nil {
+				original = &unstructured.Unstructured{Object: map[string]interface{}{}}
+			}
+			patch, err := common.JsonMergePatch(original, write.Updated)
+			if err != nil {
+				return nil, fmt.Errorf("can't diff %v %v/%v: %w", change.Kind, change.Namespace, change.Name, err)
+			}
+			change.Patch = patch
+			if isObject(write.Updated, parent) {
+				status, _ = write.Updated.Object["status"].(map[string]interface{})
+			}
+		}
+		out.Diff = append(out.Diff, change)
+	}
+	out.Status = status
+	return out, nil
+}
+
+// isObject reports whether two objects are the same object.
+func isObject(a, b *unstructured.Unstructured) bool {
+	return a.GroupVersionKind() == b.GroupVersionKind() && a.GetNamespace() == b.GetNamespace() && a.GetName() == b.GetName()
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/logging"
+)
+
+const compositeController = `
+apiVersion: metacontroller.k8s.io/v1alpha1
+kind: CompositeController
+metadata:
+  name: things
+spec:
+  parentResource:
+    apiVersion: example.com/v1
+    resource: things
+  childResources:
+  - apiVersion: v1
+    resource: configmaps
+    updateStrategy:
+      method: InPlace
+  hooks:
+    sync:
+      version: v2
+      webhook:
+        url: http://things.example.com/sync
+`
+
+const thingCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: things.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  names:
+    plural: things
+    kind: Thing
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+`
+
+const thing = `
+apiVersion: example.com/v1
+kind: Thing
+metadata:
+  name: a
+  namespace: default
+  uid: 1234
+  generation: 2
+spec:
+  selector:
+    matchLabels:
+      app: a
+`
+
+const observedConfigMaps = `
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+    namespace: default
+  data:
+    value: old
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: stale
+    namespace: default
+---
+`
+
+func readObjects(t *testing.T, yaml string) []*unstructured.Unstructured {
+	t.Helper()
+	objects, err := ReadObjects(strings.NewReader(yaml))
+	require.NoError(t, err)
+	return objects
+}
+
+// respondWith returns a hook that responds with the given response and keeps
+// the body of the request.
+func respondWith(response string, request *[]byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*request, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(response))
+	})
+}
+
+func TestReadObjects(t *testing.T) {
+	objects := readObjects(t, thing+"---\n"+observedConfigMaps+"---\n{\"apiVersion\":\"v1\",\"kind\":\"Secret\",\"metadata\":{\"name\":\"s\"}}")
+
+	require.Len(t, objects, 4)
+	assert.Equal(t, "Thing", objects[0].GetKind())
+	assert.Equal(t, int64(2), objects[0].GetGeneration())
+	assert.Equal(t, "stale", objects[2].GetName())
+	assert.Equal(t, "Secret", objects[3].GetKind())
+}
+
+func TestReadObjects_withoutKind(t *testing.T) {
+	_, err := ReadObjects(strings.NewReader("metadata:\n  name: a\n"))
+
+	assert.Error(t, err)
+}
+
+func TestRender_compositeController(t *testing.T) {
+	logging.Logger = testr.New(t)
+	var request []byte
+	out, err := Render(context.Background(), Input{
+		Controller: readObjects(t, compositeController)[0],
+		Parent:     readObjects(t, thing)[0],
+		Children:   readObjects(t, observedConfigMaps),
+		CRDs:       readObjects(t, thingCRD),
+		Handler: respondWith(`{
+			"status": {"ready": true},
+			"children": [{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "labels": {"app": "a"}}, "data": {"value": "x"}}]
+		}`, &request),
+	})
+	require.NoError(t, err)
+
+	var sent map[string]interface{}
+	require.NoError(t, json.Unmarshal(request, &sent))
+	assert.Contains(t, sent["children"], "ConfigMap.v1")
+	assert.NotNil(t, out.Request)
+
+	require.Len(t, out.Children, 1)
+	assert.Equal(t, "default", out.Children[0].GetNamespace())
+	assert.Equal(t, map[string]interface{}{"ready": true, "observedGeneration": int64(2)}, out.Status)
+
+	require.Len(t, out.Diff, 3)
+	assert.Equal(t, Change{Action: common.DryRunDelete, APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "stale"}, out.Diff[0])
+	assert.Equal(t, common.DryRunUpdate, out.Diff[1].Action)
+	assert.Equal(t, "a", out.Diff[1].Name)
+	assert.Contains(t, string(out.Diff[1].Patch), `"data":{"value":"x"}`)
+	assert.Contains(t, string(out.Diff[1].Patch), `"labels":{"app":"a"}`)
+	assert.Equal(t, common.DryRunUpdateStatus, out.Diff[2].Action)
+	assert.Equal(t, "Thing", out.Diff[2].Kind)
+	assert.JSONEq(t, `{"status":{"ready":true,"observedGeneration":2}}`, string(out.Diff[2].Patch))
+}
+
+const decoratorController = `
+apiVersion: metacontroller.k8s.io/v1alpha1
+kind: DecoratorController
+metadata:
+  name: configmaps
+spec:
+  resources:
+  - apiVersion: v1
+    resource: configmaps
+  attachments:
+  - apiVersion: v1
+    resource: secrets
+  hooks:
+    sync:
+      webhook:
+        url: http://configmaps.example.com/sync
+`
+
+func TestRender_decoratorController(t *testing.T) {
+	logging.Logger = testr.New(t)
+	var request []byte
+	out, err := Render(context.Background(), Input{
+		Controller: readObjects(t, decoratorController)[0],
+		Parent:     readObjects(t, "{apiVersion: v1, kind: ConfigMap, metadata: {name: a, namespace: default, uid: '1234'}}")[0],
+		Handler: respondWith(`{
+			"labels": {"decorated": "true"},
+			"attachments": [{"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "a"}}]
+		}`, &request),
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, string(request), `"attachments":{"Secret.v1":{}}`)
+	require.Len(t, out.Children, 1)
+	assert.Equal(t, "configmaps", out.Children[0].GetAnnotations()["metacontroller.k8s.io/decorator-controller"])
+
+	require.Len(t, out.Diff, 2)
+	assert.Equal(t, common.DryRunUpdate, out.Diff[0].Action)
+	assert.Equal(t, "ConfigMap", out.Diff[0].Kind)
+	// The controller sets the annotations and the status even if they're
+	// empty.
+	assert.JSONEq(t, `{"metadata":{"annotations":{},"labels":{"decorated":"true"}},"status":null}`, string(out.Diff[0].Patch))
+	assert.Equal(t, common.DryRunCreate, out.Diff[1].Action)
+	assert.Equal(t, "Secret", out.Diff[1].Kind)
+}
+
+func TestRender_unknownResource(t *testing.T) {
+	parent := readObjects(t, thing)[0]
+	parent.SetKind("Other")
+	_, err := Render(context.Background(), Input{
+		Controller: readObjects(t, compositeController)[0],
+		Parent:     parent,
+		Handler:    http.NotFoundHandler(),
+	})
+
+	assert.ErrorContains(t, err, `can't find the kind of resource "things"`)
+}
+
+func TestRender_rollingUpdate(t *testing.T) {
+	logging.Logger = testr.New(t)
+	var request []byte
+	out, err := Render(context.Background(), Input{
+		Controller: readObjects(t, strings.Replace(compositeController, "method: InPlace", "method: RollingInPlace", 1))[0],
+		Parent:     readObjects(t, thing)[0],
+		Children:   readObjects(t, observedConfigMaps),
+		CRDs:       readObjects(t, thingCRD),
+		Handler: respondWith(`{
+			"status": {"ready": true},
+			"children": [{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "labels": {"app": "a"}}, "data": {"value": "x"}}]
+		}`, &request),
+	})
+	require.NoError(t, err)
+
+	// The parent has no revision yet, so the rollout starts with one.
+	require.Len(t, out.Diff, 4)
+	assert.Equal(t, common.DryRunCreate, out.Diff[0].Action)
+	assert.Equal(t, "ControllerRevision", out.Diff[0].Kind)
+	assert.Equal(t, common.DryRunDelete, out.Diff[1].Action)
+	assert.Equal(t, common.DryRunUpdate, out.Diff[2].Action)
+	assert.Equal(t, common.DryRunUpdateStatus, out.Diff[3].Action)
+	revisions, ok := out.Status["revisions"].(map[string]interface{})
+	require.True(t, ok, "status = %v", out.Status)
+	assert.Equal(t, out.Diff[0].Name, revisions["updateRevision"])
+}
+
+const widgetCRD = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  names:
+    plural: widgets
+    kind: Widget
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              parts:
+                type: array
+                x-kubernetes-list-type: map
+                x-kubernetes-list-map-keys: [id]
+                items:
+                  type: object
+                  properties:
+                    id: {type: string}
+                    size: {type: integer}
+`
+
+func TestRender_crdSchema(t *testing.T) {
+	logging.Logger = testr.New(t)
+	controller := readObjects(t, compositeController)[0]
+	require.NoError(t, unstructured.SetNestedSlice(controller.Object, []interface{}{
+		map[string]interface{}{"apiVersion": "example.com/v1", "resource": "widgets", "updateStrategy": map[string]interface{}{"method": "InPlace"}},
+	}, "spec", "childResources"))
+	var request []byte
+	out, err := Render(context.Background(), Input{
+		Controller: controller,
+		Parent:     readObjects(t, thing)[0],
+		Children: readObjects(t, `{apiVersion: example.com/v1, kind: Widget, metadata: {name: a, namespace: default, labels: {app: a}},
+			spec: {parts: [{id: x, size: 1, color: red}]}}`),
+		CRDs: readObjects(t, thingCRD+"---\n"+widgetCRD),
+		Handler: respondWith(`{
+			"children": [{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "a", "labels": {"app": "a"}}, "spec": {"parts": [{"id": "x", "size": 2}]}}]
+		}`, &request),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, out.Diff, 2)
+	assert.Equal(t, common.DryRunUpdate, out.Diff[0].Action)
+	// The schema of the CRD makes the parts a list map, so the fields of the
+	// observed part that the hook doesn't set are kept.
+	assert.Contains(t, string(out.Diff[0].Patch), `"parts":[{"color":"red","id":"x","size":2}]`)
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+// newResourceMap returns a ResourceMap that serves the resources of the given
+// rules, like discovery would. The kind of a resource is looked up in the
+// CRDs, then in the given objects and then in the built-in kinds.
+func newResourceMap(rules []v1alpha1.ResourceRule, crds, objects []*unstructured.Unstructured) (*dynamicdiscovery.ResourceMap, error) {
+	definitions := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(crds))
+	for _, obj := range crds {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+			return nil, fmt.Errorf("can't convert CustomResourceDefinition %v: %w", obj.GetName(), err)
+		}
+		definitions = append(definitions, crd)
+	}
+
+	groups := make(map[string]*metav1.APIResourceList)
+	seen := make(map[schema.GroupVersionResource]bool)
+	for _, rule := range rules {
+		gv, err := schema.ParseGroupVersion(rule.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse apiVersion %q: %w", rule.APIVersion, err)
+		}
+		gvr := gv.WithResource(rule.Resource)
+		if seen[gvr] {
+			continue
+		}
+		seen[gvr] = true
+		resource, hasStatus, ok := findResource(gvr, definitions, objects)
+		if !ok {
+			return nil, fmt.Errorf("can't find the kind of resource %q in apiVersion %q: pass its CustomResourceDefinition or an object of it", rule.Resource, rule.APIVersion)
+		}
+		group := groups[rule.APIVersion]
+		if group == nil {
+			group = &metav1.APIResourceList{GroupVersion: rule.APIVersion}
+			groups[rule.APIVersion] = group
+		}
+		group.APIResources = append(group.APIResources, resource)
+		if hasStatus {
+			group.APIResources = append(group.APIResources, metav1.APIResource{Name: resource.Name + "/status", Kind: resource.Kind, Namespaced: resource.Namespaced})
+		}
+	}
+
+	apiVersions := make([]string, 0, len(groups))
+	for apiVersion := range groups {
+		apiVersions = append(apiVersions, apiVersion)
+	}
+	sort.Strings(apiVersions)
+	lists := make([]*metav1.APIResourceList, 0, len(groups))
+	for _, apiVersion := range apiVersions {
+		lists = append(lists, groups[apiVersion])
+	}
+	resources := dynamicdiscovery.NewStaticResourceMap(lists)
+	if err := setSchemas(resources, definitions); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// setSchemas sets the schemas of the versions of the given CRDs on the
+// resources, so that children are merged like the API server publishes them.
+func setSchemas(resources *dynamicdiscovery.ResourceMap, crds []*apiextensionsv1.CustomResourceDefinition) error {
+	for _, crd := range crds {
+		for _, version := range crd.Spec.Versions {
+			if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			data, err := json.Marshal(version.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return fmt.Errorf("can't read the schema of version %v of CustomResourceDefinition %v: %w", version.Name, crd.Name, err)
+			}
+			var openAPISchema map[string]interface{}
+			if err := json.Unmarshal(data, &openAPISchema); err != nil {
+				return fmt.Errorf("can't read the schema of version %v of CustomResourceDefinition %v: %w", version.Name, crd.Name, err)
+			}
+			apiVersion := schema.GroupVersion{Group: crd.Spec.Group, Version: version.Name}.String()
+			resources.SetSchema(apiVersion, crd.Spec.Names.Kind, openAPISchema)
+		}
+	}
+	return nil
+}
+
+// findResource returns the resource of the given GroupVersionResource and
+// whether it's known to have a status subresource.
+func findResource(gvr schema.GroupVersionResource, crds []*apiextensionsv1.CustomResourceDefinition, objects []*unstructured.Unstructured) (resource metav1.APIResource, hasStatus, ok bool) {
+	resource = metav1.APIResource{Name: gvr.Resource, Verbs: metav1.Verbs{"get", "list", "watch", "create", "update", "patch", "delete"}}
+	for _, crd := range crds {
+		if crd.Spec.Group != gvr.Group || crd.Spec.Names.Plural != gvr.Resource {
+			continue
+		}
+		for _, version := range crd.Spec.Versions {
+			if version.Name != gvr.Version {
+				continue
+			}
+			resource.Kind = crd.Spec.Names.Kind
+			resource.Namespaced = crd.Spec.Scope == apiextensionsv1.NamespaceScoped
+			return resource, version.Subresources != nil && version.Subresources.Status != nil, true
+		}
+	}
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if plural, _ := meta.UnsafeGuessKindToResource(gvk); plural == gvr {
+			resource.Kind = gvk.Kind
+			resource.Namespaced = obj.GetNamespace() != ""
+			return resource, false, true
+		}
+	}
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		if plural, _ := meta.UnsafeGuessKindToResource(gvk); plural == gvr {
+			resource.Kind = gvk.Kind
+			resource.Namespaced = true
+			return resource, false, true
+		}
+	}
+	return resource, false, false
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"metacontroller/pkg/controller/common"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/logging"
+)
+
+// debugPath is where the state of Metacontroller is served, next to the
+// metrics.
+const debugPath = "/debug/metacontroller"
+
+// debugState is the state of Metacontroller, for introspection.
+type debugState struct {
+	Controllers         []common.ControllerStatus       `json:"controllers"`
+	Informers           []dynamicinformer.InformerStats `json:"informers"`
+	DiscoveryGeneration int64                           `json:"discoveryGeneration"`
+}
+
+// debugHandler serves the state of Metacontroller as JSON. Requests are
+// authorized by the authorizer.
+type debugHandler struct {
+	authorizer *authorizer
+	state      func() debugState
+}
+
+func (h *debugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if code, err := h.authorizer.authorize(r.Context(), r); err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(h.state()); err != nil {
+		logging.Logger.Error(err, "failed to write debug state")
+	}
+}
+
+// reviewCacheTTL is how long the outcomes of token and access reviews are
+// reused.
+const reviewCacheTTL = 30 * time.Second
+
+// authorizer authorizes requests to the debug endpoints with TokenReviews and
+// SubjectAccessReviews, whose outcomes it caches for reviewCacheTTL.
+type authorizer struct {
+	clientset kubernetes.Interface
+	// allowLoopback allows requests from loopback addresses without a token.
+	// It's only safe if the server is bound to a loopback address, since
+	// sidecars like service meshes forward remote requests from loopback
+	// addresses too.
+	allowLoopback bool
+	now           func() time.Time
+
+	mutex sync.Mutex
+	// users holds the users of tokens, by the SHA-256 of the token, or nil
+	// for invalid tokens.
+	users map[string]cachedUser
+	// allowed holds whether users may access paths, by user, verb and path.
+	allowed map[string]cachedAccess
+}
+
+type cachedUser struct {
+	user    *authenticationv1.UserInfo
+	expires time.Time
+}
+
+type cachedAccess struct {
+	allowed bool
+	expires time.Time
+}
+
+// newAuthorizer returns an authorizer, which allows requests from loopback
+// addresses without a token if bindAddress is a loopback address, like
+// localhost:9999.
+func newAuthorizer(clientset kubernetes.Interface, bindAddress string) *authorizer {
+	host, _, err := net.SplitHostPort(bindAddress)
+	if err != nil {
+		host = bindAddress
+	}
+	return &authorizer{
+		clientset:     clientset,
+		allowLoopback: host == "localhost" || isLoopback(host),
+		now:           time.Now,
+		users:         make(map[string]cachedUser),
+		allowed:       make(map[string]cachedAccess),
+	}
+}
+
+// authorize returns the HTTP status code and an error if the request isn't
+// allowed. Requests from loopback addresses are allowed if the authorizer
+// allows them. Other requests need a bearer token of a user that may use the
+// method of the request, like get or put, on its path.
+func (a *authorizer) authorize(ctx context.Context, r *http.Request) (int, error) {
+	if a.allowLoopback && isLoopback(r.RemoteAddr) {
+		return http.StatusOK, nil
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return http.StatusUnauthorized, fmt.Errorf("bearer token required")
+	}
+	user, err := a.reviewToken(ctx, token)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("can't review token: %w", err)
+	}
+	if user == nil {
+		return http.StatusUnauthorized, fmt.Errorf("invalid bearer token")
+	}
+
+	verb := strings.ToLower(r.Method)
+	allowed, err := a.reviewAccess(ctx, user, verb, r.URL.Path)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("can't review access: %w", err)
+	}
+	if !allowed {
+		return http.StatusForbidden, fmt.Errorf("user %q may not %s %s", user.Username, verb, r.URL.Path)
+	}
+	return http.StatusOK, nil
+}
+
+// reviewToken returns the user of a token, or nil if the token is invalid.
+func (a *authorizer) reviewToken(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	a.mutex.Lock()
+	cached, ok := a.users[key]
+	a.mutex.Unlock()
+	if ok && a.now().Before(cached.expires) {
+		return cached.user, nil
+	}
+
+	tokenReview, err := a.clientset.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var user *authenticationv1.UserInfo
+	if tokenReview.Status.Authenticated {
+		user = &tokenReview.Status.User
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.removeExpired()
+	a.users[key] = cachedUser{user: user, expires: a.now().Add(reviewCacheTTL)}
+	return user, nil
+}
+
+// reviewAccess returns true if the user may use the verb on the path.
+func (a *authorizer) reviewAccess(ctx context.Context, user *authenticationv1.UserInfo, verb, path string) (bool, error) {
+	key := user.UID + "/" + user.Username + "/" + verb + "/" + path
+	a.mutex.Lock()
+	cached, ok := a.allowed[key]
+	a.mutex.Unlock()
+	if ok && a.now().Before(cached.expires) {
+		return cached.allowed, nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	accessReview, err := a.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: path,
+				Verb: verb,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.removeExpired()
+	a.allowed[key] = cachedAccess{allowed: accessReview.Status.Allowed, expires: a.now().Add(reviewCacheTTL)}
+	return accessReview.Status.Allowed, nil
+}
+
+// removeExpired removes the expired reviews from the caches. The mutex must
+// be held.
+func (a *authorizer) removeExpired() {
+	now := a.now()
+	for key, cached := range a.users {
+		if !now.Before(cached.expires) {
+			delete(a.users, key)
+		}
+	}
+	for key, cached := range a.allowed {
+		if !now.Before(cached.expires) {
+			delete(a.allowed, key)
+		}
+	}
+}
+
+// isLoopback returns true if the remote address of a request is a loopback
+// address.
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func newFakeReviewClientset() *fake.Clientset {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch review.Spec.Token {
+		case "admin", "viewer":
+			review.Status.Authenticated = true
+			review.Status.User.Username = review.Spec.Token
+		}
+		return true, review, nil
+	})
+	clientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.NonResourceAttributes
+		switch review.Spec.User {
+		case "admin":
+			review.Status.Allowed = attributes.Path == logLevelPath ||
+				attributes.Path == debugPath && attributes.Verb == "get"
+		case "viewer":
+			review.Status.Allowed = attributes.Path == logLevelPath && attributes.Verb == "get"
+		}
+		return true, review, nil
+	})
+	return clientset
+}
+
+func TestDebugHandler(t *testing.T) {
+	state := func() debugState {
+		return debugState{
+			Controllers:         []common.ControllerStatus{{Kind: "CompositeController", Name: "test"}},
+			DiscoveryGeneration: 3,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		bindAddress string
+		remoteAddr  string
+		token       string
+		want        int
+	}{
+		{name: "loopback", bindAddress: "127.0.0.1:9999", remoteAddr: "127.0.0.1:4321", want: http.StatusOK},
+		{name: "loopback IPv6", bindAddress: "[::1]:9999", remoteAddr: "[::1]:4321", want: http.StatusOK},
+		{name: "loopback bound to localhost", bindAddress: "localhost:9999", remoteAddr: "127.0.0.1:4321", want: http.StatusOK},
+		{name: "loopback bound to all addresses", bindAddress: ":9999", remoteAddr: "127.0.0.6:4321", want: http.StatusUnauthorized},
+		{name: "loopback bound to all addresses with access", bindAddress: ":9999", remoteAddr: "127.0.0.6:4321", token: "admin", want: http.StatusOK},
+		{name: "remote without token", bindAddress: ":9999", remoteAddr: "10.0.0.1:4321", want: http.StatusUnauthorized},
+		{name: "remote with invalid token", bindAddress: ":9999", remoteAddr: "10.0.0.1:4321", token: "invalid", want: http.StatusUnauthorized},
+		{name: "remote without access", bindAddress: ":9999", remoteAddr: "10.0.0.1:4321", token: "viewer", want: http.StatusForbidden},
+		{name: "remote with access", bindAddress: ":9999", remoteAddr: "10.0.0.1:4321", token: "admin", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &debugHandler{
+				authorizer: newAuthorizer(newFakeReviewClientset(), tt.bindAddress),
+				state:      state,
+			}
+			request := httptest.NewRequest(http.MethodGet, debugPath, nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var state debugState
+			if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+				t.Fatal(err)
+			}
+			if len(state.Controllers) != 1 || state.DiscoveryGeneration != 3 {
+				t.Errorf("state = %+v, want the state of the handler", state)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_cachesReviews(t *testing.T) {
+	clientset := newFakeReviewClientset()
+	a := newAuthorizer(clientset, ":9999")
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	authorize := func(token string) int {
+		request := httptest.NewRequest(http.MethodGet, debugPath, nil)
+		request.RemoteAddr = "10.0.0.1:4321"
+		request.Header.Set("Authorization", "Bearer "+token)
+		code, _ := a.authorize(context.TODO(), request)
+		return code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := authorize("admin"); code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", code, http.StatusOK)
+		}
+		if code := authorize("invalid"); code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want %d", code, http.StatusUnauthorized)
+		}
+	}
+	if got := len(clientset.Actions()); got != 3 {
+		t.Errorf("reviews = %d, want 3", got)
+	}
+
+	now = now.Add(reviewCacheTTL)
+	authorize("admin")
+	if got := len(clientset.Actions()); got != 5 {
+		t.Errorf("reviews after expiry = %d, want 5", got)
+	}
+}
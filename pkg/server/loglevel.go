@@ -0,0 +1,76 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"metacontroller/pkg/logging"
+)
+
+// logLevelPath is where the log level of Metacontroller is served and set,
+// next to the metrics.
+const logLevelPath = "/debug/metacontroller/loglevel"
+
+// logLevel is the body of requests and responses of the log level endpoint.
+type logLevel struct {
+	// Level is one of debug, info, error and panic, or a verbosity greater
+	// than 0, like the --zap-log-level flag.
+	Level string `json:"level"`
+}
+
+// logLevelHandler serves the log level of Metacontroller on GET, and sets it
+// on PUT. Requests are authorized by the authorizer.
+type logLevelHandler struct {
+	authorizer *authorizer
+}
+
+func (h *logLevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if code, err := h.authorizer.authorize(r.Context(), r); err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var request logLevel
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1024)).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("can't decode log level: %v", err), http.StatusBadRequest)
+			return
+		}
+		level, err := logging.ParseLevel(request.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logging.SetLevel(level)
+		logging.Logger.Info("Changed log level", "level", logging.Level())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(logLevel{Level: logging.Level()}); err != nil {
+		logging.Logger.Error(err, "failed to write log level")
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"metacontroller/pkg/logging"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	handler := &logLevelHandler{authorizer: newAuthorizer(newFakeReviewClientset(), ":9999")}
+	defer logging.SetLevel(zapcore.InfoLevel)
+
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		token     string
+		want      int
+		wantLevel string
+	}{
+		{name: "get", method: http.MethodGet, token: "viewer", want: http.StatusOK, wantLevel: "info"},
+		{name: "put without access", method: http.MethodPut, body: `{"level":"6"}`, token: "viewer", want: http.StatusForbidden},
+		{name: "put verbosity", method: http.MethodPut, body: `{"level":"6"}`, token: "admin", want: http.StatusOK, wantLevel: "6"},
+		{name: "get after put", method: http.MethodGet, token: "viewer", want: http.StatusOK, wantLevel: "6"},
+		{name: "put name", method: http.MethodPut, body: `{"level":"error"}`, token: "admin", want: http.StatusOK, wantLevel: "error"},
+		{name: "put invalid level", method: http.MethodPut, body: `{"level":"0"}`, token: "admin", want: http.StatusBadRequest},
+		{name: "put invalid body", method: http.MethodPut, body: `6`, token: "admin", want: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, token: "admin", want: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, logLevelPath, strings.NewReader(tt.body))
+			request.RemoteAddr = "10.0.0.1:4321"
+			request.Header.Set("Authorization", "Bearer "+tt.token)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var level logLevel
+			if err := json.Unmarshal(recorder.Body.Bytes(), &level); err != nil {
+				t.Fatal(err)
+			}
+			if level.Level != tt.wantLevel {
+				t.Errorf("level = %q, want %q", level.Level, tt.wantLevel)
+			}
+		})
+	}
+}
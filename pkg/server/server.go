@@ -19,6 +19,8 @@ package server
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -26,6 +28,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -34,6 +37,8 @@ import (
 
 	"metacontroller/pkg/controller/common"
 	"metacontroller/pkg/controller/decorator"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
 	"metacontroller/pkg/logging"
 	"metacontroller/pkg/options"
 
@@ -133,9 +138,33 @@ func New(ctx context.Context, configuration options.Configuration) (controllerru
 		return nil, fmt.Errorf("unknown apply strategy: %s", configuration.ApplyStrategy)
 	}
 
+	var lastAppliedEncoding dynamicapply.LastAppliedEncoding
+	switch configuration.LastAppliedEncoding {
+	case "", "json":
+		lastAppliedEncoding = dynamicapply.LastAppliedEncodingJSON
+	case "gzip":
+		lastAppliedEncoding = dynamicapply.LastAppliedEncodingGzip
+	case "auto":
+		lastAppliedEncoding = dynamicapply.LastAppliedEncodingAuto
+	default:
+		return nil, fmt.Errorf("unknown last-applied encoding: %s", configuration.LastAppliedEncoding)
+	}
+
 	applyOptions := &common.ApplyOptions{
-		FieldManager: configuration.SsaFieldManager,
-		Strategy:     strategy,
+		FieldManager:         configuration.SsaFieldManager,
+		Strategy:             strategy,
+		MigrateFieldManagers: configuration.SsaMigrateFieldManagers,
+		LastAppliedEncoding:  lastAppliedEncoding,
+		Stripped: dynamicinformer.StripOptions{
+			Annotations: configuration.CacheStripAnnotations,
+			Fields:      configuration.CacheStripFields,
+		},
+	}
+	// Each controller records its writes under its own name.
+	applyOptions.Audit = common.NewAudit(configuration.AuditSink)
+	if configuration.DryRun {
+		// Each controller replaces it with a reporter of its own.
+		applyOptions.DryRun = &common.DryRun{}
 	}
 
 	compositeReconciler := composite.NewMetacontroller(ctx, *controllerContext, mcClient, configuration.Workers, applyOptions)
@@ -150,6 +179,12 @@ func New(ctx context.Context, configuration options.Configuration) (controllerru
 	if err != nil {
 		return nil, err
 	}
+	// Retry controllers that failed to sync as soon as discovery changes.
+	// They passed the label selector already.
+	err = compositeCtrl.Watch(compositeReconciler.RetrySource())
+	if err != nil {
+		return nil, err
+	}
 	decoratorReconciler := decorator.NewMetacontroller(ctx, *controllerContext, configuration.Workers, applyOptions)
 	decoratorCtrl, err := controller.New("decorator-metacontroller", mgr, controller.Options{
 		Reconciler: decoratorReconciler,
@@ -161,6 +196,38 @@ func New(ctx context.Context, configuration options.Configuration) (controllerru
 	if err != nil {
 		return nil, err
 	}
+	err = decoratorCtrl.Watch(decoratorReconciler.RetrySource())
+	if err != nil {
+		return nil, err
+	}
+
+	err = addHealthChecks(mgr, controllerContext, compositeReconciler, decoratorReconciler, configuration.StuckQueueTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(configuration.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	authorizer := newAuthorizer(clientset, configuration.MetricsEndpoint)
+	err = mgr.AddMetricsServerExtraHandler(debugPath, &debugHandler{
+		authorizer: authorizer,
+		state: func() debugState {
+			return debugState{
+				Controllers:         append(compositeReconciler.ControllerStatuses(), decoratorReconciler.ControllerStatuses()...),
+				Informers:           controllerContext.DynInformers.Stats(),
+				DiscoveryGeneration: controllerContext.Resources.Generation(),
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = mgr.AddMetricsServerExtraHandler(logLevelPath, &logLevelHandler{authorizer: authorizer})
+	if err != nil {
+		return nil, err
+	}
 
 	// We need to call Start after initializing the controllers
 	// to make sure all the needed informers are already created
@@ -169,6 +236,47 @@ func New(ctx context.Context, configuration options.Configuration) (controllerru
 	return mgr, nil
 }
 
+// addHealthChecks adds readiness checks that fail until discovery and all
+// informers have synced, and while any controller fails to sync, and a
+// liveness check that fails if the workqueue of a controller is stuck.
+func addHealthChecks(mgr manager.Manager, controllerContext *common.ControllerContext, compositeReconciler *composite.Metacontroller, decoratorReconciler *decorator.Metacontroller, stuckQueueTimeout time.Duration) error {
+	err := mgr.AddReadyzCheck("discovery", func(_ *http.Request) error {
+		if !controllerContext.Resources.HasSynced() {
+			return fmt.Errorf("discovery hasn't synced yet")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = mgr.AddReadyzCheck("informers", func(_ *http.Request) error {
+		if unsynced := controllerContext.DynInformers.Unsynced(); len(unsynced) > 0 {
+			return fmt.Errorf("informers haven't synced yet: %s", strings.Join(unsynced, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = mgr.AddReadyzCheck("controllers", func(_ *http.Request) error {
+		var failed []string
+		for _, name := range compositeReconciler.FailedControllers() {
+			failed = append(failed, "CompositeController "+name)
+		}
+		for _, name := range decoratorReconciler.FailedControllers() {
+			failed = append(failed, "DecoratorController "+name)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("controllers failed to sync: %s", strings.Join(failed, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return mgr.AddHealthzCheck("workqueues", common.StuckQueuesCheck(stuckQueueTimeout))
+}
+
 func k8sCommunicationCheck(ctx context.Context, client *discovery.DiscoveryClient) (err error) {
 	// retry 6 times with a delay of 5 seconds each retry
 	// the retry and sleep intervals were observed anecdotally that service mesh sidecars
@@ -0,0 +1,129 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports OpenTelemetry traces of parent syncs over OTLP.
+//
+// Until Setup is called with an endpoint, spans are started on the no-op
+// tracer provider of OpenTelemetry, so they cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const tracerName = "metacontroller"
+
+// propagator writes the W3C traceparent header into hook requests.
+var propagator = propagation.TraceContext{}
+
+// Options configures the export of traces.
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. Tracing is
+	// disabled if it's empty.
+	Endpoint string
+	// Insecure disables TLS to the collector.
+	Insecure bool
+	// SampleRatio is the fraction of parent syncs that are traced.
+	SampleRatio float64
+	// Version is reported as the service version of the spans.
+	Version string
+}
+
+// Setup starts exporting traces as configured, and returns a function that
+// flushes the pending spans and stops the export.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	if options.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporterOptions := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(options.Endpoint)}
+	if options.Insecure {
+		exporterOptions = append(exporterOptions, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("can't create OTLP exporter for %s: %w", options.Endpoint, err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "metacontroller"),
+			attribute.String("service.version", options.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with the given name, as a child of the span in ctx if
+// there is one.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends the span, marking it as failed if err isn't nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject writes the traceparent header of the span in ctx to the header, so
+// that the spans of the receiver join the trace.
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// StartSync starts the root span of a sync of the parent with the given key.
+func StartSync(ctx context.Context, controllerType, controllerName, parentKey string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, controllerType+" sync",
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("metacontroller.controller.type", controllerType),
+			attribute.String("metacontroller.controller.name", controllerName),
+			attribute.String("metacontroller.parent.key", parentKey),
+		),
+	)
+}
+
+// Object returns the attributes of an object that a span acts on, with keys
+// prefixed by the role of the object, e.g. parent or child.
+func Object(role string, obj *unstructured.Unstructured) []attribute.KeyValue {
+	if obj == nil {
+		return nil
+	}
+	prefix := "metacontroller." + role + "."
+	return []attribute.KeyValue{
+		attribute.String(prefix+"api_version", obj.GetAPIVersion()),
+		attribute.String(prefix+"kind", obj.GetKind()),
+		attribute.String(prefix+"namespace", obj.GetNamespace()),
+		attribute.String(prefix+"name", obj.GetName()),
+	}
+}
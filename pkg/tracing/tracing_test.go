@@ -0,0 +1,79 @@
+/*
+Copyright 2026 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	ctx, previous := StartSync(context.Background(), "CompositeController", "test", "default/a")
+	previous.End()
+	// A sync is a new trace, even if ctx carries a span.
+	ctx, sync := StartSync(ctx, "CompositeController", "test", "default/a")
+	_, child := Start(ctx, "apply child")
+	End(child, errors.New("failed"))
+	End(sync, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	if spans[2].Parent().IsValid() || spans[2].SpanContext().TraceID() == spans[0].SpanContext().TraceID() {
+		t.Errorf("sync span is not the root of a new trace")
+	}
+	if spans[1].Parent().SpanID() != spans[2].SpanContext().SpanID() {
+		t.Errorf("child span is not a child of the sync span")
+	}
+	if got := spans[1].Status().Code; got != codes.Error {
+		t.Errorf("child span status = %v, want %v", got, codes.Error)
+	}
+	if got := spans[2].Status().Code; got != codes.Unset {
+		t.Errorf("sync span status = %v, want %v", got, codes.Unset)
+	}
+}
+
+func TestInject(t *testing.T) {
+	header := http.Header{}
+	Inject(context.Background(), header)
+	if got := header.Get("traceparent"); got != "" {
+		t.Errorf("without a span: traceparent = %q, want none", got)
+	}
+}
+
+func TestSetup_disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() = %v, want nil", err)
+	}
+}